@@ -0,0 +1,158 @@
+// Checking that the generated files on disk match the schemas, without writing
+// anything.
+
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	cg "splits-go-schema-codegen/codegen"
+	"strconv"
+	"strings"
+)
+
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	configFlags := addConfigFlags(fs)
+	var verbose bool
+	fs.BoolVar(&verbose, "v", false, "Also list the files that are up to date")
+	filterFlags := addFilterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	config, err := configFlags.load(fs)
+	if err != nil {
+		return err
+	}
+	filter, err := filterFlags.apply(&config)
+	if err != nil {
+		return err
+	}
+
+	// Render with the manual sections on disk, so only generated code differs
+	dirs := cg.LayerDirs(config)
+	manualParts, err := cg.ReadManualSections(dirs...)
+	if err != nil {
+		return err
+	}
+	files, unmatched, err := renderChecked(config, filter, manualParts)
+	if err != nil {
+		return err
+	}
+
+	// Files in the manifest are checked against it, others by their signature
+	manifest, err := cg.LoadManifest(config.StatePath())
+	if err != nil {
+		return err
+	}
+	owned := manifest.Owned(config.Root, "")
+
+	found := map[cg.FileStatus]int{}
+	for _, f := range files {
+		status := cg.StatusUnmatched
+		if _, ok := unmatched[f.Path]; !ok {
+			status, err = f.Check(owned)
+			if err != nil {
+				return err
+			}
+		}
+		if status != cg.StatusUpToDate {
+			found[status]++
+		} else if !verbose {
+			continue
+		}
+		printStatus(config, status, f.Path)
+		for _, err := range unmatched[f.Path] {
+			fmt.Printf("%-20s %v\n", "", err)
+		}
+	}
+
+	// Orphans can only be told apart when every file is rendered
+	orphans := []cg.Orphan{}
+	if filter.IsEmpty() {
+		orphans, err = findOrphans(config, manifest, files)
+		if err != nil {
+			return err
+		}
+	}
+	for _, o := range orphans {
+		found[cg.StatusExtra]++
+		printStatus(config, cg.StatusExtra, filepath.Join(config.Root, o.Path))
+	}
+
+	problems := 0
+	for _, count := range found {
+		problems += count
+	}
+	if problems > 0 {
+		return errors.New(strconv.Itoa(problems) + " generated file(s) do not " +
+			"match the schemas: " + checkAdvice(found))
+	}
+	fmt.Printf("All %d generated files are up to date\n", len(files))
+	return nil
+}
+
+// renderChecked renders the files with the manual sections on disk. A file
+// whose manual sections no longer match its template is rendered without them
+// instead, and returned with the error, so the other files are still checked.
+func renderChecked(
+	config cg.Config,
+	filter cg.Filter,
+	manualParts map[string][]cg.ManualSection,
+) ([]cg.File, map[string][]error, error) {
+	unmatched := map[string][]error{}
+	files, err := cg.RenderFiles(config, schemas, filter, manualParts)
+	errs, ok := err.(cg.Errors)
+	if !ok {
+		return files, unmatched, err
+	}
+	for _, e := range errs {
+		if e.Code != cg.CodeUnmatchedManual {
+			return nil, nil, err
+		}
+		unmatched[e.File] = append(unmatched[e.File], e.Err)
+	}
+
+	rest := map[string][]cg.ManualSection{}
+	for path, sections := range manualParts {
+		if _, ok := unmatched[path]; !ok {
+			rest[path] = sections
+		}
+	}
+	files, err = cg.RenderFiles(config, schemas, filter, rest)
+	return files, unmatched, err
+}
+
+// checkAdvice tells how to fix the statuses found by check, as a forced or
+// merge run is needed for edited files, which a plain generate refuses to
+// write over.
+func checkAdvice(found map[cg.FileStatus]int) string {
+	advice := []string{}
+	if found[cg.StatusStale]+found[cg.StatusOutdated]+
+		found[cg.StatusMissing] > 0 {
+		advice = append(advice, "regenerate with the generate command")
+	}
+	if found[cg.StatusTampered] > 0 {
+		advice = append(advice, "merge the edits outside of manual sections "+
+			"with generate --merge, or drop them with generate --force")
+	}
+	if found[cg.StatusUnmatched] > 0 {
+		advice = append(advice, "move the code of the unmatched manual "+
+			"sections to sections the templates still have")
+	}
+	if found[cg.StatusExtra] > 0 {
+		advice = append(advice, "remove the orphaned files with clean --delete")
+	}
+	return strings.Join(advice, "; ")
+}
+
+// printStatus prints the status of a file, relative to the root.
+func printStatus(config cg.Config, status cg.FileStatus, path string) {
+	rel, err := filepath.Rel(config.Root, path)
+	if err != nil {
+		rel = path
+	}
+	fmt.Printf("%-20s %s\n", status, rel)
+}
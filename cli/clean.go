@@ -0,0 +1,93 @@
+// Finding and removing generated files that no schema renders anymore.
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	cg "splits-go-schema-codegen/codegen"
+)
+
+func runClean(args []string) error {
+	fs := flag.NewFlagSet("clean", flag.ContinueOnError)
+	configFlags := addConfigFlags(fs)
+	var deleteFlag bool
+	fs.BoolVar(&deleteFlag, "delete", false, "Delete the orphaned files "+
+		"instead of only listing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	config, err := configFlags.load(fs)
+	if err != nil {
+		return err
+	}
+
+	// Only the paths of the rendered files matter here, the manifest is only
+	// updated for the deleted files
+	files, err := cg.RenderFiles(config, schemas, cg.Filter{}, map[string][]cg.ManualSection{})
+	if err != nil {
+		return err
+	}
+	manifest, err := cg.LoadManifest(config.StatePath())
+	if err != nil {
+		return err
+	}
+	orphans, err := findOrphans(config, manifest, files)
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		fmt.Println("No orphaned files")
+		return nil
+	}
+	if !deleteFlag {
+		printOrphans(orphans, false)
+		fmt.Println("\nRun clean with --delete to remove them")
+		return nil
+	}
+
+	remove, _ := cg.SplitOrphans(config.Root, orphans, true)
+	manifestFile, err := manifest.Without(config.Root, remove).File(
+		config.StatePath())
+	if err != nil {
+		return err
+	}
+
+	// Rewrite the manifest only, the generated files are left as they are.
+	// The bases of the removed files go with them, or a file generated at the
+	// same path later would be merged against them.
+	removeFiles := append(append([]string{}, remove...),
+		cg.ExistingBases(config.Root, config.StatePath(), remove)...)
+	_, err = cg.WriteFiles(config.Root, config.StatePath(),
+		[]cg.File{manifestFile}, removeFiles)
+	if err != nil {
+		return err
+	}
+	printOrphans(orphans, true)
+	return nil
+}
+
+// findOrphans finds the orphaned files of the enabled layers.
+func findOrphans(
+	config cg.Config,
+	manifest cg.Manifest,
+	files []cg.File,
+) ([]cg.Orphan, error) {
+	return cg.FindOrphans(config.Root, manifest, config.Layers,
+		cg.LayerDirs(config), files)
+}
+
+// printOrphans lists the orphans, and whether they were deleted.
+func printOrphans(orphans []cg.Orphan, deleted bool) {
+	for _, o := range orphans {
+		switch {
+		case o.ManualCode:
+			fmt.Printf("%-10s %s (its manual sections contain code, move it "+
+				"out and delete the file by hand)\n", "kept", o.Path)
+		case deleted:
+			fmt.Printf("%-10s %s\n", "deleted", o.Path)
+		default:
+			fmt.Printf("%-10s %s\n", "orphaned", o.Path)
+		}
+	}
+}
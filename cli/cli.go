@@ -0,0 +1,528 @@
+// Package cli is the command line of the generator, which generates code for a
+// schema, this includes queries, mutations, deleters, as well as constraints
+// and indices.
+//
+// Usage:
+//
+//	splits-go-schema-codegen <command> [flags] [root]
+//
+// The target repository and output layout are read from a versioned config
+// file (codegen.json by default), and can be overridden with flags. Schemas
+// registered from Go are compiled into a binary that imports their package
+// along with this one, and calls Main.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	cg "splits-go-schema-codegen/codegen"
+	"strconv"
+	"strings"
+
+	// The built in generators register themselves when they are imported
+	_ "splits-go-schema-codegen/codegen/db"
+	_ "splits-go-schema-codegen/codegen/graphql"
+	_ "splits-go-schema-codegen/codegen/logic"
+)
+
+// schemas are the schemas registered by the imported schema packages,
+// followed by the schemas in the schema files of the config, once the config
+// is loaded. They can not be read before, as the schema packages may be
+// initialized after this one.
+var schemas []cg.Schema
+
+// command is a subcommand of the generator.
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{"generate", "Generate the code for all enabled layers", runGenerate},
+	{"check", "Check the generated files without writing anything", runCheck},
+	{"diff", "Show what a generation run would change", runDiff},
+	{"list", "List the schemas and edges that code is generated for", runList},
+	{"clean", "Remove generated files that are no longer owned", runClean},
+	{"restore", "Undo the last generation run", runRestore},
+	{"templates", "List the templates and the data they are executed with",
+		runTemplates},
+	{"watch", "Generate again whenever the schemas change", runWatch},
+}
+
+// Main runs the command given in the arguments of the process, and exits with
+// a non-zero status if it fails.
+func Main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+	for _, c := range commands {
+		if c.name == name {
+			err := c.run(os.Args[2:])
+			if err == flag.ErrHelp {
+				return
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] [root]\n\n",
+		os.Args[0])
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.description)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a "+
+		"command.\n", os.Args[0])
+}
+
+// =============================================================================
+// Config
+// =============================================================================
+
+// configFlags are the flags shared by every command for locating the config.
+type configFlags struct {
+	path      string
+	root      string
+	templates string
+	workers   int
+}
+
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	f := new(configFlags)
+	fs.StringVar(&f.path, "config", "", "Path of the config file (default "+
+		cg.DefaultConfigFile+" if it exists)")
+	fs.StringVar(&f.root, "root", "", "Root of the target repository, "+
+		"overrides the config")
+	fs.StringVar(&f.templates, "templates", "", "Directory of template "+
+		"overrides, overrides the config")
+	fs.IntVar(&f.workers, "workers", 0, "Number of files rendered at the "+
+		"same time, overrides the config")
+	return f
+}
+
+// load reads the config file and applies the flag overrides, then loads the
+// template overrides and the schemas.
+func (f *configFlags) load(fs *flag.FlagSet) (cg.Config, error) {
+	config, err := f.read(fs)
+	if err != nil {
+		return config, err
+	}
+
+	if config.Templates != "" {
+		_, err = cg.LoadTemplates(config.Templates)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	schemas, err = cg.LoadSchemas(config)
+	return config, err
+}
+
+// read reads the config file and applies the flag overrides. A positional
+// argument is accepted as the root as well.
+func (f *configFlags) read(fs *flag.FlagSet) (cg.Config, error) {
+	config := cg.DefaultConfig()
+	path := f.configPath()
+	if path != "" {
+		var err error
+		config, err = cg.LoadConfig(path)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	if fs.NArg() > 1 {
+		return config, errors.New("too many arguments: only the root can be " +
+			"given as an argument")
+	}
+	if fs.NArg() == 1 {
+		config.Root = fs.Arg(0)
+	}
+	if f.root != "" {
+		config.Root = f.root
+	}
+	if f.templates != "" {
+		config.Templates = f.templates
+	}
+	if f.workers != 0 {
+		config.Workers = f.workers
+	}
+	return config, config.Validate()
+}
+
+// configPath returns the path of the config file that is read, empty if
+// there is none.
+func (f *configFlags) configPath() string {
+	if f.path != "" {
+		return f.path
+	}
+	if _, err := os.Stat(cg.DefaultConfigFile); err == nil {
+		return cg.DefaultConfigFile
+	}
+	return ""
+}
+
+// args returns the flags that were set, for running another command with the
+// same config.
+func (f *configFlags) args(fs *flag.FlagSet) []string {
+	args := []string{}
+	if f.path != "" {
+		args = append(args, "--config", f.path)
+	}
+	if f.root != "" {
+		args = append(args, "--root", f.root)
+	}
+	if f.templates != "" {
+		args = append(args, "--templates", f.templates)
+	}
+	if f.workers != 0 {
+		args = append(args, "--workers", strconv.Itoa(f.workers))
+	}
+	return append(args, fs.Args()...)
+}
+
+// listFlag is a flag that can be repeated, or given a comma separated list.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// filterFlags are the flags for selecting the layers, schemas and edges to
+// render files for.
+type filterFlags struct {
+	layers  listFlag
+	schemas listFlag
+	edges   listFlag
+}
+
+func addFilterFlags(fs *flag.FlagSet) *filterFlags {
+	f := new(filterFlags)
+	fs.Var(&f.layers, "layers", "Comma separated layers to render, "+
+		"overrides the config")
+	fs.Var(&f.schemas, "schema", "Only render the files of the schema and "+
+		"its edges (repeatable)")
+	fs.Var(&f.edges, "edge", "Only render the files of the edge, by name or "+
+		"code name (repeatable)")
+	return f
+}
+
+// apply restricts the layers of the config, and returns the filter for the
+// schemas and edges.
+func (f *filterFlags) apply(config *cg.Config) (cg.Filter, error) {
+	filter := cg.Filter{Schemas: f.schemas, Edges: f.edges}
+	if len(f.layers) > 0 {
+		config.Layers = f.layers
+		if err := config.Validate(); err != nil {
+			return filter, err
+		}
+	}
+	return filter, filter.Validate(schemas)
+}
+
+// =============================================================================
+// Commands
+// =============================================================================
+
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	configFlags := addConfigFlags(fs)
+	var mergeFlag bool
+	var forceFlag bool
+	fs.BoolVar(&mergeFlag, "merge", false, "Merge the edits made to tampered "+
+		"files with the generated code, leaving conflict markers where they "+
+		"can not be merged")
+	fs.BoolVar(&mergeFlag, "m", false, "Shorthand for --merge")
+	fs.BoolVar(&forceFlag, "force", false, "Overwrite tampered files, "+
+		"dropping their manual sections")
+	fs.BoolVar(&forceFlag, "f", false, "Shorthand for --force")
+	var pruneFlag bool
+	fs.BoolVar(&pruneFlag, "prune", false, "Delete orphaned files that have "+
+		"no code in their manual sections")
+	var dryRunFlag bool
+	fs.BoolVar(&dryRunFlag, "dry-run", false, "Go through the run without "+
+		"writing anything, and print the diff of what would be written")
+	var fullFlag bool
+	fs.BoolVar(&fullFlag, "full", false, "Render every file, even if its "+
+		"inputs did not change")
+	var verifyFlag bool
+	fs.BoolVar(&verifyFlag, "verify", false, "Type-check the rendered "+
+		"packages with the target repository before writing them")
+	var reportFlag string
+	fs.StringVar(&reportFlag, "report", "", "Write a json report of the run "+
+		"to the file, or to stdout instead of the summary with -")
+	filterFlags := addFilterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	config, err := configFlags.load(fs)
+	if err != nil {
+		return err
+	}
+	filter, err := filterFlags.apply(&config)
+	if err != nil {
+		return err
+	}
+
+	quiet := reportFlag == "-"
+	switch {
+	case quiet:
+		// The report is the only output
+	case mergeFlag && forceFlag:
+		fmt.Println("Cannot MERGE and FORCE at the same time. Defaulting to MERGE.")
+	case mergeFlag:
+		fmt.Println("MERGE FLAG IS SET")
+	case forceFlag:
+		fmt.Println("FORCE FLAG IS SET")
+	}
+
+	report, err := cg.Generate(config, cg.Options{
+		Filter: filter,
+		Merge:  mergeFlag,
+		Force:  forceFlag,
+		Prune:  pruneFlag,
+		Full:   fullFlag,
+		Verify: verifyFlag,
+		DryRun: dryRunFlag,
+	})
+	if err != nil {
+		return fmt.Errorf("%v\nNo files were written", err)
+	}
+	switch {
+	case quiet:
+	case dryRunFlag:
+		err = printDryRun(config, report)
+		if err != nil {
+			return err
+		}
+	default:
+		printReport(report, pruneFlag)
+	}
+	if reportFlag != "" {
+		if err := writeReport(reportFlag, report); err != nil {
+			return err
+		}
+	}
+	if dryRunFlag {
+		return nil
+	}
+	return conflictsError(report)
+}
+
+// printDryRun prints the diffs of the files a dry run would write, and the
+// orphans it would remove.
+func printDryRun(config cg.Config, report cg.Report) error {
+	err := printFileDiffs(config, report.Output, false)
+	if err != nil {
+		return err
+	}
+	for _, path := range report.Removed {
+		rel, err := filepath.Rel(config.Root, path)
+		if err != nil {
+			rel = path
+		}
+		fmt.Println("Would remove", rel)
+	}
+	return nil
+}
+
+// conflictsError returns an error if merged files were written with conflict
+// markers, so scripts do not go on with code that does not build.
+func conflictsError(report cg.Report) error {
+	conflicted := 0
+	for _, m := range report.Merged {
+		if len(m.Conflicts) > 0 {
+			conflicted++
+		}
+	}
+	if conflicted == 0 {
+		return nil
+	}
+	return fmt.Errorf("conflicts are left in %d merged files, resolve the "+
+		"conflict markers and run generate with --merge again", conflicted)
+}
+
+// writeReport writes the json report of a run to the file, or to stdout if
+// the path is -.
+func writeReport(path string, report cg.Report) error {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// printReport prints the files a generation run wrote, and the orphans it
+// found.
+func printReport(report cg.Report, pruneFlag bool) {
+	for _, path := range report.Generated {
+		fmt.Printf("Generated %s\n", path)
+	}
+	unchanged := len(report.Unchanged) + len(report.Skipped)
+	fmt.Printf("%d of %d files unchanged", unchanged,
+		len(report.Generated)+unchanged)
+	if len(report.Skipped) > 0 {
+		fmt.Printf(", %d of them not rendered", len(report.Skipped))
+	}
+	fmt.Println()
+	upgraded := 0
+	for _, f := range report.Files {
+		if f.Signature == cg.SignatureOutdated && f.Action == cg.ActionUpdated {
+			upgraded++
+		}
+	}
+	if upgraded > 0 {
+		fmt.Printf("Upgraded the files of another version of the generator "+
+			"or other templates: %d\n", upgraded)
+	}
+	if len(report.Orphans) > 0 {
+		fmt.Println()
+		printOrphans(report.Orphans, pruneFlag)
+		if !pruneFlag {
+			fmt.Println("\nRun generate with --prune or clean with --delete to " +
+				"remove the orphaned files")
+		}
+	}
+	if len(report.Merged) > 0 {
+		fmt.Println()
+		printMerged(report.Merged)
+	}
+	if len(report.Generated) > 0 || len(report.Removed) > 0 {
+		fmt.Println("\nThe replaced files were backed up, run the restore " +
+			"command to undo this run")
+	}
+}
+
+// printMerged lists the files whose edits were merged, with the lines of the
+// conflicts left in them.
+func printMerged(merged []cg.MergedFile) {
+	fmt.Println("Merged the edits of:")
+	for _, m := range merged {
+		line := "  " + m.Path
+		if !m.Base {
+			line += " (no content of the last run to merge with, every " +
+				"difference is a conflict)"
+		}
+		if len(m.Conflicts) > 0 {
+			lines := []string{}
+			for _, c := range m.Conflicts {
+				lines = append(lines, strconv.Itoa(c.Line))
+			}
+			line += ": conflicts at lines " + strings.Join(lines, ", ")
+		}
+		fmt.Println(line)
+	}
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	configFlags := addConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	config, err := configFlags.load(fs)
+	if err != nil {
+		return err
+	}
+
+	restored, err := cg.Restore(config.Root, config.StatePath())
+	if err != nil {
+		return err
+	}
+	for _, path := range restored {
+		fmt.Printf("Restored %s\n", path)
+	}
+	return nil
+}
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	configFlags := addConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if _, err := configFlags.load(fs); err != nil {
+		return err
+	}
+
+	for _, s := range schemas {
+		fmt.Printf("%s (%d fields)\n", s.GetName(), len(s.GetFields()))
+		for _, e := range s.GetEdges() {
+			fmt.Printf("  %s (%s): %s -> %s\n", e.Name, e.CodeName,
+				e.FromNode.GetName(), e.ToNode.GetName())
+		}
+	}
+	return nil
+}
+
+func runTemplates(args []string) error {
+	fs := flag.NewFlagSet("templates", flag.ContinueOnError)
+	configFlags := addConfigFlags(fs)
+	printFlag := fs.String("print", "", "Print the built in text of the "+
+		"template, as a starting point for an override")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if _, err := configFlags.load(fs); err != nil {
+		return err
+	}
+
+	if *printFlag != "" {
+		for _, t := range cg.Templates() {
+			if t.Name == *printFlag {
+				fmt.Print(t.Text)
+				return nil
+			}
+		}
+		return fmt.Errorf("there is no template named %s", *printFlag)
+	}
+
+	for _, t := range cg.Templates() {
+		if t.Overridden() {
+			fmt.Printf("%s (overridden)\n", t.Name)
+		} else {
+			fmt.Println(t.Name)
+		}
+		for _, field := range cg.TemplateFields(t) {
+			fmt.Printf("  .%s\n", field)
+		}
+	}
+	return nil
+}
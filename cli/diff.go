@@ -0,0 +1,187 @@
+// Previewing what a generation run would change, as a unified diff per file.
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	cg "splits-go-schema-codegen/codegen"
+	"strconv"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// Terminal colors used when highlighting a diff.
+const (
+	colorReset   = "\x1b[0m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorCyan    = "\x1b[36m"
+	colorMagenta = "\x1b[35m"
+	colorBold    = "\x1b[1m"
+)
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	configFlags := addConfigFlags(fs)
+	var forceFlag bool
+	var colorFlag bool
+	fs.BoolVar(&forceFlag, "force", false, "Show the diff of a forced run, "+
+		"dropping manual sections")
+	fs.BoolVar(&forceFlag, "f", false, "Shorthand for --force")
+	fs.BoolVar(&colorFlag, "color", false, "Highlight the diff, with manual "+
+		"sections in magenta")
+	filterFlags := addFilterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	config, err := configFlags.load(fs)
+	if err != nil {
+		return err
+	}
+	filter, err := filterFlags.apply(&config)
+	if err != nil {
+		return err
+	}
+
+	return printDiff(config, filter, forceFlag, colorFlag)
+}
+
+// printDiff renders the files as a generation run would, carrying over the
+// manual sections on disk unless forced, and prints their diffs.
+func printDiff(
+	config cg.Config,
+	filter cg.Filter,
+	forceFlag bool,
+	colorFlag bool,
+) error {
+	manualParts := map[string][]cg.ManualSection{}
+	if !forceFlag {
+		var err error
+		manualParts, err = cg.ReadManualSections(cg.LayerDirs(config)...)
+		if err != nil {
+			return err
+		}
+	}
+	files, err := cg.RenderFiles(config, schemas, filter, manualParts)
+	if err != nil {
+		return err
+	}
+	return printFileDiffs(config, files, colorFlag)
+}
+
+// printFileDiffs prints how the files differ from the disk, followed by what
+// happens to their manual sections.
+func printFileDiffs(config cg.Config, files []cg.File, colorFlag bool) error {
+	changed := 0
+	for _, f := range files {
+		rel, err := filepath.Rel(config.Root, f.Path)
+		if err != nil {
+			rel = f.Path
+		}
+		oldName := "a/" + rel
+		oldContent := ""
+		content, err := ioutil.ReadFile(f.Path)
+		if os.IsNotExist(err) {
+			oldName = "/dev/null"
+		} else if err != nil {
+			return err
+		} else {
+			oldContent = string(content)
+		}
+
+		hunks := cg.DiffHunks(cg.DiffLines(oldContent, f.Content), diffContext)
+		if len(hunks) == 0 {
+			continue
+		}
+		changed++
+		diff := cg.UnifiedDiff(oldName, "b/"+rel, hunks)
+		if colorFlag {
+			diff = colorDiff(hunks, diff)
+		}
+		fmt.Print(diff)
+		for _, c := range cg.CompareManualSections(oldContent, f.Content) {
+			printManualSectionChange(c, colorFlag)
+		}
+	}
+
+	if changed == 0 {
+		fmt.Printf("No changes in %d generated files\n", len(files))
+	} else {
+		fmt.Printf("%d of %d generated files would change\n", changed,
+			len(files))
+	}
+	return nil
+}
+
+// colorDiff highlights a unified diff. Changed lines inside manual sections
+// are shown in magenta, so hand-written code that would be lost stands out.
+func colorDiff(hunks []cg.Hunk, diff string) string {
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+
+	// Line kinds of the hunk bodies, in the order they were printed
+	manual := []bool{}
+	for _, h := range hunks {
+		manual = append(manual, false)
+		for _, l := range h.Lines {
+			manual = append(manual, l.Op != cg.DiffEqual && l.Manual)
+		}
+	}
+
+	var b strings.Builder
+	for i, l := range lines {
+		color := ""
+		switch {
+		case i < 2:
+			color = colorBold
+		case manual[i-2]:
+			color = colorMagenta
+		case strings.HasPrefix(l, "@@"):
+			color = colorCyan
+		case strings.HasPrefix(l, "-"):
+			color = colorRed
+		case strings.HasPrefix(l, "+"):
+			color = colorGreen
+		}
+		if color == "" {
+			b.WriteString(l + "\n")
+		} else {
+			b.WriteString(color + l + colorReset + "\n")
+		}
+	}
+	return b.String()
+}
+
+// printManualSectionChange prints what happens to a manual section on disk.
+func printManualSectionChange(c cg.ManualSectionChange, colorFlag bool) {
+	var line string
+	switch c.Status {
+	case "preserved":
+		line = fmt.Sprintf("# manual section %s: preserved (%d lines)",
+			sectionLabel(c.Name, c.Index), c.Lines)
+	case "moved":
+		line = fmt.Sprintf("# manual section %s: moved to section %s "+
+			"(%d lines)", sectionLabel(c.Name, c.Index),
+			sectionLabel(c.NewName, c.NewIndex), c.Lines)
+	default:
+		line = fmt.Sprintf("# manual section %s: DROPPED (%d lines)",
+			sectionLabel(c.Name, c.Index), c.Lines)
+	}
+	if colorFlag && c.Status != "preserved" {
+		line = colorMagenta + line + colorReset
+	}
+	fmt.Println(line)
+}
+
+// sectionLabel names a manual section, by its position if it has no name.
+func sectionLabel(name string, index int) string {
+	if name == "" {
+		return strconv.Itoa(index + 1)
+	}
+	return name
+}
@@ -0,0 +1,322 @@
+// Watching the schema sources and generating again whenever they change. Every
+// run is a separate generate process, so schemas that were removed or changed
+// since the last run are never left behind in memory.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	cg "splits-go-schema-codegen/codegen"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// stamp is what a watched file is compared by between polls.
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshot is the state of the watched files, keyed by path.
+type snapshot map[string]stamp
+
+// watcher polls the inputs of a generation run, and runs generate when they
+// change.
+type watcher struct {
+	configFlags *configFlags
+	fs          *flag.FlagSet
+	sources     []string // Directories of Go schema packages
+	build       string   // Directory of the generator package
+	binary      string   // Generator that is run
+	buildDir    string   // Temporary directory of the rebuilt generator
+	rebuild     bool     // Whether the Go sources changed since the build
+}
+
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	configFlags := addConfigFlags(fs)
+	var sources listFlag
+	fs.Var(&sources, "sources", "Directory of Go schema sources, the "+
+		"generator is rebuilt when they change (repeatable)")
+	build := fs.String("build", ".", "Directory of the generator package "+
+		"that is rebuilt")
+	interval := fs.Duration("interval", 500*time.Millisecond, "How often the "+
+		"files are polled")
+	debounce := fs.Duration("debounce", 300*time.Millisecond, "How long the "+
+		"files have to be left alone before generating")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *interval <= 0 || *debounce < 0 {
+		return errors.New("the interval must be positive and the debounce " +
+			"can not be negative")
+	}
+	if _, err := configFlags.read(fs); err != nil {
+		return err
+	}
+
+	w := &watcher{
+		configFlags: configFlags,
+		fs:          fs,
+		sources:     sources,
+		build:       *build,
+		rebuild:     len(sources) > 0,
+	}
+	binary, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	w.binary = binary
+	if w.rebuild {
+		w.buildDir, err = ioutil.TempDir("", "codegen-watch")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(w.buildDir)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	last := w.snapshot()
+	w.run()
+	fmt.Println("Watching for changes, press Ctrl+C to stop")
+	changed := []string{}
+	var changedAt time.Time
+	for {
+		select {
+		case <-interrupt:
+			return nil
+		case <-ticker.C:
+		}
+
+		// Wait until the files are left alone, as editors and scripts often
+		// write several files, or a file several times, in a row
+		current := w.snapshot()
+		if paths := last.changes(current); len(paths) > 0 {
+			changed = mergePaths(changed, paths)
+			changedAt = time.Now()
+			last = current
+			continue
+		}
+		if len(changed) == 0 || time.Since(changedAt) < *debounce {
+			continue
+		}
+		for _, path := range changed {
+			if w.isSource(path) {
+				w.rebuild = true
+			}
+		}
+		fmt.Printf("\n%s %s\n", clock(), describeChanges(changed))
+		changed = []string{}
+		w.run()
+	}
+}
+
+// run rebuilds the generator if needed, runs generate and prints a summary.
+// Failures are printed, as the watch goes on until it is stopped.
+func (w *watcher) run() {
+	start := time.Now()
+	if w.rebuild {
+		binary := filepath.Join(w.buildDir, "splits-go-schema-codegen")
+		cmd := exec.Command("go", "build", "-o", binary)
+		cmd.Dir = w.build
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			fmt.Printf("%s build failed:\n%s\n", clock(),
+				indent(string(output)))
+			return
+		}
+		w.binary = binary
+		w.rebuild = false
+	}
+
+	args := append([]string{"generate", "--report", "-"},
+		w.configFlags.args(w.fs)...)
+	cmd := exec.Command(w.binary, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		fmt.Printf("%s generate failed after %s:\n%s\n", clock(), elapsed,
+			indent(message))
+		return
+	}
+
+	var report cg.Report
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		fmt.Printf("%s could not read the report of the run: %v\n", clock(),
+			err)
+		return
+	}
+	printWatchSummary(report, elapsed)
+}
+
+// maxListedFiles is the number of written files listed after a run, so a
+// first run or a change to every schema does not flood the terminal.
+const maxListedFiles = 10
+
+// printWatchSummary prints the number of files by action on one line,
+// followed by the files that were written or removed.
+func printWatchSummary(report cg.Report, elapsed time.Duration) {
+	counts := map[cg.FileAction]int{}
+	changed := []string{}
+	for _, f := range report.Files {
+		counts[f.Action]++
+		switch {
+		case f.Action == cg.ActionCreated || f.Action == cg.ActionUpdated ||
+			f.Action == cg.ActionMerged:
+			changed = append(changed, fmt.Sprintf("%s %s", f.Action, f.Path))
+		case f.Removed:
+			changed = append(changed, "removed "+f.Path)
+		}
+	}
+	parts := []string{}
+	for _, a := range []cg.FileAction{cg.ActionCreated, cg.ActionUpdated,
+		cg.ActionMerged, cg.ActionUnchanged, cg.ActionSkipped,
+		cg.ActionOrphaned, cg.ActionKeptOrphan} {
+		if counts[a] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[a], a))
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "no files")
+	}
+	fmt.Printf("%s generated in %s: %s\n", clock(), elapsed,
+		strings.Join(parts, ", "))
+	for i, c := range changed {
+		if i == maxListedFiles {
+			fmt.Printf("  and %d more\n", len(changed)-i)
+			break
+		}
+		fmt.Println("  " + c)
+	}
+}
+
+// snapshot returns the state of the config file, the schema files, the
+// template overrides and the Go sources. The config is read again on every
+// poll, so schema files and templates added to it are picked up.
+func (w *watcher) snapshot() snapshot {
+	s := snapshot{}
+	if path := w.configFlags.configPath(); path != "" {
+		s.add(path)
+	}
+	config, err := w.configFlags.read(w.fs)
+	if err == nil {
+		for _, pattern := range config.SchemaFiles {
+			matches, _ := filepath.Glob(pattern)
+			for _, path := range matches {
+				s.add(path)
+			}
+		}
+		if config.Templates != "" {
+			s.addDir(config.Templates, cg.TemplateExt)
+		}
+	}
+	for _, dir := range w.sources {
+		s.addDir(dir, ".go")
+	}
+	return s
+}
+
+// isSource returns whether the path is a Go file of the schema sources.
+func (w *watcher) isSource(path string) bool {
+	if filepath.Ext(path) != ".go" {
+		return false
+	}
+	for _, dir := range w.sources {
+		if rel, err := filepath.Rel(dir, path); err == nil &&
+			!strings.HasPrefix(rel, "..") {
+			return true
+		}
+	}
+	return false
+}
+
+// add records the state of a file, which is left out if it does not exist.
+func (s snapshot) add(path string) {
+	info, err := os.Stat(path)
+	if err == nil && !info.IsDir() {
+		s[path] = stamp{modTime: info.ModTime(), size: info.Size()}
+	}
+}
+
+// addDir records the state of the files in a directory and its
+// subdirectories that have the extension.
+func (s snapshot) addDir(dir string, ext string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && filepath.Ext(path) == ext {
+			s[path] = stamp{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+}
+
+// changes returns the sorted paths that were added, removed or modified
+// between the snapshots.
+func (s snapshot) changes(next snapshot) []string {
+	paths := []string{}
+	for path, st := range s {
+		if n, ok := next[path]; !ok || n != st {
+			paths = append(paths, path)
+		}
+	}
+	for path := range next {
+		if _, ok := s[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// mergePaths adds the paths that are not in the list yet.
+func mergePaths(list []string, paths []string) []string {
+	seen := map[string]bool{}
+	for _, path := range list {
+		seen[path] = true
+	}
+	for _, path := range paths {
+		if !seen[path] {
+			list = append(list, path)
+			seen[path] = true
+		}
+	}
+	return list
+}
+
+// describeChanges names the changed file, or counts them if there are several.
+func describeChanges(paths []string) string {
+	if len(paths) == 1 {
+		return paths[0] + " changed"
+	}
+	return fmt.Sprintf("%d files changed", len(paths))
+}
+
+func clock() string {
+	return time.Now().Format("15:04:05")
+}
+
+func indent(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	return "  " + strings.Join(lines, "\n  ")
+}
@@ -0,0 +1,208 @@
+// Configuration for a code generation run, loaded from a versioned json file.
+
+package codegen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigVersion is the version of the config file format understood by this
+// generator.
+const ConfigVersion = 1
+
+// Version is the version of the generator, recorded with every generated file.
+const Version = "1.1.0"
+
+// DefaultConfigFile is the config file used when none is specified.
+const DefaultConfigFile = "codegen.json"
+
+// Names of the layers that can be generated.
+const (
+	LayerDB      = "db"
+	LayerLogic   = "logic"
+	LayerGraphQL = "graphql"
+)
+
+// Layers lists the built in layers in the order they are generated.
+var Layers = []string{LayerDB, LayerLogic, LayerGraphQL}
+
+// Config holds the settings for a generation run.
+type Config struct {
+	Version int           `json:"version"` // Version of the config format
+	Root    string        `json:"root"`    // Root of the target repository
+	Layers  []string      `json:"layers"`  // Layers to generate, in order
+	DB      LayerConfig   `json:"db"`
+	Logic   LayerConfig   `json:"logic"`
+	GraphQL GraphQLConfig `json:"graphql"`
+
+	// Directory for the staging area and backups, relative to the root
+	StateDir string `json:"state_dir"`
+
+	// Glob patterns of declarative schema files, relative to the config file
+	SchemaFiles []string `json:"schema_files"`
+
+	// Output settings of registered generators that are not built in
+	Generators map[string]LayerConfig `json:"generators"`
+
+	// Directory of template overrides, relative to the config file
+	Templates string `json:"templates"`
+
+	// Number of files rendered at the same time, the number of CPUs if 0
+	Workers int `json:"workers,omitempty"`
+}
+
+// LayerConfig holds the output settings for a single layer.
+type LayerConfig struct {
+	Package string `json:"package"` // Package name of the generated code
+	Path    string `json:"path"`    // Output directory, relative to the root
+}
+
+// GraphQLConfig holds the output settings for the graphql layer.
+type GraphQLConfig struct {
+	LayerConfig
+	ResolversPackage string `json:"resolvers_package"`
+}
+
+// DefaultConfig returns the config that matches the layout of splits-go-api.
+func DefaultConfig() Config {
+	return Config{
+		Version: ConfigVersion,
+		Root:    "",
+		Layers:  []string{LayerDB, LayerLogic, LayerGraphQL},
+		DB: LayerConfig{
+			Package: "models",
+			Path:    "db/models",
+		},
+		Logic: LayerConfig{
+			Package: "logic",
+			Path:    "logic",
+		},
+		GraphQL: GraphQLConfig{
+			LayerConfig: LayerConfig{
+				Package: "graphql",
+				Path:    "api/graphql",
+			},
+			ResolversPackage: "resolvers",
+		},
+		StateDir:    ".codegen",
+		SchemaFiles: []string{},
+		Generators:  map[string]LayerConfig{},
+	}
+}
+
+// LoadConfig reads a config file on top of the defaults. A relative root, and
+// the other relative paths, are resolved against the directory of the config
+// file.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+
+	// Reset the version so that a file without one is caught
+	config.Version = 0
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return config, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	if config.Version == 0 {
+		return config, fmt.Errorf("config file %s has no version", path)
+	}
+	if config.Version > ConfigVersion {
+		return config, fmt.Errorf("config file %s has version %d, but this "+
+			"generator only understands up to version %d", path, config.Version,
+			ConfigVersion)
+	}
+
+	if config.Root != "" && !filepath.IsAbs(config.Root) {
+		config.Root = filepath.Join(filepath.Dir(path), config.Root)
+	}
+	if config.Templates != "" && !filepath.IsAbs(config.Templates) {
+		config.Templates = filepath.Join(filepath.Dir(path), config.Templates)
+	}
+	for i, pattern := range config.SchemaFiles {
+		if !filepath.IsAbs(pattern) {
+			config.SchemaFiles[i] = filepath.Join(filepath.Dir(path), pattern)
+		}
+	}
+	return config, nil
+}
+
+// Validate checks that the config can be used for a generation run.
+func (c Config) Validate() error {
+	if c.Root == "" {
+		return errors.New("no target root set")
+	}
+	for _, l := range c.Layers {
+		if _, ok := LookupGenerator(l); !ok {
+			return errors.New("unknown layer: " + l + " (valid layers are " +
+				strings.Join(GeneratorNames(), ", ") + ")")
+		}
+	}
+	if c.DB.Package == "" || c.Logic.Package == "" ||
+		c.GraphQL.Package == "" || c.GraphQL.ResolversPackage == "" {
+		return errors.New("every layer needs a package name")
+	}
+	if c.StateDir == "" || filepath.IsAbs(c.StateDir) {
+		return errors.New("the state dir must be relative to the root")
+	}
+	// The staged files and bases are kept relative to the root, so the state
+	// dir has to be inside of it
+	stateDir := filepath.Clean(c.StateDir)
+	if stateDir == "." || stateDir == ".." ||
+		strings.HasPrefix(stateDir, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("the state dir %s must be a directory inside the "+
+			"root", c.StateDir)
+	}
+	if c.Workers < 0 {
+		return errors.New("the number of workers can not be negative")
+	}
+	return nil
+}
+
+// HasLayer returns whether the layer is enabled.
+func (c Config) HasLayer(layer string) bool {
+	for _, l := range c.Layers {
+		if l == layer {
+			return true
+		}
+	}
+	return false
+}
+
+// DBDir is the output directory of the db layer.
+func (c Config) DBDir() string {
+	return filepath.Join(c.Root, c.DB.Path)
+}
+
+// LogicDir is the output directory of the logic layer.
+func (c Config) LogicDir() string {
+	return filepath.Join(c.Root, c.Logic.Path)
+}
+
+// GraphQLDir is the output directory of the graphql schema.
+func (c Config) GraphQLDir() string {
+	return filepath.Join(c.Root, c.GraphQL.Path)
+}
+
+// ResolversDir is the output directory of the graphql resolvers.
+func (c Config) ResolversDir() string {
+	return filepath.Join(c.GraphQLDir(), c.GraphQL.ResolversPackage)
+}
+
+// GeneratorDir is the output directory of a registered generator that is not
+// built in, as set in the generators section of the config.
+func (c Config) GeneratorDir(name string) string {
+	return filepath.Join(c.Root, c.Generators[name].Path)
+}
+
+// StatePath is the directory holding the staging area and backups.
+func (c Config) StatePath() string {
+	return filepath.Join(c.Root, c.StateDir)
+}
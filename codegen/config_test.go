@@ -0,0 +1,26 @@
+package codegen
+
+import "testing"
+
+func TestConfigValidateStateDir(t *testing.T) {
+	for stateDir, valid := range map[string]bool{
+		".codegen":          true,
+		"tools/../.codegen": true,
+		"":                  false,
+		"/tmp/state":        false,
+		".":                 false,
+		"..":                false,
+		"../x":              false,
+		"a/../../x":         false,
+	} {
+		config := DefaultConfig()
+		config.Root = "target"
+		config.StateDir = stateDir
+		err := config.Validate()
+		if valid && err != nil {
+			t.Errorf("%q: expected no error, got %v", stateDir, err)
+		} else if !valid && err == nil {
+			t.Errorf("%q: expected an error", stateDir)
+		}
+	}
+}
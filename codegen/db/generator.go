@@ -0,0 +1,106 @@
+// The built in generator of the db layer. It is registered when the package is
+// imported.
+
+package db
+
+import (
+	"path/filepath"
+	cg "splits-go-schema-codegen/codegen"
+	"strings"
+)
+
+func init() {
+	cg.RegisterGenerator(Generator{})
+}
+
+// Generator is the built in generator of the db layer.
+type Generator struct{}
+
+// Name returns the name of the db layer.
+func (Generator) Name() string {
+	return cg.LayerDB
+}
+
+// Dirs returns the db package.
+func (Generator) Dirs(config cg.Config) []string {
+	return []string{config.DBDir()}
+}
+
+// Validate checks the db files on disk, and returns their manual sections.
+func (g Generator) Validate(
+	config cg.Config,
+	schemas []cg.Schema,
+	owned map[string]cg.ManifestEntry,
+	mergeFlag bool,
+	forceFlag bool,
+) (map[string][]cg.ManualSection, error) {
+	return cg.ValidateOwnedFiles(g.Dirs(config), owned, mergeFlag, forceFlag)
+}
+
+// Render renders the selected files of the db layer in memory. The files
+// generated from every schema are always rendered.
+func (Generator) Render(
+	config cg.Config,
+	schemas []cg.Schema,
+	filter cg.Filter,
+	manualParts map[string][]cg.ManualSection,
+) ([]cg.File, error) {
+	dir := config.DBDir()
+	packageName := config.DB.Package
+	r := cg.Renderer{Layer: cg.LayerDB, Workers: config.Workers}
+
+	// Generate the constants
+	constantsFilePath := filepath.Join(dir, "constants.go")
+	r.Render(constantsFilePath, cg.Source{}, func() (string, error) {
+		return WriteConstants(schemas, manualParts[constantsFilePath],
+			packageName)
+	})
+
+	// Generate the node and edge definition code
+	for _, s := range schemas {
+		s := s
+		if filter.SelectsSchema(s.GetName()) {
+			filePath := filepath.Join(dir,
+				strings.ToLower(s.GetName())+"_node.go")
+			r.Render(filePath, cg.Source{Schema: s.GetName()},
+				func() (string, error) {
+					return WriteSchemaNode(s, manualParts[filePath],
+						packageName)
+				})
+		}
+		for _, e := range s.GetEdges() {
+			e := e
+			if !filter.SelectsEdge(s.GetName(), e.Name, e.CodeName) {
+				continue
+			}
+			edgeFilePath := filepath.Join(dir, strings.ToLower(e.Name)+"_edge.go")
+			r.Render(edgeFilePath, cg.Source{Schema: s.GetName(), Edge: e.Name},
+				func() (string, error) {
+					return WriteSchemaEdge(s, e, manualParts[edgeFilePath],
+						packageName)
+				})
+		}
+	}
+
+	// Generate the constraints
+	constraintFilePath := filepath.Join(dir, "constraints", "data",
+		"constraints.json")
+	r.Render(constraintFilePath, cg.Source{}, func() (string, error) {
+		return WriteConstraints(schemas)
+	})
+
+	// Generate the indices
+	indicesFilePath := filepath.Join(dir, "indices", "data", "indices.json")
+	r.Render(indicesFilePath, cg.Source{}, func() (string, error) {
+		return WriteIndices(schemas)
+	})
+
+	// Generate the db tests
+	autogenFilePath := filepath.Join(dir, "autogen_test.go")
+	r.Render(autogenFilePath, cg.Source{}, func() (string, error) {
+		return WriteAutogenTests(schemas, manualParts[autogenFilePath],
+			packageName)
+	})
+
+	return r.Result()
+}
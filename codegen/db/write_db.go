@@ -5,11 +5,7 @@
 package db
 
 import (
-	"crypto/md5"
-	"encoding/hex"
 	"encoding/json"
-	"fmt"
-	"go/format"
 	c "splits-go-api/db/models/constraints"
 	i "splits-go-api/db/models/indices"
 	cg "splits-go-schema-codegen/codegen"
@@ -17,71 +13,88 @@ import (
 )
 
 // WriteSchemaNode generates the string that represents a schema node.
-func WriteSchemaNode(s cg.Schema, packageName string) string {
+func WriteSchemaNode(
+	s cg.Schema,
+	manualParts []cg.ManualSection,
+	packageName string,
+) (string, error) {
+	manual := cg.NewManualParts(manualParts)
 
 	// Use templates to generate the node
-	sections := make([]string, 0, 11)
-	sections = append(sections, GetNodeFileHeaderCommentStr(s))
-	sections = append(sections, GetNodePackageStr(s, packageName))
-	sections = append(sections, GetNodeImportStr(s))
-	sections = append(sections, GetNodeStr(s))
-	sections = append(sections, GetNodeQueryStructStr(s))
-	sections = append(sections, GetNodeQueryConstructorStr(s))
-	sections = append(sections, GetNodeQueryWhereStr(s))
-	sections = append(sections, GetNodeQueryReturnStr(s))
-	sections = append(sections, GetNodeQueryOrderStr(s))
-	sections = append(sections, GetNodeQueryEdgesStr(s))
-	sections = append(sections, GetNodeMutatorStr(s))
-	sections = append(sections, GetNodeDeleterStr(s))
-	result := strings.Join(sections, "\n")
-	res, err := format.Source([]byte(result))
+	sections := cg.Sections{}
+	sections.Add(GetNodeFileHeaderCommentStr(s))
+	sections.Add(GetNodePackageStr(s, packageName))
+	sections.Add(GetNodeImportStr(
+		s, manual.Get(cg.ManualImports)))
+	sections.Add(GetExtraFunctionsStr(
+		manual.Get(cg.ManualFunctions)))
+	sections.Add(GetGeneratedFunctionsTagStr())
+	sections.Add(GetNodeStr(s))
+	sections.Add(GetNodeQueryStructStr(s))
+	sections.Add(GetNodeQueryConstructorStr(s))
+	sections.Add(GetNodeQueryWhereStr(s))
+	sections.Add(GetNodeQueryReturnStr(s))
+	sections.Add(GetNodeQueryOrderStr(s))
+	sections.Add(GetNodeQueryEdgesStr(s))
+	sections.Add(GetNodeMutatorStr(s))
+	sections.Add(GetNodeDeleterStr(s))
+	if err := manual.Err(); err != nil {
+		return "", err
+	}
+	result, err := sections.Join("\n")
 	if err != nil {
-		fmt.Println(result)
-		panic(err)
+		return "", err
 	}
-
-	// Generate the MD5 signature
-	sum := md5.Sum([]byte(res))
-	signature := hex.EncodeToString([]byte(sum[:]))
-
-	// Add the signature to the top of the file
-	return "// @SignedSource (" + signature + ")\n" + string(res)
+	res, err := cg.FormatSource(result)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
 }
 
 // WriteSchemaEdge generates the string that represents a schema edge.
-func WriteSchemaEdge(s cg.Schema, e cg.EdgeStruct, packageName string) string {
+func WriteSchemaEdge(
+	s cg.Schema,
+	e cg.EdgeStruct,
+	manualParts []cg.ManualSection,
+	packageName string,
+) (string, error) {
+	manual := cg.NewManualParts(manualParts)
 
 	// Use templates to generate the edge
-	sections := make([]string, 0, 11)
-	sections = append(sections, GetEdgeFileHeaderCommentStr(e))
-	sections = append(sections, GetEdgePackageStr(s, packageName))
-	sections = append(sections, GetEdgeImportStr(e))
-	sections = append(sections, GetEdgeStr(e))
-	sections = append(sections, GetEdgeQueryStructStr(e))
-	sections = append(sections, GetEdgeQueryConstructorStr(e))
-	sections = append(sections, GetEdgeQueryWhereStr(e))
-	sections = append(sections, GetEdgeQueryReturnStr(e))
-	sections = append(sections, GetEdgeQueryOrderStr(e))
-	sections = append(sections, GetEdgeQueryNodesStr(e))
-	sections = append(sections, GetEdgeMutatorStr(e))
-	sections = append(sections, GetEdgeDeleterStr(e))
-	result := strings.Join(sections, "\n")
-	res, err := format.Source([]byte(result))
+	sections := cg.Sections{}
+	sections.Add(GetEdgeFileHeaderCommentStr(e))
+	sections.Add(GetEdgePackageStr(s, packageName))
+	sections.Add(GetEdgeImportStr(
+		e, manual.Get(cg.ManualImports)))
+	sections.Add(GetExtraFunctionsStr(
+		manual.Get(cg.ManualFunctions)))
+	sections.Add(GetGeneratedFunctionsTagStr())
+	sections.Add(GetEdgeStr(e))
+	sections.Add(GetEdgeQueryStructStr(e))
+	sections.Add(GetEdgeQueryConstructorStr(e))
+	sections.Add(GetEdgeQueryWhereStr(e))
+	sections.Add(GetEdgeQueryReturnStr(e))
+	sections.Add(GetEdgeQueryOrderStr(e))
+	sections.Add(GetEdgeQueryNodesStr(e))
+	sections.Add(GetEdgeMutatorStr(e))
+	sections.Add(GetEdgeDeleterStr(e))
+	if err := manual.Err(); err != nil {
+		return "", err
+	}
+	result, err := sections.Join("\n")
 	if err != nil {
-		fmt.Println(result)
-		panic(err)
+		return "", err
 	}
-
-	// Generate the MD5 signature
-	sum := md5.Sum([]byte(res))
-	signature := hex.EncodeToString([]byte(sum[:]))
-
-	// Add the signature to the top of the file
-	return "// @SignedSource (" + signature + ")\n" + string(res)
+	res, err := cg.FormatSource(result)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
 }
 
 // WriteConstraints generates the string that represents the constraints.
-func WriteConstraints(schemas []cg.Schema) string {
+func WriteConstraints(schemas []cg.Schema) (string, error) {
 	cd := c.ConstraintData{
 		Nodes: []c.ConstraintNode{},
 		Edges: []c.ConstraintEdge{},
@@ -111,13 +124,13 @@ func WriteConstraints(schemas []cg.Schema) string {
 
 	res, err := json.MarshalIndent(cd, "", "  ")
 	if err != nil {
-		panic(err)
+		return "", err
 	}
-	return string(res)
+	return string(res), nil
 }
 
 // WriteIndices generates the string that represents the indices.
-func WriteIndices(schemas []cg.Schema) string {
+func WriteIndices(schemas []cg.Schema) (string, error) {
 	id := i.IndexData{
 		Nodes: []i.IndexNode{},
 	}
@@ -135,13 +148,103 @@ func WriteIndices(schemas []cg.Schema) string {
 
 	res, err := json.MarshalIndent(id, "", "  ")
 	if err != nil {
-		panic(err)
+		return "", err
+	}
+	return string(res), nil
+}
+
+// ConstantsFileHeaderCommentData is the data of the
+// db/constants_file_header_comment template.
+type ConstantsFileHeaderCommentData struct {
+	Name string
+}
+
+var constantsFileHeaderCommentTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/constants_file_header_comment",
+	Data: ConstantsFileHeaderCommentData{},
+	Text: "// Autogenerated {{.Name}} - regenerate with splits-go-schema-" +
+		"codegen\n// Force regeneration over hand edits with " +
+		"generate --force.\n",
+})
+
+// GetConstantsFileHeaderCommentStr generates an autogenerated tag.
+func GetConstantsFileHeaderCommentStr() (string, error) {
+	data := ConstantsFileHeaderCommentData{
+		Name: "Constants",
 	}
-	return string(res)
+	return constantsFileHeaderCommentTemplate.Exec(data)
+}
+
+// ConstantsPackageData is the data of the db/constants_package template.
+type ConstantsPackageData struct {
+	Package string
 }
 
+var constantsPackageTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/constants_package",
+	Data: ConstantsPackageData{},
+	Text: "package {{.Package}}\n",
+})
+
+// GetConstantsPackageStr generates the package tag.
+func GetConstantsPackageStr(packageName string) (string, error) {
+	data := ConstantsPackageData{
+		Package: packageName,
+	}
+	return constantsPackageTemplate.Exec(data)
+}
+
+// ConstantsImportData is the data of the db/constants_import template.
+type ConstantsImportData struct {
+	ManualPart string
+}
+
+var constantsImportTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/constants_import",
+	Data: ConstantsImportData{},
+	Text: "import (\n" +
+		cg.StartManualSection(cg.ManualImports) + "\n" +
+		"{{.ManualPart}}\n" +
+		cg.EndManual + "\n" +
+		")\n",
+})
+
+// GetConstantsImportStr generates the import block, which only has the manual
+// imports.
+func GetConstantsImportStr(manualPart string) (string, error) {
+	data := ConstantsImportData{
+		ManualPart: manualPart,
+	}
+	return constantsImportTemplate.Exec(data)
+}
+
+// ConstantsData is the data of the db/constants template.
+type ConstantsData struct {
+	Constants map[string]string
+}
+
+var constantsTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/constants",
+	Data: ConstantsData{},
+	Text: "var constants = struct {\n" +
+		"{{ range $var, $value := .Constants }}" +
+		"\t{{$var}} string\n" +
+		"{{ end }}" +
+		"} {\n" +
+		"{{ range $var, $value := .Constants }}" +
+		"\t{{$var}}: \"{{$value}}\",\n" +
+		"{{ end }}" +
+		"}",
+})
+
 // WriteConstants helps write some constants.
-func WriteConstants(schemas []cg.Schema) string {
+func WriteConstants(
+	schemas []cg.Schema,
+	manualParts []cg.ManualSection,
+	packageName string,
+) (string, error) {
+	manual := cg.NewManualParts(manualParts)
+
 	constants := map[string]string{}
 	for _, s := range schemas {
 		constants[s.GetName()+"Label"] = s.GetName()
@@ -149,128 +252,200 @@ func WriteConstants(schemas []cg.Schema) string {
 			constants[e.CodeName+"Label"] = e.Name
 		}
 	}
-	data := struct {
-		Constants map[string]string
-	}{
+	data := ConstantsData{
 		Constants: constants,
 	}
-	template :=
-		"package models\n\n" +
-			"var constants = struct {\n" +
-			"{{ range $var, $value := .Constants }}" +
-			"\t{{$var}} string\n" +
-			"{{ end }}" +
-			"} {\n" +
-			"{{ range $var, $value := .Constants }}" +
-			"\t{{$var}}: \"{{$value}}\",\n" +
-			"{{ end }}" +
-			"}"
-
-	result := cg.ExecTemplate(template, "constants", data, nil)
-	res, err := format.Source([]byte(result))
+
+	sections := cg.Sections{}
+	sections.Add(GetConstantsFileHeaderCommentStr())
+	sections.Add(GetConstantsPackageStr(packageName))
+	sections.Add(GetConstantsImportStr(
+		manual.Get(cg.ManualImports)))
+	sections.Add(GetExtraFunctionsStr(
+		manual.Get(cg.ManualFunctions)))
+	sections.Add(GetGeneratedFunctionsTagStr())
+	sections.Add(constantsTemplate.Exec(data))
+	if err := manual.Err(); err != nil {
+		return "", err
+	}
+	result, err := sections.Join("\n")
 	if err != nil {
-		fmt.Println(result)
-		panic(err)
+		return "", err
 	}
-	return string(res)
+	res, err := cg.FormatSource(result)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// ExtraFunctionsData is the data of the db/extra_functions template.
+type ExtraFunctionsData struct {
+	ManualPart string
+}
+
+var extraFunctionsTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/extra_functions",
+	Data: ExtraFunctionsData{},
+	Text: cg.StartManualSection(cg.ManualFunctions) + "\n" +
+		"{{.ManualPart}}\n" +
+		cg.EndManual + "\n",
+})
+
+// GetExtraFunctionsStr adds a manual section for user defined functions, such
+// as custom query helpers.
+func GetExtraFunctionsStr(manualPart string) (string, error) {
+	data := ExtraFunctionsData{
+		ManualPart: manualPart,
+	}
+	return extraFunctionsTemplate.Exec(data)
+}
+
+// GetGeneratedFunctionsTagStr writes a generated functions tagline.
+func GetGeneratedFunctionsTagStr() (string, error) {
+	return "// === GENERATED FUNCTIONS === \n", nil
 }
 
 // =============================================================================
 // Node generation
 // =============================================================================
 
+// NodeFileHeaderCommentData is the data of the db/node_file_header_comment
+// template.
+type NodeFileHeaderCommentData struct {
+	Name string
+}
+
+var nodeFileHeaderCommentTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/node_file_header_comment",
+	Data: NodeFileHeaderCommentData{},
+	Text: "// Autogenerated {{.Name}} - regenerate with splits-go-schema-" +
+		"codegen\n// Force regeneration over hand edits with " +
+		"generate --force.\n",
+})
+
 // GetNodeFileHeaderCommentStr generates an autogenerated tag.
-func GetNodeFileHeaderCommentStr(s cg.Schema) string {
-	data := struct {
-		Name string
-	}{
+func GetNodeFileHeaderCommentStr(s cg.Schema) (string, error) {
+	data := NodeFileHeaderCommentData{
 		Name: s.GetName(),
 	}
-	template := "// Autogenerated {{.Name}} - regenerate with splits-go-schema-" +
-		"codegen\n// Force autogen by deleting the @SignedSource line.\n"
-	return cg.ExecTemplate(template, "node_file_header_comment", data, nil)
+	return nodeFileHeaderCommentTemplate.Exec(data)
 }
 
+// NodePackageData is the data of the db/node_package template.
+type NodePackageData struct {
+	Package string
+}
+
+var nodePackageTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/node_package",
+	Data: NodePackageData{},
+	Text: "package {{.Package}}\n",
+})
+
 // GetNodePackageStr generates the package tag.
-func GetNodePackageStr(s cg.Schema, packageName string) string {
-	data := struct {
-		Package string
-	}{
+func GetNodePackageStr(s cg.Schema, packageName string) (string, error) {
+	data := NodePackageData{
 		Package: packageName,
 	}
-	template := "package {{.Package}}\n"
-	return cg.ExecTemplate(template, "node_package", data, nil)
+	return nodePackageTemplate.Exec(data)
+}
+
+// NodeImportData is the data of the db/node_import template.
+type NodeImportData struct {
+	Imports    []string
+	ManualPart string
 }
 
+var nodeImportTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/node_import",
+	Data: NodeImportData{},
+	Text: "import (\n" +
+		"{{range .Imports}} \t{{.}}\n {{end}}" +
+		"\n" +
+		cg.StartManualSection(cg.ManualImports) + "\n" +
+		"{{.ManualPart}}\n" +
+		cg.EndManual + "\n" +
+		")\n",
+})
+
 // GetNodeImportStr generates the import statements.
-func GetNodeImportStr(s cg.Schema) string {
-	data := struct {
-		Imports []string
-	}{
+func GetNodeImportStr(s cg.Schema, manualPart string) (string, error) {
+	data := NodeImportData{
 		Imports: []string{
 			"\"splits-go-api/db/models/base\"",
 			"p \"splits-go-api/db/models/predicates\"",
 		},
+		ManualPart: manualPart,
 	}
-	template :=
-		"import (\n" +
-			"{{range .Imports}} \t{{.}}\n {{end}}" +
-			")\n"
-	return cg.ExecTemplate(template, "node_import", data, nil)
+	return nodeImportTemplate.Exec(data)
 }
 
-// GetNodeStr generates the base node definition.
-func GetNodeStr(s cg.Schema) string {
-	edgePointers := make([]cg.EdgeStruct, 0, len(s.GetEdgePointers()))
-	for _, v := range s.GetEdgePointers() {
-		edgePointers = append(edgePointers, v)
-	}
-	data := struct {
-		Name         string
-		Fields       []cg.FieldStruct
-		Edges        []cg.EdgeStruct
-		EdgePointers []cg.EdgeStruct
-	}{
-		Name:         s.GetName(),
-		Fields:       s.GetFields(),
-		Edges:        s.GetEdges(),
-		EdgePointers: edgePointers,
-	}
-	template := "// {{.Name}}Node is the base {{.Name}} definition.\n" +
+// NodeData is the data of the db/node template.
+type NodeData struct {
+	Name         string
+	Fields       []cg.FieldStruct
+	Edges        []cg.EdgeStruct
+	EdgePointers []cg.EdgeStruct
+}
+
+var nodeTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/node",
+	Data: NodeData{},
+	Text: "// {{.Name}}Node is the base {{.Name}} definition.\n" +
 		"type {{.Name}}Node struct {\n" +
 		"\t// Node fields\n" +
 		"{{range .Fields}} \t{{.CodeName}} {{.Type}}\n{{end}}\n" +
 		"\t// Edges\n" +
 		"{{range .Edges}} \t{{.CodeName}} *{{.CodeName}}Edge\n{{end}}" +
 		"{{range .EdgePointers}} \t{{.CodeName}} *{{.CodeName}}Edge\n{{end}}\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "node", data, nil)
+		"}\n",
+})
+
+// GetNodeStr generates the base node definition.
+func GetNodeStr(s cg.Schema) (string, error) {
+	data := NodeData{
+		Name:         s.GetName(),
+		Fields:       s.GetFields(),
+		Edges:        s.GetEdges(),
+		EdgePointers: cg.SortedEdgePointers(s),
+	}
+	return nodeTemplate.Exec(data)
+}
+
+// NodeQueryStructData is the data of the db/node_query_struct template.
+type NodeQueryStructData struct {
+	Name string
 }
 
+var nodeQueryStructTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/node_query_struct",
+	Data: NodeQueryStructData{},
+	Text: "// {{.Name}}Q is the base {{.Name}} query struct.\n" +
+		"type {{.Name}}Q struct {\n" +
+		"\tbase.Query\n" +
+		"}\n",
+})
+
 // GetNodeQueryStructStr generates the base node query struct.
-func GetNodeQueryStructStr(s cg.Schema) string {
-	data := struct {
-		Name string
-	}{
+func GetNodeQueryStructStr(s cg.Schema) (string, error) {
+	data := NodeQueryStructData{
 		Name: s.GetName(),
 	}
-	template := "// {{.Name}}Q is the base {{.Name}} query struct.\n" +
-		"type {{.Name}}Q struct {\n" +
-		"\tbase.Query\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "node_query", data, nil)
+	return nodeQueryStructTemplate.Exec(data)
 }
 
-// GetNodeQueryConstructorStr generates the base node query constructor.
-func GetNodeQueryConstructorStr(s cg.Schema) string {
-	data := struct {
-		Name    string
-		VarName string
-	}{
-		Name:    s.GetName(),
-		VarName: strings.ToLower(string(s.GetName()[0])),
-	}
-	template := "// {{.Name}}Query is the {{.Name}} query constructor.\n" +
+// NodeQueryConstructorData is the data of the db/node_query_constructor
+// template.
+type NodeQueryConstructorData struct {
+	Name    string
+	VarName string
+}
+
+var nodeQueryConstructorTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/node_query_constructor",
+	Data: NodeQueryConstructorData{},
+	Text: "// {{.Name}}Query is the {{.Name}} query constructor.\n" +
 		"func {{.Name}}Query() *{{.Name}}Q {\n" +
 		"\t{{.VarName}} := new({{.Name}}Q)\n" +
 		"\t{{.VarName}}.Fields = []p.WhereClauseStruct{}\n" +
@@ -279,22 +454,29 @@ func GetNodeQueryConstructorStr(s cg.Schema) string {
 		"\t{{.VarName}}.Prefix = 'a'\n " +
 		"\t{{.VarName}}.Label = constants.{{.Name}}Label\n" +
 		"\treturn {{.VarName}}\n" +
-		"}"
-	return cg.ExecTemplate(template, "node_query_constructor", data, nil)
-}
+		"}",
+})
 
-// GetNodeQueryWhereStr generates all the WhereClause functions for a node.
-func GetNodeQueryWhereStr(s cg.Schema) string {
-	data := struct {
-		Name    string
-		VarName string
-		Fields  []cg.FieldStruct
-	}{
+// GetNodeQueryConstructorStr generates the base node query constructor.
+func GetNodeQueryConstructorStr(s cg.Schema) (string, error) {
+	data := NodeQueryConstructorData{
 		Name:    s.GetName(),
-		VarName: strings.ToLower(string(s.GetName()[0])) + "q",
-		Fields:  s.GetFields(),
+		VarName: strings.ToLower(string(s.GetName()[0])),
 	}
-	template := "{{range .Fields}}" +
+	return nodeQueryConstructorTemplate.Exec(data)
+}
+
+// NodeQueryWhereData is the data of the db/node_query_where template.
+type NodeQueryWhereData struct {
+	Name    string
+	VarName string
+	Fields  []cg.FieldStruct
+}
+
+var nodeQueryWhereTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/node_query_where",
+	Data: NodeQueryWhereData{},
+	Text: "{{range .Fields}}" +
 		"// Where{{.CodeName}} is the query where clause for {{.CodeName}}.\n" +
 		"func ({{$.VarName}} *{{$.Name}}Q) Where{{.CodeName}}(pred p.Predicate) " +
 		"*{{$.Name}}Q {\n" +
@@ -302,46 +484,61 @@ func GetNodeQueryWhereStr(s cg.Schema) string {
 		"append({{$.VarName}}.Fields, p.WhereClause(\"{{.Name}}\", pred))\n" +
 		"return {{$.VarName}}\n" +
 		"}\n\n" +
-		"{{end}}"
-	return cg.ExecTemplate(template, "node_query_where", data, nil)
-}
+		"{{end}}",
+})
 
-// GetNodeQueryReturnStr generates all the Return clause functions for a node.
-func GetNodeQueryReturnStr(s cg.Schema) string {
-	data := struct {
-		Name    string
-		VarName string
-		Fields  []cg.FieldStruct
-	}{
+// GetNodeQueryWhereStr generates all the WhereClause functions for a node.
+func GetNodeQueryWhereStr(s cg.Schema) (string, error) {
+	data := NodeQueryWhereData{
 		Name:    s.GetName(),
 		VarName: strings.ToLower(string(s.GetName()[0])) + "q",
 		Fields:  s.GetFields(),
 	}
+	return nodeQueryWhereTemplate.Exec(data)
+}
+
+// NodeQueryReturnData is the data of the db/node_query_return template.
+type NodeQueryReturnData struct {
+	Name    string
+	VarName string
+	Fields  []cg.FieldStruct
+}
 
-	template := "{{range .Fields}}" +
+var nodeQueryReturnTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/node_query_return",
+	Data: NodeQueryReturnData{},
+	Text: "{{range .Fields}}" +
 		"// Return{{.CodeName}} is the return clause for {{.CodeName}}.\n" +
 		"func ({{$.VarName}} *{{$.Name}}Q) Return{{.CodeName}}() *{{$.Name}}Q {\n" +
 		"{{$.VarName}}.Return = append({{$.VarName}}.Return, p.ReturnClause" +
 		"(\"{{.Name}}\"))\n" +
 		"return {{$.VarName}}\n" +
 		"}\n\n" +
-		"{{end}}"
-	return cg.ExecTemplate(template, "node_query_return", data, nil)
-}
+		"{{end}}",
+})
 
-// GetNodeQueryOrderStr generates all the Order clause functions for a node.
-func GetNodeQueryOrderStr(s cg.Schema) string {
-	data := struct {
-		Name    string
-		VarName string
-		Fields  []cg.FieldStruct
-	}{
+// GetNodeQueryReturnStr generates all the Return clause functions for a node.
+func GetNodeQueryReturnStr(s cg.Schema) (string, error) {
+	data := NodeQueryReturnData{
 		Name:    s.GetName(),
 		VarName: strings.ToLower(string(s.GetName()[0])) + "q",
 		Fields:  s.GetFields(),
 	}
 
-	template := "{{range .Fields}}" +
+	return nodeQueryReturnTemplate.Exec(data)
+}
+
+// NodeQueryOrderData is the data of the db/node_query_order template.
+type NodeQueryOrderData struct {
+	Name    string
+	VarName string
+	Fields  []cg.FieldStruct
+}
+
+var nodeQueryOrderTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/node_query_order",
+	Data: NodeQueryOrderData{},
+	Text: "{{range .Fields}}" +
 		"// OrderBy{{.CodeName}} is the order clause for {{.CodeName}}.\n" +
 		"func ({{$.VarName}} *{{$.Name}}Q) OrderBy{{.CodeName}}(desc bool) " +
 		"*{{$.Name}}Q {\n" +
@@ -349,28 +546,32 @@ func GetNodeQueryOrderStr(s cg.Schema) string {
 		"(\"{{.Name}}\", desc))\n" +
 		"return {{$.VarName}}\n" +
 		"}\n\n" +
-		"{{end}}"
-	return cg.ExecTemplate(template, "node_query_order", data, nil)
-}
+		"{{end}}",
+})
 
-// GetNodeQueryEdgesStr generates the Query functions for traversing the graph.
-func GetNodeQueryEdgesStr(s cg.Schema) string {
-	edgePointers := make([]cg.EdgeStruct, 0, len(s.GetEdgePointers()))
-	for _, v := range s.GetEdgePointers() {
-		edgePointers = append(edgePointers, v)
-	}
-	data := struct {
-		Name         string
-		VarName      string
-		Edges        []cg.EdgeStruct
-		EdgePointers []cg.EdgeStruct
-	}{
-		Name:         s.GetName(),
-		VarName:      strings.ToLower(string(s.GetName()[0])) + "q",
-		Edges:        s.GetEdges(),
-		EdgePointers: edgePointers,
+// GetNodeQueryOrderStr generates all the Order clause functions for a node.
+func GetNodeQueryOrderStr(s cg.Schema) (string, error) {
+	data := NodeQueryOrderData{
+		Name:    s.GetName(),
+		VarName: strings.ToLower(string(s.GetName()[0])) + "q",
+		Fields:  s.GetFields(),
 	}
-	template := "{{range .Edges}}" +
+
+	return nodeQueryOrderTemplate.Exec(data)
+}
+
+// NodeQueryEdgesData is the data of the db/node_query_edges template.
+type NodeQueryEdgesData struct {
+	Name         string
+	VarName      string
+	Edges        []cg.EdgeStruct
+	EdgePointers []cg.EdgeStruct
+}
+
+var nodeQueryEdgesTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/node_query_edges",
+	Data: NodeQueryEdgesData{},
+	Text: "{{range .Edges}}" +
 		"// Query{{.CodeName}} traverses the graph to the {{.CodeName}} edge.\n" +
 		"func ({{$.VarName}} *{{$.Name}}Q) Query{{.CodeName}}() *{{.CodeName}}Q " +
 		"{\n" +
@@ -392,24 +593,32 @@ func GetNodeQueryEdgesStr(s cg.Schema) string {
 		"\t{{$.VarName}}.Next = &query.Query\n" +
 		"\treturn query\n" +
 		"}\n\n" +
-		"{{end}}"
+		"{{end}}",
+})
 
-	return cg.ExecTemplate(template, "node_query_edges", data, nil)
+// GetNodeQueryEdgesStr generates the Query functions for traversing the graph.
+func GetNodeQueryEdgesStr(s cg.Schema) (string, error) {
+	data := NodeQueryEdgesData{
+		Name:         s.GetName(),
+		VarName:      strings.ToLower(string(s.GetName()[0])) + "q",
+		Edges:        s.GetEdges(),
+		EdgePointers: cg.SortedEdgePointers(s),
+	}
+	return nodeQueryEdgesTemplate.Exec(data)
 }
 
-// GetNodeMutatorStr generates the mutator helper functions.
-func GetNodeMutatorStr(s cg.Schema) string {
-	data := struct {
-		Name    string
-		VarName string
-		Fields  []cg.FieldStruct
-	}{
-		Name:    s.GetName(),
-		VarName: strings.ToLower(string(s.GetName()[0])) + "m",
-		Fields:  s.GetFields(),
-	}
+// NodeMutatorData is the data of the db/node_mutator template.
+type NodeMutatorData struct {
+	Name    string
+	VarName string
+	Fields  []cg.FieldStruct
+}
+
+var nodeMutatorTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/node_mutator",
+	Data: NodeMutatorData{},
 	// Base mutator
-	template := "// {{.Name}}M is the base {{.Name}} mutator struct.\n" +
+	Text: "// {{.Name}}M is the base {{.Name}} mutator struct.\n" +
 		"type {{.Name}}M struct {\n" +
 		"\tbase.NodeMutator\n" +
 		"}\n\n" +
@@ -441,28 +650,33 @@ func GetNodeMutatorStr(s cg.Schema) string {
 		"{{$.VarName}}.DefaultFields[\"{{.Name}}\"] = v\n" +
 		"\treturn {{$.VarName}}\n" +
 		"}\n\n" +
-		"{{end}}"
+		"{{end}}",
+})
 
-	return cg.ExecTemplate(template, "node_mutator", data, nil)
+// GetNodeMutatorStr generates the mutator helper functions.
+func GetNodeMutatorStr(s cg.Schema) (string, error) {
+	data := NodeMutatorData{
+		Name:    s.GetName(),
+		VarName: strings.ToLower(string(s.GetName()[0])) + "m",
+		Fields:  s.GetFields(),
+	}
+	return nodeMutatorTemplate.Exec(data)
 }
 
-// GetNodeDeleterStr generates the deleter helper functions.
-func GetNodeDeleterStr(s cg.Schema) string {
-	data := struct {
-		Name         string
-		VarName      string
-		Fields       []cg.FieldStruct
-		Edges        []cg.EdgeStruct
-		EdgePointers map[string]cg.EdgeStruct
-	}{
-		Name:         s.GetName(),
-		VarName:      strings.ToLower(string(s.GetName()[0])) + "d",
-		Fields:       s.GetFields(),
-		Edges:        s.GetEdges(),
-		EdgePointers: s.GetEdgePointers(),
-	}
+// NodeDeleterData is the data of the db/node_deleter template.
+type NodeDeleterData struct {
+	Name         string
+	VarName      string
+	Fields       []cg.FieldStruct
+	Edges        []cg.EdgeStruct
+	EdgePointers map[string]cg.EdgeStruct
+}
+
+var nodeDeleterTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/node_deleter",
+	Data: NodeDeleterData{},
 	// Base deleter
-	template := "// {{.Name}}D is the base {{.Name}} deleter struct.\n" +
+	Text: "// {{.Name}}D is the base {{.Name}} deleter struct.\n" +
 		"type {{.Name}}D struct {\n" +
 		"\nbase.Deleter\n" +
 		"}\n\n" +
@@ -521,105 +735,161 @@ func GetNodeDeleterStr(s cg.Schema) string {
 		"\t{{$.VarName}}.Next = &deleter.Deleter\n" +
 		"\treturn deleter\n" +
 		"}\n\n" +
-		"{{end}}"
+		"{{end}}",
+})
 
-	return cg.ExecTemplate(template, "node_deleter", data, nil)
+// GetNodeDeleterStr generates the deleter helper functions.
+func GetNodeDeleterStr(s cg.Schema) (string, error) {
+	data := NodeDeleterData{
+		Name:         s.GetName(),
+		VarName:      strings.ToLower(string(s.GetName()[0])) + "d",
+		Fields:       s.GetFields(),
+		Edges:        s.GetEdges(),
+		EdgePointers: s.GetEdgePointers(),
+	}
+	return nodeDeleterTemplate.Exec(data)
 }
 
 // =============================================================================
 // Edge generation
 // =============================================================================
 
+// EdgeFileHeaderCommentData is the data of the db/edge_file_header_comment
+// template.
+type EdgeFileHeaderCommentData struct {
+	Name string
+}
+
+var edgeFileHeaderCommentTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/edge_file_header_comment",
+	Data: EdgeFileHeaderCommentData{},
+	Text: "// Autogenerated {{.Name}} - regenerate with " +
+		"splits-go-schema-codegen\n" +
+		"// Force regeneration over hand edits with generate --force.\n",
+})
+
 // GetEdgeFileHeaderCommentStr generates an autogenerated tag.
-func GetEdgeFileHeaderCommentStr(e cg.EdgeStruct) string {
-	data := struct {
-		Name string
-	}{
+func GetEdgeFileHeaderCommentStr(e cg.EdgeStruct) (string, error) {
+	data := EdgeFileHeaderCommentData{
 		Name: e.CodeName,
 	}
-	template := "// Autogenerated {{.Name}} - regenerate with " +
-		"splits-go-schema-codegen\n" +
-		"// Force autogen by deleting the @SignedSource line.\n"
-	return cg.ExecTemplate(template, "edge_file_header_comment", data, nil)
+	return edgeFileHeaderCommentTemplate.Exec(data)
+}
+
+// EdgePackageData is the data of the db/edge_package template.
+type EdgePackageData struct {
+	Package string
 }
 
+var edgePackageTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/edge_package",
+	Data: EdgePackageData{},
+	Text: "package {{.Package}}\n",
+})
+
 // GetEdgePackageStr generates the package tag.
-func GetEdgePackageStr(s cg.Schema, packageName string) string {
-	data := struct {
-		Package string
-	}{
+func GetEdgePackageStr(s cg.Schema, packageName string) (string, error) {
+	data := EdgePackageData{
 		Package: packageName,
 	}
-	template := "package {{.Package}}\n"
-	return cg.ExecTemplate(template, "edge_package", data, nil)
+	return edgePackageTemplate.Exec(data)
 }
 
+// EdgeImportData is the data of the db/edge_import template.
+type EdgeImportData struct {
+	Imports    []string
+	ManualPart string
+}
+
+var edgeImportTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/edge_import",
+	Data: EdgeImportData{},
+	Text: "import (\n" +
+		"{{range .Imports}} \t{{.}}\n {{end}}" +
+		"\n" +
+		cg.StartManualSection(cg.ManualImports) + "\n" +
+		"{{.ManualPart}}\n" +
+		cg.EndManual + "\n" +
+		")\n",
+})
+
 // GetEdgeImportStr generates the import statements.
-func GetEdgeImportStr(e cg.EdgeStruct) string {
-	data := struct {
-		Imports []string
-	}{
+func GetEdgeImportStr(e cg.EdgeStruct, manualPart string) (string, error) {
+	data := EdgeImportData{
 		Imports: []string{
 			"\"splits-go-api/db/models/base\"",
 			"p \"splits-go-api/db/models/predicates\"",
 		},
+		ManualPart: manualPart,
 	}
-	template :=
-		"import (\n" +
-			"{{range .Imports}} \t{{.}}\n {{end}}" +
-			")\n"
-	return cg.ExecTemplate(template, "edge_import", data, nil)
+	return edgeImportTemplate.Exec(data)
+}
+
+// EdgeData is the data of the db/edge template.
+type EdgeData struct {
+	Name     string
+	CodeName string
+	Fields   []cg.EdgeFieldStruct
+	FromNode cg.Schema
+	ToNode   cg.Schema
 }
 
+var edgeTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/edge",
+	Data: EdgeData{},
+	Text: "// {{.CodeName}}Edge is the base {{.CodeName}} definition.\n" +
+		"type {{.CodeName}}Edge struct {\n" +
+		"\t// Edge fields\n" +
+		"{{range .Fields}} \t{{.CodeName}} {{.Type}}\n{{end}}\n" +
+		"}\n",
+})
+
 // GetEdgeStr generates the base edge definition.
-func GetEdgeStr(e cg.EdgeStruct) string {
-	data := struct {
-		Name     string
-		CodeName string
-		Fields   []cg.EdgeFieldStruct
-		FromNode cg.Schema
-		ToNode   cg.Schema
-	}{
+func GetEdgeStr(e cg.EdgeStruct) (string, error) {
+	data := EdgeData{
 		Name:     e.Name,
 		CodeName: e.CodeName,
 		Fields:   e.Fields,
 		FromNode: e.FromNode,
 		ToNode:   e.ToNode,
 	}
-	template := "// {{.CodeName}}Edge is the base {{.CodeName}} definition.\n" +
-		"type {{.CodeName}}Edge struct {\n" +
-		"\t// Edge fields\n" +
-		"{{range .Fields}} \t{{.CodeName}} {{.Type}}\n{{end}}\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge", data, nil)
+	return edgeTemplate.Exec(data)
 }
 
+// EdgeQueryStructData is the data of the db/edge_query_struct template.
+type EdgeQueryStructData struct {
+	Name string
+}
+
+var edgeQueryStructTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/edge_query_struct",
+	Data: EdgeQueryStructData{},
+	Text: "// {{.Name}}Q is the base {{.Name}} query struct.\n" +
+		"type {{.Name}}Q struct {\n" +
+		"\tbase.Query\n" +
+		"}\n",
+})
+
 // GetEdgeQueryStructStr generates the base edge query struct.
-func GetEdgeQueryStructStr(e cg.EdgeStruct) string {
-	data := struct {
-		Name string
-	}{
+func GetEdgeQueryStructStr(e cg.EdgeStruct) (string, error) {
+	data := EdgeQueryStructData{
 		Name: e.CodeName,
 	}
-	template := "// {{.Name}}Q is the base {{.Name}} query struct.\n" +
-		"type {{.Name}}Q struct {\n" +
-		"\tbase.Query\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge_query", data, nil)
+	return edgeQueryStructTemplate.Exec(data)
 }
 
-// GetEdgeQueryConstructorStr generates the base edge query constructor.
-func GetEdgeQueryConstructorStr(e cg.EdgeStruct) string {
-	data := struct {
-		Name     string
-		CodeName string
-		VarName  string
-	}{
-		Name:     e.Name,
-		CodeName: e.CodeName,
-		VarName:  strings.ToLower(string(e.Name[0])),
-	}
-	t := "// {{.CodeName}}Query is the {{.CodeName}} query constructor.\n" +
+// EdgeQueryConstructorData is the data of the db/edge_query_constructor
+// template.
+type EdgeQueryConstructorData struct {
+	Name     string
+	CodeName string
+	VarName  string
+}
+
+var edgeQueryConstructorTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/edge_query_constructor",
+	Data: EdgeQueryConstructorData{},
+	Text: "// {{.CodeName}}Query is the {{.CodeName}} query constructor.\n" +
 		"func {{.CodeName}}Query() *{{.CodeName}}Q {\n" +
 		"\t{{.VarName}} := new({{.CodeName}}Q)\n" +
 		"\t{{.VarName}}.Fields = []p.WhereClauseStruct{}\n" +
@@ -628,22 +898,30 @@ func GetEdgeQueryConstructorStr(e cg.EdgeStruct) string {
 		"\t{{.VarName}}.Prefix = 'a'\n" +
 		"\t{{.VarName}}.Label = constants.{{.CodeName}}Label\n" +
 		"\treturn {{.VarName}}\n" +
-		"}\n"
-	return cg.ExecTemplate(t, "node_query_constructor", data, nil)
-}
+		"}\n",
+})
 
-// GetEdgeQueryWhereStr generates all the WhereClause functions for an edge.
-func GetEdgeQueryWhereStr(e cg.EdgeStruct) string {
-	data := struct {
-		Name    string
-		VarName string
-		Fields  []cg.EdgeFieldStruct
-	}{
-		Name:    e.CodeName,
-		VarName: strings.ToLower(string(e.CodeName[0])) + "q",
-		Fields:  e.Fields,
+// GetEdgeQueryConstructorStr generates the base edge query constructor.
+func GetEdgeQueryConstructorStr(e cg.EdgeStruct) (string, error) {
+	data := EdgeQueryConstructorData{
+		Name:     e.Name,
+		CodeName: e.CodeName,
+		VarName:  strings.ToLower(string(e.Name[0])),
 	}
-	t := "{{range .Fields}}" +
+	return edgeQueryConstructorTemplate.Exec(data)
+}
+
+// EdgeQueryWhereData is the data of the db/edge_query_where template.
+type EdgeQueryWhereData struct {
+	Name    string
+	VarName string
+	Fields  []cg.EdgeFieldStruct
+}
+
+var edgeQueryWhereTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/edge_query_where",
+	Data: EdgeQueryWhereData{},
+	Text: "{{range .Fields}}" +
 		"// Where{{.CodeName}} is the where clause for {{.CodeName}}.\n" +
 		"func ({{$.VarName}} *{{$.Name}}Q) Where{{.CodeName}}(pred p.Predicate) " +
 		"*{{$.Name}}Q {\n" +
@@ -651,74 +929,95 @@ func GetEdgeQueryWhereStr(e cg.EdgeStruct) string {
 		"append({{$.VarName}}.Fields, p.WhereClause(\"{{.Name}}\", pred))\n" +
 		"return {{$.VarName}}\n" +
 		"}\n\n" +
-		"{{end}}"
-	return cg.ExecTemplate(t, "edge_query_where", data, nil)
-}
+		"{{end}}",
+})
 
-// GetEdgeQueryReturnStr generates all the Return clause functions for an edge.
-func GetEdgeQueryReturnStr(e cg.EdgeStruct) string {
-	data := struct {
-		Name    string
-		VarName string
-		Fields  []cg.EdgeFieldStruct
-	}{
+// GetEdgeQueryWhereStr generates all the WhereClause functions for an edge.
+func GetEdgeQueryWhereStr(e cg.EdgeStruct) (string, error) {
+	data := EdgeQueryWhereData{
 		Name:    e.CodeName,
-		VarName: strings.ToLower(string(e.Name[0])) + "q",
+		VarName: strings.ToLower(string(e.CodeName[0])) + "q",
 		Fields:  e.Fields,
 	}
+	return edgeQueryWhereTemplate.Exec(data)
+}
+
+// EdgeQueryReturnData is the data of the db/edge_query_return template.
+type EdgeQueryReturnData struct {
+	Name    string
+	VarName string
+	Fields  []cg.EdgeFieldStruct
+}
 
-	template := "{{range .Fields}}" +
+var edgeQueryReturnTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/edge_query_return",
+	Data: EdgeQueryReturnData{},
+	Text: "{{range .Fields}}" +
 		"// Return{{.CodeName}} is the return clause for {{.CodeName}}\n" +
 		"func ({{$.VarName}} *{{$.Name}}Q) Return{{.CodeName}}() *{{$.Name}}Q {\n" +
 		"{{$.VarName}}.Return = append({{$.VarName}}.Return, p.ReturnClause" +
 		"(\"{{.Name}}\"))\n" +
 		"return {{$.VarName}}\n" +
 		"}\n\n" +
-		"{{end}}"
-	return cg.ExecTemplate(template, "edge_query_return", data, nil)
-}
+		"{{end}}",
+})
 
-// GetEdgeQueryOrderStr generates all the Order clause functions for an edge.
-func GetEdgeQueryOrderStr(e cg.EdgeStruct) string {
-	data := struct {
-		Name    string
-		VarName string
-		Fields  []cg.EdgeFieldStruct
-	}{
+// GetEdgeQueryReturnStr generates all the Return clause functions for an edge.
+func GetEdgeQueryReturnStr(e cg.EdgeStruct) (string, error) {
+	data := EdgeQueryReturnData{
 		Name:    e.CodeName,
 		VarName: strings.ToLower(string(e.Name[0])) + "q",
 		Fields:  e.Fields,
 	}
 
-	template := "{{range .Fields}}" +
+	return edgeQueryReturnTemplate.Exec(data)
+}
+
+// EdgeQueryOrderData is the data of the db/edge_query_order template.
+type EdgeQueryOrderData struct {
+	Name    string
+	VarName string
+	Fields  []cg.EdgeFieldStruct
+}
+
+var edgeQueryOrderTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/edge_query_order",
+	Data: EdgeQueryOrderData{},
+	Text: "{{range .Fields}}" +
 		"// OrderBy{{.CodeName}} is the return clause for {{.CodeName}}\n" +
 		"func ({{$.VarName}} *{{$.Name}}Q) OrderBy{{.CodeName}}(desc bool) *{{$.Name}}Q {\n" +
 		"{{$.VarName}}.Order = append({{$.VarName}}.Order, p.OrderClause" +
 		"(\"{{.Name}}\", desc))\n" +
 		"return {{$.VarName}}\n" +
 		"}\n\n" +
-		"{{end}}"
-	return cg.ExecTemplate(template, "edge_query_order", data, nil)
-}
+		"{{end}}",
+})
 
-// GetEdgeQueryNodesStr generates the Query functions for traversing the graph.
-func GetEdgeQueryNodesStr(e cg.EdgeStruct) string {
-	data := struct {
-		Name           string
-		CodeName       string
-		VarName        string
-		FromNode       string
-		ToNode         string
-		DifferentNodes bool
-	}{
-		Name:           e.Name,
-		CodeName:       e.CodeName,
-		VarName:        strings.ToLower(string(e.Name[0])) + "q",
-		FromNode:       e.FromNode.GetName(),
-		ToNode:         e.ToNode.GetName(),
-		DifferentNodes: e.FromNode.GetName() != e.ToNode.GetName(),
+// GetEdgeQueryOrderStr generates all the Order clause functions for an edge.
+func GetEdgeQueryOrderStr(e cg.EdgeStruct) (string, error) {
+	data := EdgeQueryOrderData{
+		Name:    e.CodeName,
+		VarName: strings.ToLower(string(e.Name[0])) + "q",
+		Fields:  e.Fields,
 	}
-	t := "// Query{{.FromNode}} traverses the graph to the {{.FromNode}} node." +
+
+	return edgeQueryOrderTemplate.Exec(data)
+}
+
+// EdgeQueryNodesData is the data of the db/edge_query_nodes template.
+type EdgeQueryNodesData struct {
+	Name           string
+	CodeName       string
+	VarName        string
+	FromNode       string
+	ToNode         string
+	DifferentNodes bool
+}
+
+var edgeQueryNodesTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/edge_query_nodes",
+	Data: EdgeQueryNodesData{},
+	Text: "// Query{{.FromNode}} traverses the graph to the {{.FromNode}} node." +
 		"\nfunc ({{$.VarName}} *{{$.CodeName}}Q) Query{{.FromNode}}() " +
 		"*{{.FromNode}}Q {\n\tquery := {{.FromNode}}Query()\n" +
 		"\tquery.Prefix = {{$.VarName}}.Prefix + 1\n" +
@@ -736,27 +1035,36 @@ func GetEdgeQueryNodesStr(e cg.EdgeStruct) string {
 		"\t{{$.VarName}}.Next = &query.Query\n" +
 		"\treturn query\n" +
 		"}\n" +
-		"{{end}}"
-	return cg.ExecTemplate(t, "edge_query_nodes", data, nil)
-}
+		"{{end}}",
+})
 
-// GetEdgeMutatorStr generates the mutator helper functions.
-func GetEdgeMutatorStr(e cg.EdgeStruct) string {
-	data := struct {
-		Name     string
-		VarName  string
-		FromNode string
-		ToNode   string
-		Fields   []cg.EdgeFieldStruct
-	}{
-		Name:     e.CodeName,
-		VarName:  strings.ToLower(string(e.Name[0])) + "m",
-		FromNode: e.FromNode.GetName(),
-		ToNode:   e.ToNode.GetName(),
-		Fields:   e.Fields,
+// GetEdgeQueryNodesStr generates the Query functions for traversing the graph.
+func GetEdgeQueryNodesStr(e cg.EdgeStruct) (string, error) {
+	data := EdgeQueryNodesData{
+		Name:           e.Name,
+		CodeName:       e.CodeName,
+		VarName:        strings.ToLower(string(e.Name[0])) + "q",
+		FromNode:       e.FromNode.GetName(),
+		ToNode:         e.ToNode.GetName(),
+		DifferentNodes: e.FromNode.GetName() != e.ToNode.GetName(),
 	}
+	return edgeQueryNodesTemplate.Exec(data)
+}
+
+// EdgeMutatorData is the data of the db/edge_mutator template.
+type EdgeMutatorData struct {
+	Name     string
+	VarName  string
+	FromNode string
+	ToNode   string
+	Fields   []cg.EdgeFieldStruct
+}
+
+var edgeMutatorTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/edge_mutator",
+	Data: EdgeMutatorData{},
 	// Base mutator
-	template := "// {{.Name}}M is the base {{.Name}} mutator struct.\n" +
+	Text: "// {{.Name}}M is the base {{.Name}} mutator struct.\n" +
 		"type {{.Name}}M struct {\n" +
 		"\tbase.EdgeMutator\n" +
 		"}\n\n" +
@@ -792,28 +1100,35 @@ func GetEdgeMutatorStr(e cg.EdgeStruct) string {
 		"{{$.VarName}}.Fields[\"{{.Name}}\"] = v\n" +
 		"\treturn {{$.VarName}}\n" +
 		"}\n\n" +
-		"{{end}}"
-
-	return cg.ExecTemplate(template, "edge_mutator", data, nil)
-}
+		"{{end}}",
+})
 
-// GetEdgeDeleterStr generates the deleter helper functions.
-func GetEdgeDeleterStr(e cg.EdgeStruct) string {
-	data := struct {
-		Name     string
-		VarName  string
-		FromNode string
-		ToNode   string
-		Fields   []cg.EdgeFieldStruct
-	}{
+// GetEdgeMutatorStr generates the mutator helper functions.
+func GetEdgeMutatorStr(e cg.EdgeStruct) (string, error) {
+	data := EdgeMutatorData{
 		Name:     e.CodeName,
 		VarName:  strings.ToLower(string(e.Name[0])) + "m",
 		FromNode: e.FromNode.GetName(),
 		ToNode:   e.ToNode.GetName(),
 		Fields:   e.Fields,
 	}
+	return edgeMutatorTemplate.Exec(data)
+}
+
+// EdgeDeleterData is the data of the db/edge_deleter template.
+type EdgeDeleterData struct {
+	Name     string
+	VarName  string
+	FromNode string
+	ToNode   string
+	Fields   []cg.EdgeFieldStruct
+}
+
+var edgeDeleterTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/edge_deleter",
+	Data: EdgeDeleterData{},
 	// Base deleter
-	template := "// {{.Name}}D is the base {{.Name}} deleter struct.\n" +
+	Text: "// {{.Name}}D is the base {{.Name}} deleter struct.\n" +
 		"type {{.Name}}D struct {\n" +
 		"\nbase.Deleter\n" +
 		"}\n\n" +
@@ -867,7 +1182,17 @@ func GetEdgeDeleterStr(e cg.EdgeStruct) string {
 		"\tdeleter.Prev = &{{$.VarName}}.Deleter\n" +
 		"\t{{$.VarName}}.Next = &deleter.Deleter\n" +
 		"\treturn deleter\n" +
-		"}\n"
+		"}\n",
+})
 
-	return cg.ExecTemplate(template, "edge_deleter", data, nil)
+// GetEdgeDeleterStr generates the deleter helper functions.
+func GetEdgeDeleterStr(e cg.EdgeStruct) (string, error) {
+	data := EdgeDeleterData{
+		Name:     e.CodeName,
+		VarName:  strings.ToLower(string(e.Name[0])) + "m",
+		FromNode: e.FromNode.GetName(),
+		ToNode:   e.ToNode.GetName(),
+		Fields:   e.Fields,
+	}
+	return edgeDeleterTemplate.Exec(data)
 }
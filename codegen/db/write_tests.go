@@ -1,95 +1,129 @@
 package db
 
 import (
-	"crypto/md5"
-	"encoding/hex"
-	"fmt"
-	"go/format"
 	cg "splits-go-schema-codegen/codegen"
 	"strings"
 	"text/template"
 )
 
 // WriteAutogenTests generates the string that tests the autogen code.
-func WriteAutogenTests(schemas []cg.Schema, packageName string) string {
+func WriteAutogenTests(
+	schemas []cg.Schema,
+	manualParts []cg.ManualSection,
+	packageName string,
+) (string, error) {
+	manual := cg.NewManualParts(manualParts)
 
 	// Use templates to generate the node
-	sections := []string{}
-	sections = append(sections, GetAutogenTestFileHeaderCommentStr())
-	sections = append(sections, GetAutogenTestPackageStr(packageName))
-	sections = append(sections, GetAutogenTestImportStr())
-	sections = append(sections, GetAutogenNodeTests(schemas))
-	sections = append(sections, GetAutogenEdgeTests(schemas))
-	result := strings.Join(sections, "\n")
-	res, err := format.Source([]byte(result))
+	sections := cg.Sections{}
+	sections.Add(GetAutogenTestFileHeaderCommentStr())
+	sections.Add(GetAutogenTestPackageStr(packageName))
+	sections.Add(GetAutogenTestImportStr(
+		manual.Get(cg.ManualImports)))
+	sections.Add(GetExtraFunctionsStr(
+		manual.Get(cg.ManualFunctions)))
+	sections.Add(GetGeneratedFunctionsTagStr())
+	sections.Add(GetAutogenNodeTests(schemas))
+	sections.Add(GetAutogenEdgeTests(schemas))
+	if err := manual.Err(); err != nil {
+		return "", err
+	}
+	result, err := sections.Join("\n")
 	if err != nil {
-		fmt.Println(result)
-		panic(err)
+		return "", err
 	}
+	res, err := cg.FormatSource(result)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
 
-	// Generate the MD5 signature
-	sum := md5.Sum([]byte(res))
-	signature := hex.EncodeToString([]byte(sum[:]))
-
-	// Add the signature to the top of the file
-	return "// @SignedSource (" + signature + ")\n" + string(res)
+// AutogenTestFileHeaderCommentData is the data of the
+// db/autogen_test_file_header_comment template.
+type AutogenTestFileHeaderCommentData struct {
+	Name string
 }
 
+var autogenTestFileHeaderCommentTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/autogen_test_file_header_comment",
+	Data: AutogenTestFileHeaderCommentData{},
+	Text: "// Autogenerated {{.Name}} - regenerate with splits-go-schema-" +
+		"codegen\n// Force regeneration over hand edits with " +
+		"generate --force.\n",
+})
+
 // GetAutogenTestFileHeaderCommentStr generates an autogenerated tag.
-func GetAutogenTestFileHeaderCommentStr() string {
-	data := struct {
-		Name string
-	}{
+func GetAutogenTestFileHeaderCommentStr() (string, error) {
+	data := AutogenTestFileHeaderCommentData{
 		Name: "AutogenTests",
 	}
-	template := "// Autogenerated {{.Name}} - regenerate with splits-go-schema-" +
-		"codegen\n// Force autogen by deleting the @SignedSource line.\n"
-	return cg.ExecTemplate(template, "autogen_file_header_comment", data, nil)
+	return autogenTestFileHeaderCommentTemplate.Exec(data)
+}
+
+// AutogenTestPackageData is the data of the db/autogen_test_package template.
+type AutogenTestPackageData struct {
+	Package string
 }
 
+var autogenTestPackageTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/autogen_test_package",
+	Data: AutogenTestPackageData{},
+	Text: "package {{.Package}}\n",
+})
+
 // GetAutogenTestPackageStr generates the package tag.
-func GetAutogenTestPackageStr(packageName string) string {
-	data := struct {
-		Package string
-	}{
+func GetAutogenTestPackageStr(packageName string) (string, error) {
+	data := AutogenTestPackageData{
 		Package: packageName,
 	}
-	template := "package {{.Package}}\n"
-	return cg.ExecTemplate(template, "autogen_package", data, nil)
+	return autogenTestPackageTemplate.Exec(data)
 }
 
+// AutogenTestImportData is the data of the db/autogen_test_import template.
+type AutogenTestImportData struct {
+	Imports    []string
+	ManualPart string
+}
+
+var autogenTestImportTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/autogen_test_import",
+	Data: AutogenTestImportData{},
+	Text: "import (\n" +
+		"{{range .Imports}} \t{{.}}\n {{end}}" +
+		"\n" +
+		cg.StartManualSection(cg.ManualImports) + "\n" +
+		"{{.ManualPart}}\n" +
+		cg.EndManual + "\n" +
+		")\n",
+})
+
 // GetAutogenTestImportStr generates the import statements.
-func GetAutogenTestImportStr() string {
-	data := struct {
-		Imports []string
-	}{
+func GetAutogenTestImportStr(manualPart string) (string, error) {
+	data := AutogenTestImportData{
 		Imports: []string{
 			"p \"splits-go-api/db/models/predicates\"",
 			"\"math/rand\"",
 			"\"splits-go-api/testingutil\"",
 			"\"testing\"",
 		},
+		ManualPart: manualPart,
 	}
-	template :=
-		"import (\n" +
-			"{{range .Imports}} \t{{.}}\n {{end}}" +
-			")\n"
-	return cg.ExecTemplate(template, "autogen_import", data, nil)
+	return autogenTestImportTemplate.Exec(data)
 }
 
-// GetAutogenNodeTests generates the tests for the db.
-func GetAutogenNodeTests(schemas []cg.Schema) string {
-	type usefulSchema struct {
-	}
-	data := struct {
-		Schemas []cg.Schema
-	}{
-		Schemas: schemas,
-	}
-	funcMap := template.FuncMap{
+// AutogenNodeTestsData is the data of the db/autogen_node_tests template.
+type AutogenNodeTestsData struct {
+	Schemas []cg.Schema
+}
+
+var autogenNodeTestsTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/autogen_node_tests",
+	Data: AutogenNodeTestsData{},
+	Funcs: template.FuncMap{
 		"ToLower": strings.ToLower,
-	}
-	template := "{{range .Schemas}}" +
+	},
+	Text: "{{range .Schemas}}" +
 		"func Test{{.GetName}}Autogen(t *testing.T) {\n" +
 		"\tconn, err := testingutil.GenDBConn()\n" +
 		"\tif err != nil {\n" +
@@ -204,21 +238,31 @@ func GetAutogenNodeTests(schemas []cg.Schema) string {
 		"\t}\n" +
 		"}\n" +
 		"\n" +
-		"{{end}}"
-	return cg.ExecTemplate(template, "autogen_node_tests", data, funcMap)
-}
+		"{{end}}",
+})
 
-// GetAutogenEdgeTests generates the tests for the db.
-func GetAutogenEdgeTests(schemas []cg.Schema) string {
-	data := struct {
-		Schemas []cg.Schema
-	}{
+// GetAutogenNodeTests generates the tests for the db.
+func GetAutogenNodeTests(schemas []cg.Schema) (string, error) {
+	type usefulSchema struct {
+	}
+	data := AutogenNodeTestsData{
 		Schemas: schemas,
 	}
-	funcMap := template.FuncMap{
+	return autogenNodeTestsTemplate.Exec(data)
+}
+
+// AutogenEdgeTestsData is the data of the db/autogen_edge_tests template.
+type AutogenEdgeTestsData struct {
+	Schemas []cg.Schema
+}
+
+var autogenEdgeTestsTemplate = cg.NewTemplate(cg.Template{
+	Name: "db/autogen_edge_tests",
+	Data: AutogenEdgeTestsData{},
+	Funcs: template.FuncMap{
 		"ToLower": strings.ToLower,
-	}
-	template := "{{range .Schemas}}{{range .GetEdges}}" +
+	},
+	Text: "{{range .Schemas}}{{range .GetEdges}}" +
 		"func Test{{.CodeName}}Autogen(t *testing.T) {\n" +
 		"\tconn, err := testingutil.GenDBConn()\n" +
 		"\tif err != nil {\n" +
@@ -393,6 +437,13 @@ func GetAutogenEdgeTests(schemas []cg.Schema) string {
 		"\t}\n" +
 		"}\n" +
 		"\n" +
-		"{{end}}{{end}}"
-	return cg.ExecTemplate(template, "autogen_edge_tests", data, funcMap)
+		"{{end}}{{end}}",
+})
+
+// GetAutogenEdgeTests generates the tests for the db.
+func GetAutogenEdgeTests(schemas []cg.Schema) (string, error) {
+	data := AutogenEdgeTestsData{
+		Schemas: schemas,
+	}
+	return autogenEdgeTestsTemplate.Exec(data)
 }
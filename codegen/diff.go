@@ -0,0 +1,375 @@
+// Line based diffs between generated files and what is on disk, with the
+// manual sections marked so moved or dropped hand-written code stands out.
+
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DiffOp is the kind of change of a line in a diff.
+type DiffOp int
+
+// Kinds of changes in a diff.
+const (
+	DiffEqual DiffOp = iota
+	DiffDelete
+	DiffInsert
+)
+
+// maxDiffEdits bounds the edit distance searched for before a diff falls back
+// to replacing every line.
+const maxDiffEdits = 2000
+
+// DiffLine is a single line of a diff.
+type DiffLine struct {
+	Op     DiffOp
+	Text   string
+	Manual bool // Whether the line is inside a manual section
+}
+
+// Hunk is a group of changed lines with their surrounding context.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []DiffLine
+}
+
+// Manual returns whether the hunk touches a manual section.
+func (h Hunk) Manual() bool {
+	for _, l := range h.Lines {
+		if l.Op != DiffEqual && l.Manual {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffLines computes the line diff between two contents. Lines inside manual
+// sections are marked on the side they come from.
+func DiffLines(oldContent string, newContent string) []DiffLine {
+	a := splitLines(oldContent)
+	b := splitLines(newContent)
+	aManual := manualLines(a)
+	bManual := manualLines(b)
+
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	x, y := 0, 0
+	for _, op := range diffOps(a, b) {
+		switch op {
+		case DiffEqual:
+			lines = append(lines, DiffLine{op, a[x], aManual[x]})
+			x++
+			y++
+		case DiffDelete:
+			lines = append(lines, DiffLine{op, a[x], aManual[x]})
+			x++
+		case DiffInsert:
+			lines = append(lines, DiffLine{op, b[y], bManual[y]})
+			y++
+		}
+	}
+	return lines
+}
+
+// diffOps returns the edit script that turns the lines of a into the lines of
+// b.
+func diffOps(a []string, b []string) []DiffOp {
+	// Trim the common prefix and suffix before searching
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]DiffOp, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, DiffEqual)
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, DiffEqual)
+	}
+	return ops
+}
+
+// myers returns the shortest edit script that turns a into b.
+func myers(a []string, b []string) []DiffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	if max > 2*maxDiffEdits {
+		max = 2 * maxDiffEdits
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	trace := [][]int{}
+
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		// Keep the furthest reaching paths of the previous step
+		snapshot := make([]int, 2*d+3)
+		copy(snapshot, v[offset-d-1:offset+d+2])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	// Too many changes, replace every line
+	if !found {
+		ops := make([]DiffOp, 0, n+m)
+		for i := 0; i < n; i++ {
+			ops = append(ops, DiffDelete)
+		}
+		for i := 0; i < m; i++ {
+			ops = append(ops, DiffInsert)
+		}
+		return ops
+	}
+
+	// Walk back through the trace to recover the edits
+	reversed := []DiffOp{}
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		snapshot := trace[d]
+		get := func(k int) int { return snapshot[k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && get(k-1) < get(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := get(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, DiffEqual)
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, DiffInsert)
+			y--
+		} else {
+			reversed = append(reversed, DiffDelete)
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, DiffEqual)
+		x--
+		y--
+	}
+
+	ops := make([]DiffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
+
+// DiffHunks groups the changed lines of a diff into hunks with the given
+// number of context lines.
+func DiffHunks(lines []DiffLine, context int) []Hunk {
+	hunks := []Hunk{}
+	oldLine, newLine := 1, 1
+	var hunk *Hunk
+	lastChange := -1
+
+	for i, l := range lines {
+		if l.Op != DiffEqual {
+			if hunk == nil || i-lastChange > 2*context {
+				if hunk != nil {
+					hunks = append(hunks, closeHunk(*hunk, lines, lastChange, context))
+				}
+
+				// Start a new hunk with the leading context
+				start := i - context
+				if start < 0 {
+					start = 0
+				}
+				hunk = &Hunk{
+					OldStart: oldLine - (i - start),
+					NewStart: newLine - (i - start),
+				}
+				for _, c := range lines[start:i] {
+					hunk.Lines = append(hunk.Lines, c)
+				}
+			} else {
+				for _, c := range lines[lastChange+1 : i] {
+					hunk.Lines = append(hunk.Lines, c)
+				}
+			}
+			hunk.Lines = append(hunk.Lines, l)
+			lastChange = i
+		}
+
+		if l.Op != DiffInsert {
+			oldLine++
+		}
+		if l.Op != DiffDelete {
+			newLine++
+		}
+	}
+	if hunk != nil {
+		hunks = append(hunks, closeHunk(*hunk, lines, lastChange, context))
+	}
+	return hunks
+}
+
+// closeHunk adds the trailing context to a hunk and counts its lines.
+func closeHunk(hunk Hunk, lines []DiffLine, lastChange int, context int) Hunk {
+	end := lastChange + 1 + context
+	if end > len(lines) {
+		end = len(lines)
+	}
+	hunk.Lines = append(hunk.Lines, lines[lastChange+1:end]...)
+	for _, l := range hunk.Lines {
+		if l.Op != DiffInsert {
+			hunk.OldLines++
+		}
+		if l.Op != DiffDelete {
+			hunk.NewLines++
+		}
+	}
+	return hunk
+}
+
+// UnifiedDiff formats the hunks as a unified diff. Hunks that touch a manual
+// section are marked in their header.
+func UnifiedDiff(oldName string, newName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks {
+		fmt.Fprintf(&buf, "@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines),
+			hunkRange(h.NewStart, h.NewLines))
+		if h.Manual() {
+			buf.WriteString(" manual section")
+		}
+		buf.WriteString("\n")
+		for _, l := range h.Lines {
+			switch l.Op {
+			case DiffEqual:
+				buf.WriteString(" ")
+			case DiffDelete:
+				buf.WriteString("-")
+			case DiffInsert:
+				buf.WriteString("+")
+			}
+			buf.WriteString(l.Text + "\n")
+		}
+	}
+	return buf.String()
+}
+
+func hunkRange(start int, lines int) string {
+	if lines == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	} else if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// ManualSectionChange describes what happens to a manual section on disk when
+// a file is regenerated.
+type ManualSectionChange struct {
+	Index    int    // Position of the section on disk
+	Name     string // Name of the section on disk, empty if it has none
+	NewIndex int    // Position of the section after generation, -1 if dropped
+	NewName  string // Name of the section after generation
+	Status   string // preserved, moved or dropped
+	Lines    int    // Number of lines of hand-written code in the section
+}
+
+// CompareManualSections matches the non-empty manual sections on disk with the
+// sections of the regenerated content. A section is preserved if its code is
+// in the section with the same name, or at the same position for the unnamed
+// sections of older files.
+func CompareManualSections(
+	oldContent string,
+	newContent string,
+) []ManualSectionChange {
+	oldSections := ExtractManualSections(oldContent)
+	newSections := ExtractManualSections(newContent)
+	changes := []ManualSectionChange{}
+	for i, s := range oldSections {
+		code := strings.TrimSpace(s.Content)
+		if code == "" {
+			continue
+		}
+		change := ManualSectionChange{
+			Index:    i,
+			Name:     s.Name,
+			NewIndex: -1,
+			Status:   "dropped",
+			Lines:    len(splitLines(code)),
+		}
+		for j, n := range newSections {
+			if strings.TrimSpace(n.Content) != code {
+				continue
+			}
+			change.NewIndex = j
+			change.NewName = n.Name
+			if s.Name == n.Name || s.Name == "" && i == j {
+				change.Status = "preserved"
+				break
+			}
+			change.Status = "moved"
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// splitLines splits content into lines, without a trailing empty line.
+func splitLines(content string) []string {
+	if content == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// manualLines marks the lines that are inside a manual section.
+func manualLines(lines []string) []bool {
+	manual := make([]bool, len(lines))
+	inside := false
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if trimmed == EndManual {
+			inside = false
+		}
+		manual[i] = inside
+		if strings.HasPrefix(trimmed, manualStartPrefix) {
+			inside = true
+		}
+	}
+	return manual
+}
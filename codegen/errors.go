@@ -0,0 +1,116 @@
+// Errors of a generation run. Every error carries what it was found in, such
+// as the schema, template and file, so a caller can tell them apart without
+// parsing the message.
+
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error is an error found while generating, along with where it was found.
+// Only the parts that are known are set.
+type Error struct {
+	Layer    string // Layer being generated
+	Schema   string // Name of the schema
+	Edge     string // Name of the edge
+	Field    string // Name of the field
+	Template string // Name of the template, see Templates
+	File     string // Path of the generated file
+	Code     ErrorCode
+	Err      error
+}
+
+// ErrorCode identifies the kind of problem found in the schemas. Codes are
+// stable, so tools can match on them rather than on the message.
+type ErrorCode string
+
+func (e *Error) Error() string {
+	parts := []string{}
+	if e.File != "" {
+		parts = append(parts, e.File)
+	} else if e.Layer != "" {
+		parts = append(parts, e.Layer+" layer")
+	}
+	if e.Schema != "" {
+		parts = append(parts, "schema "+e.Schema)
+	}
+	if e.Edge != "" {
+		parts = append(parts, "edge "+e.Edge)
+	}
+	if e.Field != "" {
+		parts = append(parts, "field "+e.Field)
+	}
+	if e.Template != "" {
+		parts = append(parts, "template "+e.Template)
+	}
+	if e.Code != "" {
+		parts = append(parts, fmt.Sprintf("[%s] %v", e.Code, e.Err))
+	} else {
+		parts = append(parts, fmt.Sprint(e.Err))
+	}
+	return strings.Join(parts, ": ")
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// withContext fills in the parts of the error that are not set from context.
+func (e *Error) withContext(context Error) *Error {
+	err := *e
+	fill := func(s *string, value string) {
+		if *s == "" {
+			*s = value
+		}
+	}
+	fill(&err.Layer, context.Layer)
+	fill(&err.Schema, context.Schema)
+	fill(&err.Edge, context.Edge)
+	fill(&err.Field, context.Field)
+	fill(&err.Template, context.Template)
+	fill(&err.File, context.File)
+	if err.Code == "" {
+		err.Code = context.Code
+	}
+	return &err
+}
+
+// Errors are every error found in a generation run.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	lines := []string{}
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Add adds an error, filling in what it was found in from context where the
+// error does not know it already. The errors in Errors are added one by one.
+func (e *Errors) Add(err error, context Error) {
+	switch err := err.(type) {
+	case nil:
+	case Errors:
+		for _, inner := range err {
+			*e = append(*e, inner.withContext(context))
+		}
+	case *Error:
+		*e = append(*e, err.withContext(context))
+	default:
+		c := context
+		c.Err = err
+		*e = append(*e, &c)
+	}
+}
+
+// Err returns the errors as an error, nil if there are none.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
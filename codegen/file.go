@@ -0,0 +1,189 @@
+// Generated files rendered in memory, and how they compare to what is on disk.
+
+package codegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File is a generated file rendered in memory.
+type File struct {
+	Path    string // Path of the file on disk
+	Layer   string // Layer the file belongs to
+	Source  Source // Schema and edge the file is generated from
+	Content string // Content of the file, exactly as it is written to disk
+	Inputs  string // Hash of what the file was rendered from, if known
+
+	// Generated is the rendered content when Content was merged with edits
+	// on disk, see MergeEdits.
+	Generated string
+}
+
+// Source is the schema and edge a file is generated from. Both are empty for
+// files generated from every schema, such as constants.go.
+type Source struct {
+	Schema string
+	Edge   string
+}
+
+// NewFile creates a file from the output of a writer, signed by the kind of
+// file its path names, see Sign.
+func NewFile(path string, layer string, source Source, output string) File {
+	return File{
+		Path:    path,
+		Layer:   layer,
+		Source:  source,
+		Content: Sign(path, Unescape(output)),
+	}
+}
+
+// describeSource returns the layer, schema and edge the file is generated
+// from, as errors name them.
+func (f File) describeSource() string {
+	parts := []string{}
+	if f.Layer != "" {
+		parts = append(parts, "the "+f.Layer+" layer")
+	}
+	if f.Source.Schema != "" {
+		parts = append(parts, "schema "+f.Source.Schema)
+	}
+	if f.Source.Edge != "" {
+		parts = append(parts, "edge "+f.Source.Edge)
+	}
+	if len(parts) == 0 {
+		return "an unknown source"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Unescape resolves the printf escaping of the writer output. Manual sections
+// and templates escape % as %%, which used to be undone by fmt.Fprintf.
+func Unescape(output string) string {
+	return strings.Replace(output, "%%", "%", -1)
+}
+
+// Write writes the file to disk, creating its directory if needed.
+func (f File) Write() error {
+	err := os.MkdirAll(filepath.Dir(f.Path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f.Path, []byte(f.Content), 0644)
+}
+
+// FileStatus is the state of a generated file compared to the disk.
+type FileStatus string
+
+// Statuses of a generated file.
+const (
+	StatusUpToDate  = FileStatus("up-to-date")
+	StatusStale     = FileStatus("stale")
+	StatusOutdated  = FileStatus("outdated")
+	StatusMissing   = FileStatus("missing")
+	StatusExtra     = FileStatus("extra")
+	StatusTampered  = FileStatus("signature-mismatch")
+	StatusUnmatched = FileStatus("unmatched-manual-section")
+)
+
+// Check compares the canonical form of the file against its content on disk,
+// see CanonicalContent. Manual sections are ignored, as they are carried over
+// on generation. Files in the manifest are checked against the hash recorded
+// in it, other files against their signature. A file that still has the
+// content of a merge is up to date as long as the rendered content is the one
+// it was merged with.
+func (f File) Check(owned map[string]ManifestEntry) (FileStatus, error) {
+	content, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return StatusMissing, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	status := contentSignatureStatus(f.Path, string(content), owned)
+	if status == SignatureMismatch {
+		return StatusTampered, nil
+	}
+	if status == SignatureMerged {
+		if owned[f.Path].Hash != HashContent(CanonicalContent(f.Content)) {
+			return StatusStale, nil
+		}
+		return StatusUpToDate, nil
+	}
+	if CanonicalContent(string(content)) != CanonicalContent(f.Content) {
+		if status == SignatureOutdated {
+			return StatusOutdated, nil
+		}
+		return StatusStale, nil
+	}
+	return StatusUpToDate, nil
+}
+
+// FindExtraFiles returns the signed files in the directories that are not
+// part of the rendered files, sorted by path.
+func FindExtraFiles(dirs []string, files []File) ([]string, error) {
+	rendered := map[string]bool{}
+	for _, f := range files {
+		rendered[f.Path] = true
+	}
+
+	extra := []string{}
+	visited := map[string]bool{}
+	for _, dir := range dirs {
+		if visited[dir] {
+			continue
+		}
+		visited[dir] = true
+
+		entries, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			path := filepath.Join(dir, e.Name())
+			if e.IsDir() || rendered[path] {
+				continue
+			}
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if VerifySignature(path, string(content)) != SignatureUnsigned {
+				extra = append(extra, path)
+			}
+		}
+	}
+	sort.Strings(extra)
+	return extra, nil
+}
+
+// ReadManualSections extracts the manual sections of every go file in the
+// directories, keyed by path.
+func ReadManualSections(dirs ...string) (map[string][]ManualSection, error) {
+	manualParts := map[string][]ManualSection{}
+	for _, dir := range dirs {
+		entries, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			manualParts[path] = ExtractManualSections(string(content))
+		}
+	}
+	return manualParts, nil
+}
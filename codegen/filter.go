@@ -0,0 +1,70 @@
+// Selecting the schemas and edges a generation run renders files for.
+
+package codegen
+
+import (
+	"errors"
+	"strings"
+)
+
+// Filter selects the schemas and edges to render files for. An empty filter
+// selects everything. Files generated from every schema, such as constants.go,
+// are always rendered from all schemas when anything they are generated from
+// is selected.
+type Filter struct {
+	Schemas []string // Names of the schemas, selecting their nodes and edges
+	Edges   []string // Names or code names of single edges
+}
+
+// IsEmpty returns whether the filter selects everything.
+func (f Filter) IsEmpty() bool {
+	return len(f.Schemas) == 0 && len(f.Edges) == 0
+}
+
+// SelectsSchema returns whether the files of the schema's node are selected.
+func (f Filter) SelectsSchema(name string) bool {
+	return f.IsEmpty() || containsString(f.Schemas, name)
+}
+
+// SelectsEdge returns whether the files of an edge from the schema are
+// selected, either through the schema or through one of the edge's names.
+func (f Filter) SelectsEdge(schema string, names ...string) bool {
+	if f.SelectsSchema(schema) {
+		return true
+	}
+	for _, n := range names {
+		if containsString(f.Edges, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that every schema and edge in the filter exists.
+func (f Filter) Validate(schemas []Schema) error {
+	unknown := []string{}
+	for _, name := range f.Schemas {
+		found := false
+		for _, s := range schemas {
+			found = found || s.GetName() == name
+		}
+		if !found {
+			unknown = append(unknown, "schema "+name)
+		}
+	}
+	for _, name := range f.Edges {
+		found := false
+		for _, s := range schemas {
+			for _, e := range s.GetEdges() {
+				found = found || e.Name == name || e.CodeName == name
+			}
+		}
+		if !found {
+			unknown = append(unknown, "edge "+name)
+		}
+	}
+	if len(unknown) > 0 {
+		return errors.New("unknown " + strings.Join(unknown, ", "))
+	}
+	return nil
+}
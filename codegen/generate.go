@@ -0,0 +1,452 @@
+// Generating the code of the enabled layers into the target repository. This
+// is the entry point for calling the generator from other tools, the commands
+// of the generator are built on it.
+
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Options are the options of a generation run. The zero value renders the
+// files whose inputs changed, and fails on files that were edited by hand.
+type Options struct {
+	Filter Filter // Schemas and edges to render, every one if empty
+	Merge  bool   // Merge the edits made to files, see MergeEdits
+	Force  bool   // Overwrite edited files, dropping their manual sections
+	Prune  bool   // Remove orphaned files without code in their manual sections
+	Full   bool   // Render every file, even if its inputs did not change
+	Verify bool   // Type-check the rendered packages before writing, see Verify
+	DryRun bool   // Write nothing, and report what would be written
+}
+
+// Generate renders the files of the enabled layers and writes the ones that
+// changed, along with the manifest. Every layer is validated and rendered
+// before anything is written, and the errors of all of them are returned
+// together as Errors, in which case nothing is written. Merge takes precedence
+// over Force if both are set. A dry run goes through the same checks and
+// merges, and reports the files it would write with Report.Output.
+func Generate(config Config, options Options) (Report, error) {
+	report := Report{GeneratorVersion: Version}
+	err := config.Validate()
+	if err != nil {
+		return report, err
+	}
+	err = loadOverrides(config)
+	if err != nil {
+		return report, err
+	}
+	schemas, err := LoadSchemas(config)
+	if err != nil {
+		return report, err
+	}
+	filter := options.Filter
+	err = filter.Validate(schemas)
+	if err != nil {
+		return report, err
+	}
+	force := options.Force && !options.Merge
+	manifest, err := LoadManifest(config.StatePath())
+	if err != nil {
+		return report, err
+	}
+
+	// Render every layer before writing anything, so a failure in any of them
+	// leaves the target untouched. Only the schemas whose files changed are
+	// rendered again, unless a forced run drops the manual sections of every
+	// file.
+	inputs := NewInputs(config, schemas)
+	incremental := filter.IsEmpty() && !options.Full && !force
+	files := []File{}
+	traced := []File{}
+	skipped := map[string]bool{}
+	errs := Errors{}
+	for _, g := range EnabledGenerators(config) {
+		start := time.Now()
+		changes := Changes{All: true}
+		if incremental {
+			changes, err = manifest.Changes(config.Root, g.Name(), schemas,
+				inputs)
+			if err != nil {
+				return report, err
+			}
+			// A signed file missing from the manifest may be the target of
+			// any schema, so it is only checked if every schema is rendered
+			untracked, err := UntrackedFiles(g.Dirs(config),
+				manifest.Owned(config.Root, ""))
+			if err != nil {
+				return report, err
+			}
+			if len(untracked) > 0 {
+				changes = Changes{All: true}
+			}
+		}
+		layerFiles := []File{}
+		if changes.All || len(changes.Schemas) > 0 {
+			layerFilter := filter
+			if !changes.All {
+				layerFilter = Filter{Schemas: changes.Schemas}
+			}
+			var layerTraced []File
+			layerFiles, layerTraced, err = generateLayer(g, config, schemas,
+				layerFilter, manifest, options.Merge, force, options.Verify)
+			if err != nil {
+				errs.Add(err, Error{Layer: g.Name()})
+				continue
+			}
+			traced = append(traced, layerTraced...)
+		}
+		setInputs(layerFiles, inputs, filter)
+		files = append(files, layerFiles...)
+
+		// Files of the unchanged schemas that were not rendered along with the
+		// changed ones are kept as they are
+		rendered := map[string]bool{}
+		for _, f := range layerFiles {
+			rendered[f.Path] = true
+		}
+		layerSkipped := 0
+		for _, f := range changes.Unchanged {
+			if !rendered[f.Path] {
+				files = append(files, f)
+				skipped[f.Path] = true
+				layerSkipped++
+			}
+		}
+		report.Layers = append(report.Layers, LayerReport{
+			Name:     g.Name(),
+			Duration: time.Since(start),
+			Rendered: len(layerFiles),
+			Skipped:  layerSkipped,
+		})
+	}
+	if err := errs.Err(); err != nil {
+		return report, err
+	}
+	if options.Verify {
+		err = Verify(config, schemas, files, traced)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	// Edits made outside of the manual sections are merged with the rendered
+	// content rather than written over, and so are the edits of the files an
+	// earlier run merged, unless forced
+	owned := manifest.Owned(config.Root, "")
+	if !force {
+		files, report.Merged, err = MergeEdits(config.Root, config.StatePath(),
+			files, owned, options.Merge)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	// Write the files along with the manifest, removing the orphans if asked.
+	// Files that were not selected can not be told apart from orphans, so they
+	// are only looked for when everything is rendered.
+	orphans := []Orphan{}
+	if filter.IsEmpty() {
+		orphans, err = FindOrphans(config.Root, manifest, config.Layers,
+			LayerDirs(config), files)
+		if err != nil {
+			return report, err
+		}
+	}
+	remove, kept := SplitOrphans(config.Root, orphans, options.Prune)
+	keptPaths := map[string]bool{}
+	for _, o := range kept {
+		keptPaths[o.Path] = true
+	}
+	manifestFile, err := NewManifest(config.Root, files, manifest,
+		func(e ManifestEntry) bool {
+			return !config.HasLayer(e.Layer) || !filter.IsEmpty() ||
+				keptPaths[e.Path]
+		}).File(config.StatePath())
+	if err != nil {
+		return report, err
+	}
+
+	// The files on disk are reported as they were before the run
+	fileReports := map[string]FileReport{}
+	for _, f := range files {
+		fileReports[f.Path], err = newFileReport(config.Root, f, owned)
+		if err != nil {
+			return report, err
+		}
+	}
+	orphanReports := []FileReport{}
+	for _, o := range orphans {
+		path := filepath.Join(config.Root, o.Path)
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return report, err
+		}
+		r, err := newFileReport(config.Root, File{Path: path, Layer: o.Layer,
+			Content: string(content)}, owned)
+		if err != nil {
+			return report, err
+		}
+		if e, ok := owned[path]; ok {
+			r.Schema, r.Edge = e.Schema, e.Edge
+		}
+		r.Action = ActionOrphaned
+		if o.ManualCode {
+			r.Action = ActionKeptOrphan
+		}
+		r.Removed = options.Prune && !o.ManualCode
+		orphanReports = append(orphanReports, r)
+	}
+
+	// The rendered content is kept as the base of the next merge, and the
+	// bases of the removed files go with them
+	bases, err := BaseFiles(config.Root, config.StatePath(), files)
+	if err != nil {
+		return report, err
+	}
+	state := map[string]bool{manifestFile.Path: true}
+	for _, b := range bases {
+		state[b.Path] = true
+	}
+	stateFiles := append([]File{manifestFile}, bases...)
+	removeFiles := append(append([]string{}, remove...),
+		ExistingBases(config.Root, config.StatePath(), remove)...)
+
+	var written []string
+	if options.DryRun {
+		written, err = changedFiles(files)
+	} else {
+		written, err = WriteFiles(config.Root, config.StatePath(),
+			append(files, stateFiles...), removeFiles)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	generated := map[string]bool{}
+	for _, path := range written {
+		if !state[path] {
+			generated[path] = true
+			report.Generated = append(report.Generated, path)
+		}
+	}
+	merged := map[string]MergedFile{}
+	for _, m := range report.Merged {
+		merged[m.Path] = m
+	}
+	for _, f := range files {
+		r := fileReports[f.Path]
+		m, isMerged := merged[f.Path]
+		r.Conflicts = m.Conflicts
+		switch {
+		case skipped[f.Path]:
+			r.Action = ActionSkipped
+			report.Skipped = append(report.Skipped, f.Path)
+		case !generated[f.Path]:
+			r.Action = ActionUnchanged
+			report.Unchanged = append(report.Unchanged, f.Path)
+		case isMerged:
+			r.Action = ActionMerged
+			r.Conflicts = m.Conflicts
+		case r.Signature == SignatureNone:
+			r.Action = ActionCreated
+		default:
+			r.Action = ActionUpdated
+		}
+		report.Files = append(report.Files, r)
+	}
+	report.Files = append(report.Files, orphanReports...)
+	sortFileReports(report.Files)
+	report.Removed = remove
+	report.Orphans = orphans
+	report.Output = files
+	return report, nil
+}
+
+// LoadSchemas returns the registered schemas followed by the schemas in the
+// schema files of the config, once they pass ValidateSchemas. Every schema
+// knows the edges that point to it.
+func LoadSchemas(config Config) ([]Schema, error) {
+	schemas, err := LoadSchemaFiles(config.SchemaFiles, RegisteredSchemas())
+	if err != nil {
+		return nil, err
+	}
+	if len(schemas) == 0 {
+		return nil, errors.New("no schemas are registered: schema packages " +
+			"have to be imported by the generator binary and call " +
+			"codegen.Register in their init function, or schema files have " +
+			"to be set in the config")
+	}
+	err = ValidateSchemas(schemas)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range schemas {
+		for _, e := range s.GetEdges() {
+			e.ToNode.AddEdgePointer(e)
+		}
+	}
+	return schemas, nil
+}
+
+// loadOverrides loads the template overrides of the config, and drops the
+// overrides of an earlier run if it has none.
+func loadOverrides(config Config) error {
+	if config.Templates == "" {
+		clearOverrides()
+		return nil
+	}
+	_, err := LoadTemplates(config.Templates)
+	return err
+}
+
+// EnabledGenerators returns the generators of the enabled layers, in the order
+// of the config.
+func EnabledGenerators(config Config) []Generator {
+	generators := []Generator{}
+	for _, l := range config.Layers {
+		if g, ok := LookupGenerator(l); ok {
+			generators = append(generators, g)
+		}
+	}
+	return generators
+}
+
+// LayerDirs returns the output directories of the enabled layers.
+func LayerDirs(config Config) []string {
+	dirs := []string{}
+	for _, g := range EnabledGenerators(config) {
+		dirs = append(dirs, g.Dirs(config)...)
+	}
+	return dirs
+}
+
+// RenderFiles renders the selected files of every enabled layer in memory,
+// without validating the files on disk. The errors of every layer are returned
+// together.
+func RenderFiles(
+	config Config,
+	schemas []Schema,
+	filter Filter,
+	manualParts map[string][]ManualSection,
+) ([]File, error) {
+	files := []File{}
+	errs := Errors{}
+	for _, g := range EnabledGenerators(config) {
+		layerFiles, err := renderLayer(g, config, schemas, filter, manualParts,
+			false)
+		errs.Add(err, Error{Layer: g.Name()})
+		files = append(files, layerFiles...)
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// generateLayer validates the files of a layer on disk and renders the
+// selected ones, carrying over their manual sections unless forced. The files
+// on disk that the layer did not own are checked once they are rendered, see
+// ValidateTargetFiles, and rendered again if they have manual sections. If
+// asked to, the files are rendered a second time traced, for Verify.
+func generateLayer(
+	g Generator,
+	config Config,
+	schemas []Schema,
+	filter Filter,
+	manifest Manifest,
+	merge bool,
+	force bool,
+	trace bool,
+) (files []File, traced []File, err error) {
+	owned := manifest.Owned(config.Root, g.Name())
+	manualParts, err := g.Validate(config, schemas, owned, merge, force)
+	if err != nil {
+		return nil, nil, err
+	}
+	if force {
+		manualParts = map[string][]ManualSection{}
+	}
+	files, err = renderLayer(g, config, schemas, filter, manualParts, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !force {
+		extra, err := ValidateTargetFiles(files, manualParts, owned, merge)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(extra) > 0 {
+			for path, sections := range manualParts {
+				extra[path] = sections
+			}
+			manualParts = extra
+			files, err = renderLayer(g, config, schemas, filter, manualParts,
+				false)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if !trace {
+		return files, nil, nil
+	}
+	traced, err = renderLayer(g, config, schemas, filter, manualParts, true)
+	return files, traced, err
+}
+
+// traceLock keeps a traced render from running at the same time as other
+// renders, as tracing changes the output of every template.
+var traceLock sync.RWMutex
+
+// renderLayer renders the files of a layer, traced if asked to. A panic of the
+// generator is returned as an error, so a broken generator does not take the
+// caller down.
+func renderLayer(
+	g Generator,
+	config Config,
+	schemas []Schema,
+	filter Filter,
+	manualParts map[string][]ManualSection,
+	trace bool,
+) (files []File, err error) {
+	if trace {
+		traceLock.Lock()
+		tracing = true
+		defer func() {
+			tracing = false
+			traceLock.Unlock()
+		}()
+	} else {
+		traceLock.RLock()
+		defer traceLock.RUnlock()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			files = nil
+			if e, ok := r.(*Error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("the generator panicked: %v", r)
+			}
+		}
+	}()
+	return g.Render(config, schemas, filter, manualParts)
+}
+
+// setInputs records the hash of what the rendered files were rendered from.
+// An incremental run takes unchanged files generated from every schema to
+// mean that every schema was rendered along with them, so they are left
+// unmarked when the run was filtered.
+func setInputs(files []File, inputs Inputs, filter Filter) {
+	for i, f := range files {
+		if f.Source.Schema != "" || filter.IsEmpty() {
+			files[i].Inputs = inputs.Hash(f.Source)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package codegen_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cg "splits-go-schema-codegen/codegen"
+)
+
+// TestGenerateDryRunMerge edits a generated file and changes the schema, and
+// checks that a dry run refuses the edit as a run would, and merges it with
+// --merge, without writing anything.
+func TestGenerateDryRunMerge(t *testing.T) {
+	payment := cg.RegisteredFixture("Payment")
+	payment.GraphQLNode.Description = "A payment"
+	root, cleanup := cg.FixtureRoot(t, map[string]string{})
+	defer cleanup()
+	config := cg.DefaultConfig()
+	config.Root = root
+	_, err := cg.Generate(config, cg.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Edit the graphql schema after its imports, away from the signature and
+	// the description
+	path := filepath.Join(root, config.GraphQL.Path, "schema.go")
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(string(content), "\n)\n", "\n)\n\n// Edited\n",
+		1)
+	err = cg.File{Path: path, Content: edited}.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment.GraphQLNode.Description = "A payment made"
+	manifestPath := filepath.Join(config.StatePath(), cg.ManifestFile)
+	manifest, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cg.Generate(config, cg.Options{DryRun: true})
+	errs, ok := err.(cg.Errors)
+	if !ok || len(errs) != 1 || errs[0].File != path ||
+		errs[0].Code != cg.CodeEdited {
+		t.Fatalf("expected %s to be edited, got %v", path, err)
+	}
+
+	report, err := cg.Generate(config, cg.Options{DryRun: true, Merge: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Merged) != 1 || report.Merged[0].Path != path ||
+		len(report.Merged[0].Conflicts) != 0 {
+		t.Errorf("unexpected merges %v", report.Merged)
+	}
+	merged := false
+	for _, f := range report.Output {
+		merged = merged || f.Path == path &&
+			strings.Contains(f.Content, "// Edited\n") &&
+			strings.Contains(f.Content, "# A payment made\n")
+	}
+	if !merged {
+		t.Errorf("the edit was not merged with the new schema")
+	}
+
+	// Nothing was written
+	for file, want := range map[string]string{
+		path:         edited,
+		manifestPath: string(manifest),
+	} {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != want {
+			t.Errorf("%s was written by the dry run", file)
+		}
+	}
+}
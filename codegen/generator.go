@@ -0,0 +1,184 @@
+// Generators produce the files of an output target, such as a layer of the
+// target repository. The db, logic and graphql layers are built in, and other
+// targets can be added by registering a generator.
+
+package codegen
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Generator renders the files of an output target from the schemas.
+type Generator interface {
+	// Name is the name of the layer the generator produces. It enables the
+	// generator in the config, and is recorded with every file it produces.
+	Name() string
+
+	// Dirs returns the output directories of the generator. Manual sections
+	// are read from them, and without a manifest the files in them are
+	// checked for orphans.
+	Dirs(config Config) []string
+
+	// Validate checks the files of the generator on disk before anything is
+	// written. Files in owned were generated before and are checked against
+	// the manifest, unless merge or force is set. It returns the manual
+	// sections of the files on disk, keyed by path. ValidateOwnedFiles does
+	// it for the files under Dirs.
+	Validate(
+		config Config,
+		schemas []Schema,
+		owned map[string]ManifestEntry,
+		merge bool,
+		force bool,
+	) (map[string][]ManualSection, error)
+
+	// Render renders the selected files in memory, carrying over the manual
+	// sections keyed by path. Files generated from every schema are rendered
+	// whenever anything is selected.
+	Render(
+		config Config,
+		schemas []Schema,
+		filter Filter,
+		manualParts map[string][]ManualSection,
+	) ([]File, error)
+}
+
+var registeredGenerators = map[string]Generator{}
+
+// RegisterGenerator adds generators to the registry, usually from the init
+// function of the package that defines them. Registering two generators with
+// the same name panics.
+func RegisterGenerator(generators ...Generator) {
+	for _, g := range generators {
+		if _, ok := registeredGenerators[g.Name()]; ok {
+			panic("codegen: generator " + g.Name() + " is registered twice")
+		}
+		registeredGenerators[g.Name()] = g
+	}
+}
+
+// LookupGenerator returns the registered generator of the layer.
+func LookupGenerator(layer string) (Generator, bool) {
+	g, ok := registeredGenerators[layer]
+	return g, ok
+}
+
+// GeneratorNames returns the names of the registered generators, sorted.
+func GeneratorNames() []string {
+	names := []string{}
+	for name := range registeredGenerators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Renderer renders the files of a generator with a pool of workers, as the
+// files of every schema and edge are independent of each other. The files
+// keep the order they were queued in, whatever order they are rendered in,
+// and a render reports every file that failed rather than the first one.
+type Renderer struct {
+	Layer   string // Layer the files belong to
+	Workers int    // Number of files rendered at the same time, see Config
+	jobs    []renderJob
+	paths   map[string]Source // Source of each queued file, by path
+}
+
+// renderJob is a file queued for rendering.
+type renderJob struct {
+	path   string
+	source Source
+	write  func() (string, error)
+}
+
+// Render queues a file, rendered by the writer when Result is called. The
+// writer runs on another goroutine, so it must not share state with the
+// writers of other files. A path that is already queued fails the render, as
+// one of the files would overwrite the other.
+func (r *Renderer) Render(
+	path string,
+	source Source,
+	write func() (string, error),
+) {
+	if r.paths == nil {
+		r.paths = map[string]Source{}
+	}
+	if other, ok := r.paths[path]; ok {
+		from := File{Layer: r.Layer, Source: other}.describeSource()
+		write = func() (string, error) {
+			return "", fmt.Errorf("the file is also rendered from %s", from)
+		}
+	} else {
+		r.paths[path] = source
+	}
+	r.jobs = append(r.jobs, renderJob{path: path, source: source, write: write})
+}
+
+// Add adds the output of a writer that already ran as a file.
+func (r *Renderer) Add(path string, source Source, output string, err error) {
+	r.Render(path, source, func() (string, error) {
+		return output, err
+	})
+}
+
+// Result renders the queued files and returns them, or the errors of the
+// writers with the path and source of their file.
+func (r *Renderer) Result() ([]File, error) {
+	jobs := r.jobs
+	r.jobs, r.paths = nil, nil
+	outputs := make([]string, len(jobs))
+	errs := make([]error, len(jobs))
+
+	workers := r.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				outputs[i], errs[i] = runWriter(jobs[i].write)
+			}
+		}()
+	}
+	for i := range jobs {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	files := []File{}
+	failed := Errors{}
+	for i, j := range jobs {
+		if errs[i] != nil {
+			failed.Add(errs[i], Error{Layer: r.Layer, Schema: j.source.Schema,
+				Edge: j.source.Edge, File: j.path})
+			continue
+		}
+		files = append(files, NewFile(j.path, r.Layer, j.source, outputs[i]))
+	}
+	if err := failed.Err(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// runWriter runs a writer on a worker. A panic can not be recovered outside
+// of the goroutine it happened on, so it is returned as an error here.
+func runWriter(write func() (string, error)) (output string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("the writer panicked: %v", r)
+		}
+	}()
+	return write()
+}
@@ -0,0 +1,52 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRendererRejectsDuplicatePaths(t *testing.T) {
+	r := Renderer{Layer: LayerGraphQL}
+	write := func() (string, error) { return "package resolvers\n", nil }
+	r.Render("type_edge_grouptouser.go", Source{Schema: "User",
+		Edge: "UserToGroup"}, write)
+	r.Render("type_edge_grouptouser.go", Source{Schema: "Group",
+		Edge: "GroupToMember"}, write)
+
+	_, err := r.Result()
+	if err == nil {
+		t.Fatal("expected an error for a path rendered twice")
+	}
+	for _, source := range []string{"edge UserToGroup", "edge GroupToMember"} {
+		if !strings.Contains(err.Error(), source) {
+			t.Errorf("error %q does not name %s", err, source)
+		}
+	}
+}
+
+func TestValidateSchemasUntypedDefaultValue(t *testing.T) {
+	user := FixtureSchema("User")
+	user.Fields = append(user.Fields,
+		*Field().SetName("balance").SetCodeName("Balance").SetType(FloatType).
+			SetDefaultValue("0").SetExampleValue("1").
+			SetPrivacy(FixturePolicy("AllowAll")).
+			SetWritePrivacy(FixturePolicy("OwnerOnly")))
+
+	err := ValidateSchemas([]Schema{user})
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("expected one error, got %v", err)
+	}
+	if e := errs[0]; e.Code != CodeInvalidDefault || e.Field != "balance" ||
+		!strings.Contains(e.Err.Error(), "stored as int") {
+		t.Errorf("unexpected error %v", e)
+	}
+
+	for _, value := range []string{"0.0", "float64(0)"} {
+		user.Fields[len(user.Fields)-1].DefaultValue = value
+		err = ValidateSchemas([]Schema{user})
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", value, err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// The built in generator of the graphql layer. It is registered when the
+// package is imported.
+
+package graphql
+
+import (
+	"errors"
+	"path/filepath"
+	cg "splits-go-schema-codegen/codegen"
+	"strings"
+)
+
+func init() {
+	cg.RegisterGenerator(Generator{})
+}
+
+// Generator is the built in generator of the graphql layer.
+type Generator struct{}
+
+// Name returns the name of the graphql layer.
+func (Generator) Name() string {
+	return cg.LayerGraphQL
+}
+
+// Dirs returns the graphql package and its resolvers package.
+func (Generator) Dirs(config cg.Config) []string {
+	return []string{config.GraphQLDir(), config.ResolversDir()}
+}
+
+// Validate checks the graphql files on disk and returns their manual sections.
+func (g Generator) Validate(
+	config cg.Config,
+	schemas []cg.Schema,
+	owned map[string]cg.ManifestEntry,
+	mergeFlag bool,
+	forceFlag bool,
+) (map[string][]cg.ManualSection, error) {
+	return cg.ValidateOwnedFiles(g.Dirs(config), owned, mergeFlag, forceFlag)
+}
+
+// Render renders the selected files of the graphql layer in memory, carrying
+// over the manual sections keyed by path. The files generated from every node
+// are rendered when any node or edge is selected.
+func (Generator) Render(
+	config cg.Config,
+	schemas []cg.Schema,
+	filter cg.Filter,
+	manualParts map[string][]cg.ManualSection,
+) ([]cg.File, error) {
+	graphqlSchema, err := prepGraphQLSchema(schemas)
+	if err != nil {
+		return nil, err
+	}
+	dir := config.GraphQLDir()
+	resolversDir := config.ResolversDir()
+	packageName := config.GraphQL.Package
+	r := cg.Renderer{Layer: cg.LayerGraphQL, Workers: config.Workers}
+
+	// Names of the schemas the graphql nodes belong to
+	schemaNames := map[string]string{}
+	for _, s := range schemas {
+		if n := s.GetGraphQLNode(); n != nil {
+			schemaNames[n.Name] = s.GetName()
+		}
+	}
+
+	// Reverse edges are defined by the schema of the node they point to
+	selectsNode := func(n cg.GraphQLNode) bool {
+		return filter.SelectsSchema(schemaNames[n.Name])
+	}
+	selectsEdge := func(n cg.GraphQLNode, e cg.GraphQLEdge) bool {
+		return filter.SelectsEdge(schemaNames[n.Name], e.EdgeCodeName) ||
+			e.IsReverse && filter.SelectsSchema(schemaNames[e.To])
+	}
+	shared := false
+	for _, n := range graphqlSchema.Nodes {
+		shared = shared || selectsNode(n)
+		for _, e := range n.Edges {
+			shared = shared || selectsEdge(n, e)
+		}
+	}
+
+	nextPackageName := config.GraphQL.ResolversPackage
+	if shared {
+		// =========================================================================
+		// Generate the schema code
+		// =========================================================================
+		schemaFile := filepath.Join(dir, "schema.go")
+		r.Render(schemaFile, cg.Source{}, func() (string, error) {
+			return WriteGraphQLSchema(schemas, graphqlSchema,
+				manualParts[schemaFile], packageName)
+		})
+
+		// =========================================================================
+		// Generate the node type
+		// =========================================================================
+		nodeTypeFile := filepath.Join(resolversDir, "type_node.go")
+		r.Render(nodeTypeFile, cg.Source{}, func() (string, error) {
+			return WriteGraphQLNodeType(schemas, graphqlSchema,
+				manualParts[nodeTypeFile], nextPackageName)
+		})
+
+		// =========================================================================
+		// Generate the root query
+		// =========================================================================
+		rootQueryFile := filepath.Join(resolversDir, "type_root_query.go")
+		r.Render(rootQueryFile, cg.Source{}, func() (string, error) {
+			return WriteRootQueryType(schemas, graphqlSchema,
+				manualParts[rootQueryFile], nextPackageName)
+		})
+	}
+
+	// ===========================================================================
+	// Generate the node resolvers
+	// ===========================================================================
+	for _, n := range graphqlSchema.Nodes {
+		n := n
+		if !selectsNode(n) {
+			continue
+		}
+		f := filepath.Join(resolversDir,
+			"type_"+strings.ToLower(n.CodeName)+".go")
+		r.Render(f, cg.Source{Schema: schemaNames[n.Name]},
+			func() (string, error) {
+				return WriteGQLNodeResolverType(n, manualParts[f],
+					nextPackageName)
+			})
+	}
+
+	// ===========================================================================
+	// Generate the edge resolvers, shared by the edges between the same nodes
+	// ===========================================================================
+	edgeFiles := map[string]bool{}
+	for _, n := range graphqlSchema.Nodes {
+		for _, e := range n.Edges {
+			e := e
+			if !selectsEdge(n, e) {
+				continue
+			}
+			f := filepath.Join(resolversDir, "type_edge_"+
+				strings.ToLower(e.FromCodeName+"to"+e.ToCodeName)+".go")
+			if edgeFiles[f] {
+				continue
+			}
+			edgeFiles[f] = true
+			r.Render(f, cg.Source{Schema: schemaNames[n.Name],
+				Edge: e.EdgeCodeName}, func() (string, error) {
+				return WriteGQLEdgeResolverType(e, manualParts[f],
+					nextPackageName)
+			})
+		}
+	}
+
+	// ===========================================================================
+	// Generate the dataloader
+	// ===========================================================================
+	if shared {
+		dataloaderFile := filepath.Join(resolversDir, "dataloader_batcher.go")
+		r.Render(dataloaderFile, cg.Source{}, func() (string, error) {
+			return WriteDataloaderBatcher(schemas, graphqlSchema,
+				manualParts[dataloaderFile], nextPackageName)
+		})
+	}
+
+	return r.Result()
+}
+
+// prepGraphQLSchema collects the graphql nodes of the schemas, and adds the
+// reverse edges to the nodes they point to.
+func prepGraphQLSchema(schemas []cg.Schema) (cg.GraphQLSchema, error) {
+	schema := cg.GraphQLSchema{}
+	nodes := []*cg.GraphQLNode{}
+	oppositeNodes := map[string]*cg.GraphQLNode{}
+	schemaNames := map[string]string{}
+	for _, s := range schemas {
+		n := s.GetGraphQLNode()
+		if n != nil {
+			schemaNames[n.Name] = s.GetName()
+			// Copy the node, as the reverse edges are added to it below
+			node := *n
+			node.Edges = append([]cg.GraphQLEdge{}, n.Edges...)
+			nodes = append(nodes, &node)
+			oppositeNodes[n.Name] = &node
+		}
+	}
+	for _, n := range nodes {
+		schema.Nodes = append(schema.Nodes, *n)
+		for _, e := range n.Edges {
+			schema.Edges = append(schema.Edges, e)
+			if e.IncludeReverse {
+				edge := cg.GraphQLEdge{
+					From:             e.To,
+					To:               e.From,
+					FieldName:        e.ReverseFieldName,
+					FieldCodeName:    e.ReverseFieldCodeName,
+					FieldResolveName: e.ReverseFieldResolveName,
+					Description:      e.ReverseDescription,
+					FromCodeName:     e.ToCodeName,
+					ToCodeName:       e.FromCodeName,
+					Fields:           e.Fields,
+					TotalName:        e.TotalName,
+					IsReverse:        true,
+					EdgeCodeName:     e.EdgeCodeName,
+					OrderBy:          e.ReverseOrderBy,
+				}
+				oppositeNode, ok := oppositeNodes[e.To]
+				if !ok {
+					return schema, &cg.Error{Layer: cg.LayerGraphQL,
+						Schema: schemaNames[n.Name], Edge: e.EdgeCodeName,
+						Err: errors.New("the reverse of the graphql edge points to " +
+							e.To + ", which has no graphql node")}
+				}
+				oppositeNode.Edges = append(oppositeNode.Edges, edge)
+				schema.Edges = append(schema.Edges, edge)
+			}
+		}
+	}
+	return schema, nil
+}
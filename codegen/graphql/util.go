@@ -1,17 +1,5 @@
 package graphql
 
-import "strings"
-
-func initManualPart(manualParts []string) func() string {
-	index := 0
-	return func() string {
-		if manualParts == nil {
-			return ""
-		}
-		if index >= len(manualParts) {
-			return ""
-		}
-		index++
-		return strings.Replace(manualParts[index-1], "%", "%%", -1)
-	}
-}
+// manualBatcher is the name of the manual section of the dataloader batcher
+// where the cases of hand-written kinds go.
+const manualBatcher = "batcher"
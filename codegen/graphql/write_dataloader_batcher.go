@@ -1,83 +1,82 @@
 package graphql
 
-import (
-	"crypto/md5"
-	"encoding/hex"
-	"fmt"
-	"go/format"
-	cg "splits-go-schema-codegen/codegen"
-	"strings"
-)
+import cg "splits-go-schema-codegen/codegen"
 
 // WriteDataloaderBatcher writes the dataloader batcher muxing.
 func WriteDataloaderBatcher(
 	schemas []cg.Schema,
 	schema cg.GraphQLSchema,
-	manualParts []string,
+	manualParts []cg.ManualSection,
 	packageName string,
-) string {
-
-	getManualPart := initManualPart(manualParts)
+) (string, error) {
+	manual := cg.NewManualParts(manualParts)
 
 	// Use templates to generate the node
-	sections := []string{}
-	sections = append(sections, GetDLBatcherFileHeaderCommentStr())
-	sections = append(sections, GetDLBatcherPackageStr(packageName))
-	sections = append(sections, GetDLBatcherImportStr(getManualPart()))
-	sections = append(sections, GetDLBatcherExtraFunctionsStr(getManualPart()))
-	sections = append(sections, GetDLBatcherGeneratedFunctionsTagStr())
-	sections = append(sections, GetDLBatcherBatcherStr(schema, getManualPart()))
-	result := strings.Join(sections, "\n")
-	res, err := format.Source([]byte(result))
+	sections := cg.Sections{}
+	sections.Add(GetDLBatcherFileHeaderCommentStr())
+	sections.Add(GetDLBatcherPackageStr(packageName))
+	sections.Add(GetDLBatcherImportStr(
+		manual.Get(cg.ManualImports)))
+	sections.Add(GetDLBatcherExtraFunctionsStr(
+		manual.Get(cg.ManualFunctions)))
+	sections.Add(GetDLBatcherGeneratedFunctionsTagStr())
+	sections.Add(GetDLBatcherBatcherStr(
+		schema, manual.Get(manualBatcher)))
+	if err := manual.Err(); err != nil {
+		return "", err
+	}
+	result, err := sections.Join("\n")
 	if err != nil {
-		fmt.Println(result)
-		panic(err)
+		return "", err
 	}
+	res, err := cg.FormatSource(result)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
 
-	signatureRes := []byte(cg.ReplaceAllStringSubmatchFunc(
-		cg.ManualExtractor,
-		string(res),
-		func(groups []string) string {
-			return cg.StartManual + groups[2] + cg.EndManual
-		},
-	))
-
-	// Generate the MD5 signature
-	sum := md5.Sum([]byte(signatureRes))
-	signature := hex.EncodeToString([]byte(sum[:]))
+var dLBatcherFileHeaderCommentTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/dl_batcher_file_header_comment",
+	Text: "// Autogenerated dataloader batcher - regenerate with " +
+		"splits-go-schema-" +
+		"codegen\n// Force regeneration over hand edits with " +
+		"generate --force.\n",
+})
 
-	// Add the signature to the top of the file
-	return "// @SignedSource (" + signature + ")\n" + string(res)
+// GetDLBatcherFileHeaderCommentStr generates an autogenerated tag.
+func GetDLBatcherFileHeaderCommentStr() (string, error) {
+	return dLBatcherFileHeaderCommentTemplate.Exec(nil)
 }
 
-// GetDLBatcherFileHeaderCommentStr generates an autogenerated tag.
-func GetDLBatcherFileHeaderCommentStr() string {
-	data := struct{}{}
-	template := "// Autogenerated dataloader batcher - regenerate with " +
-		"splits-go-schema-" +
-		"codegen\n// Force autogen by deleting the @SignedSource line.\n"
-	return cg.ExecTemplate(template, "dl_batcher_file_header_comment", data, nil)
+// DLBatcherPackageData is the data of the graphql/dl_batcher_package template.
+type DLBatcherPackageData struct {
+	Package string
 }
 
+var dLBatcherPackageTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/dl_batcher_package",
+	Data: DLBatcherPackageData{},
+	Text: "package {{.Package}}\n",
+})
+
 // GetDLBatcherPackageStr generates the package string.
-func GetDLBatcherPackageStr(packageName string) string {
-	data := struct {
-		Package string
-	}{
+func GetDLBatcherPackageStr(packageName string) (string, error) {
+	data := DLBatcherPackageData{
 		Package: packageName,
 	}
-	template := "package {{.Package}}\n"
-	return cg.ExecTemplate(template, "dl_batcher_package_string", data, nil)
+	return dLBatcherPackageTemplate.Exec(data)
 }
 
-// GetDLBatcherImportStr generates the import block.
-func GetDLBatcherImportStr(manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
-		ManualPart: manualPart,
-	}
-	template := "import (\n" +
+// DLBatcherImportData is the data of the graphql/dl_batcher_import template.
+type DLBatcherImportData struct {
+	ManualPart string
+}
+
+var dLBatcherImportTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/dl_batcher_import",
+	Data: DLBatcherImportData{},
+	Text: "import (\n" +
 		"\t\"context\"\n" +
 		"\t\"splits-go-api/auth/contexts\"\n" +
 		"\t\"splits-go-api/constants\"\n" +
@@ -87,63 +86,60 @@ func GetDLBatcherImportStr(manualPart string) string {
 		"\t\"splits-go-api/logic/util\"\n" +
 		"\t\"strings\"\n" +
 		"\n" +
-		cg.StartManual + "\n" +
+		cg.StartManualSection(cg.ManualImports) + "\n" +
 		"{{.ManualPart}}\n" +
 		cg.EndManual + "\n" +
-		")\n"
-	return cg.ExecTemplate(template, "dl_batcher_import_string", data, nil)
+		")\n",
+})
+
+// GetDLBatcherImportStr generates the import block.
+func GetDLBatcherImportStr(manualPart string) (string, error) {
+	data := DLBatcherImportData{
+		ManualPart: manualPart,
+	}
+	return dLBatcherImportTemplate.Exec(data)
+}
+
+// DLBatcherExtraFunctionsData is the data of the
+// graphql/dl_batcher_extra_functions template.
+type DLBatcherExtraFunctionsData struct {
+	ManualPart string
 }
 
+var dLBatcherExtraFunctionsTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/dl_batcher_extra_functions",
+	Data: DLBatcherExtraFunctionsData{},
+	Text: cg.StartManualSection(cg.ManualFunctions) + "\n" +
+		"{{.ManualPart}}\n" +
+		cg.EndManual + "\n",
+})
+
 // GetDLBatcherExtraFunctionsStr adds a manual sections for user defined
 // functions.
-func GetDLBatcherExtraFunctionsStr(manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
+func GetDLBatcherExtraFunctionsStr(manualPart string) (string, error) {
+	data := DLBatcherExtraFunctionsData{
 		ManualPart: manualPart,
 	}
-	template :=
-		cg.StartManual + "\n" +
-			"{{.ManualPart}}\n" +
-			cg.EndManual + "\n"
-	return cg.ExecTemplate(template, "dl_batcher_extra_functions", data, nil)
+	return dLBatcherExtraFunctionsTemplate.Exec(data)
 }
 
 // GetDLBatcherGeneratedFunctionsTagStr writes a generated functions tagline.
-func GetDLBatcherGeneratedFunctionsTagStr() string {
-	return "// === GENERATED FUNCTIONS === \n"
+func GetDLBatcherGeneratedFunctionsTagStr() (string, error) {
+	return "// === GENERATED FUNCTIONS === \n", nil
 }
 
-// GetDLBatcherBatcherStr writes the batcher function.
-func GetDLBatcherBatcherStr(s cg.GraphQLSchema, manualPart string) string {
-	edgeFields := []cg.GraphQLEdge{}
-	edgeFieldMap := map[string]bool{}
-	for _, e := range s.Edges {
-		if _, ok := edgeFieldMap[e.TotalName]; !ok {
-			edgeFields = append(edgeFields, e)
-			edgeFieldMap[e.TotalName] = true
-		}
-	}
-	edges := []cg.GraphQLEdge{}
-	edgeMap := map[string]bool{}
-	for _, e := range s.Edges {
-		if _, ok := edgeMap[e.FromCodeName+e.ToCodeName]; !ok {
-			edges = append(edges, e)
-			edgeMap[e.FromCodeName+e.ToCodeName] = true
-		}
-	}
-	data := struct {
-		Nodes      []cg.GraphQLNode
-		EdgeFields []cg.GraphQLEdge
-		Edges      []cg.GraphQLEdge
-		ManualPart string
-	}{
-		Nodes:      s.Nodes,
-		EdgeFields: edgeFields,
-		Edges:      edges,
-		ManualPart: manualPart,
-	}
-	template := "// Parse a connection's fields and get the order by clauses.\n" +
+// DLBatcherBatcherData is the data of the graphql/dl_batcher_batcher template.
+type DLBatcherBatcherData struct {
+	Nodes      []cg.GraphQLNode
+	EdgeFields []cg.GraphQLEdge
+	Edges      []cg.GraphQLEdge
+	ManualPart string
+}
+
+var dLBatcherBatcherTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/dl_batcher_batcher",
+	Data: DLBatcherBatcherData{},
+	Text: "// Parse a connection's fields and get the order by clauses.\n" +
 		"func parseConnectionOrderBy(fields []string) []p.OrderClauseStruct {\n" +
 		"\torderClauses := []p.OrderClauseStruct{}\n" +
 		"\tf := strings.Split(fields[0], \"#\")\n" +
@@ -200,7 +196,7 @@ func GetDLBatcherBatcherStr(s cg.GraphQLSchema, manualPart string) string {
 		// Node to node
 
 		"{{range .Edges}}" +
-		"\t\t\tcase \"{{.FromCodeName}}{{.ToCodeName}}\":\n" +
+		"\t\t\tcase \"{{.FromCodeName}}{{.FieldCodeName}}\":\n" +
 		"\t\t\t\t{\n" +
 		"\t\t\t\t\torderBy := parseConnectionOrderBy(fields)\n" +
 		"\t\t\t\t\tb, err := logic.Get{{.FromCodeName}}{{.FieldResolveName}}" +
@@ -234,13 +230,39 @@ func GetDLBatcherBatcherStr(s cg.GraphQLSchema, manualPart string) string {
 		"\t\t\t\t\t}\n" +
 		"\t\t\t\t}\n" +
 		"{{end}}" +
-		"\t\t\t" + cg.StartManual + "\n" +
-		"\t\t\t{{.ManualPart}}\n" +
+		"\t\t\t" + cg.StartManualSection(manualBatcher) + "\n" +
+		"{{.ManualPart}}\n" +
 		"\t\t\t" + cg.EndManual + "\n" +
 		"\t\t\t}\n" +
 		"\t\t}\n" +
 		"\t}\n" +
 		"\treturn batchedQueries, batchedMapper\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "node_type_parse_schema", data, nil)
+		"}\n",
+})
+
+// GetDLBatcherBatcherStr writes the batcher function.
+func GetDLBatcherBatcherStr(s cg.GraphQLSchema, manualPart string) (string, error) {
+	edgeFields := []cg.GraphQLEdge{}
+	edgeFieldMap := map[string]bool{}
+	for _, e := range s.Edges {
+		if _, ok := edgeFieldMap[e.TotalName]; !ok {
+			edgeFields = append(edgeFields, e)
+			edgeFieldMap[e.TotalName] = true
+		}
+	}
+	edges := []cg.GraphQLEdge{}
+	edgeMap := map[string]bool{}
+	for _, e := range s.Edges {
+		if _, ok := edgeMap[e.FromCodeName+e.FieldCodeName]; !ok {
+			edges = append(edges, e)
+			edgeMap[e.FromCodeName+e.FieldCodeName] = true
+		}
+	}
+	data := DLBatcherBatcherData{
+		Nodes:      s.Nodes,
+		EdgeFields: edgeFields,
+		Edges:      edges,
+		ManualPart: manualPart,
+	}
+	return dLBatcherBatcherTemplate.Exec(data)
 }
@@ -1,10 +1,6 @@
 package graphql
 
 import (
-	"crypto/md5"
-	"encoding/hex"
-	"fmt"
-	"go/format"
 	cg "splits-go-schema-codegen/codegen"
 	"strings"
 )
@@ -12,119 +8,137 @@ import (
 // WriteGQLEdgeResolverType writes the graphql node resolvers.
 func WriteGQLEdgeResolverType(
 	edge cg.GraphQLEdge,
-	manualParts []string,
+	manualParts []cg.ManualSection,
 	packageName string,
-) string {
-
-	getManualPart := initManualPart(manualParts)
+) (string, error) {
+	manual := cg.NewManualParts(manualParts)
 
 	// Use templates to generate the node
-	sections := []string{}
-	sections = append(sections, GetGQLEdgeResolverFileHeaderCommentStr())
-	sections = append(sections, GetGQLEdgeResolverPackageStr(packageName))
-	sections = append(sections, GetGQLEdgeResolverImportStr(getManualPart()))
-	sections = append(sections, GetGQLEdgeResolverExtraFunctionsStr(
-		getManualPart()))
-	sections = append(sections, GetGQLEdgeResolverGeneratedFunctionsTagStr())
-	sections = append(sections, GetGQLEdgeConnectionResolverStr(edge))
-	sections = append(sections, GetGQLEdgeEdgeResolverStr(edge))
-	result := strings.Join(sections, "\n")
-	res, err := format.Source([]byte(result))
+	sections := cg.Sections{}
+	sections.Add(GetGQLEdgeResolverFileHeaderCommentStr())
+	sections.Add(GetGQLEdgeResolverPackageStr(packageName))
+	sections.Add(GetGQLEdgeResolverImportStr(
+		manual.Get(cg.ManualImports)))
+	sections.Add(GetGQLEdgeResolverExtraFunctionsStr(
+		manual.Get(cg.ManualFunctions)))
+	sections.Add(GetGQLEdgeResolverGeneratedFunctionsTagStr())
+	sections.Add(GetGQLEdgeConnectionResolverStr(edge))
+	sections.Add(GetGQLEdgeEdgeResolverStr(edge))
+	if err := manual.Err(); err != nil {
+		return "", err
+	}
+	result, err := sections.Join("\n")
 	if err != nil {
-		fmt.Println(result)
-		panic(err)
+		return "", err
 	}
+	res, err := cg.FormatSource(result)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
 
-	signatureRes := []byte(cg.ReplaceAllStringSubmatchFunc(
-		cg.ManualExtractor,
-		string(res),
-		func(groups []string) string {
-			return cg.StartManual + groups[2] + cg.EndManual
-		},
-	))
-
-	// Generate the MD5 signature
-	sum := md5.Sum([]byte(signatureRes))
-	signature := hex.EncodeToString([]byte(sum[:]))
+var gQLEdgeResolverFileHeaderCommentTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_edge_resolver_file_header_comment",
+	Text: "// Autogenerated node type - regenerate with splits-go-schema-" +
+		"codegen\n// Force regeneration over hand edits with " +
+		"generate --force.\n",
+})
 
-	// Add the signature to the top of the file
-	return "// @SignedSource (" + signature + ")\n" + string(res)
+// GetGQLEdgeResolverFileHeaderCommentStr generates an autogenerated tag.
+func GetGQLEdgeResolverFileHeaderCommentStr() (string, error) {
+	return gQLEdgeResolverFileHeaderCommentTemplate.Exec(nil)
 }
 
-// GetGQLEdgeResolverFileHeaderCommentStr generates an autogenerated tag.
-func GetGQLEdgeResolverFileHeaderCommentStr() string {
-	data := struct{}{}
-	template := "// Autogenerated node type - regenerate with splits-go-schema-" +
-		"codegen\n// Force autogen by deleting the @SignedSource line.\n"
-	return cg.ExecTemplate(template, "edge_type_file_header_comment", data, nil)
+// GQLEdgeResolverPackageData is the data of the
+// graphql/gql_edge_resolver_package template.
+type GQLEdgeResolverPackageData struct {
+	Package string
 }
 
+var gQLEdgeResolverPackageTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_edge_resolver_package",
+	Data: GQLEdgeResolverPackageData{},
+	Text: "package {{.Package}}\n",
+})
+
 // GetGQLEdgeResolverPackageStr generates the package string.
-func GetGQLEdgeResolverPackageStr(packageName string) string {
-	data := struct {
-		Package string
-	}{
+func GetGQLEdgeResolverPackageStr(packageName string) (string, error) {
+	data := GQLEdgeResolverPackageData{
 		Package: packageName,
 	}
-	template := "package {{.Package}}\n"
-	return cg.ExecTemplate(template, "edge_type_package_string", data, nil)
+	return gQLEdgeResolverPackageTemplate.Exec(data)
 }
 
-// GetGQLEdgeResolverImportStr generates the import block.
-func GetGQLEdgeResolverImportStr(manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
-		ManualPart: manualPart,
-	}
-	template := "import (\n" +
+// GQLEdgeResolverImportData is the data of the graphql/gql_edge_resolver_import
+// template.
+type GQLEdgeResolverImportData struct {
+	ManualPart string
+}
+
+var gQLEdgeResolverImportTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_edge_resolver_import",
+	Data: GQLEdgeResolverImportData{},
+	Text: "import (\n" +
 		"\tgraphql \"github.com/neelance/graphql-go\"\n" +
 		"\n" +
-		cg.StartManual + "\n" +
+		cg.StartManualSection(cg.ManualImports) + "\n" +
 		"{{.ManualPart}}\n" +
 		cg.EndManual + "\n" +
-		")\n"
-	return cg.ExecTemplate(template, "edge_type_import_string", data, nil)
+		")\n",
+})
+
+// GetGQLEdgeResolverImportStr generates the import block.
+func GetGQLEdgeResolverImportStr(manualPart string) (string, error) {
+	data := GQLEdgeResolverImportData{
+		ManualPart: manualPart,
+	}
+	return gQLEdgeResolverImportTemplate.Exec(data)
 }
 
+// GQLEdgeResolverExtraFunctionsData is the data of the
+// graphql/gql_edge_resolver_extra_functions template.
+type GQLEdgeResolverExtraFunctionsData struct {
+	ManualPart string
+}
+
+var gQLEdgeResolverExtraFunctionsTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_edge_resolver_extra_functions",
+	Data: GQLEdgeResolverExtraFunctionsData{},
+	Text: cg.StartManualSection(cg.ManualFunctions) + "\n" +
+		"{{.ManualPart}}\n" +
+		cg.EndManual + "\n",
+})
+
 // GetGQLEdgeResolverExtraFunctionsStr adds a manual sections for user defined
 // functions.
-func GetGQLEdgeResolverExtraFunctionsStr(manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
+func GetGQLEdgeResolverExtraFunctionsStr(manualPart string) (string, error) {
+	data := GQLEdgeResolverExtraFunctionsData{
 		ManualPart: manualPart,
 	}
-	template :=
-		cg.StartManual + "\n" +
-			"{{.ManualPart}}\n" +
-			cg.EndManual + "\n"
-	return cg.ExecTemplate(template, "edge_type_extra_functions", data, nil)
+	return gQLEdgeResolverExtraFunctionsTemplate.Exec(data)
 }
 
 // GetGQLEdgeResolverGeneratedFunctionsTagStr writes a generated functions
 // tagline.
-func GetGQLEdgeResolverGeneratedFunctionsTagStr() string {
-	return "// === GENERATED FUNCTIONS === \n"
+func GetGQLEdgeResolverGeneratedFunctionsTagStr() (string, error) {
+	return "// === GENERATED FUNCTIONS === \n", nil
 }
 
-// GetGQLEdgeConnectionResolverStr writes the resolver connection type.
-func GetGQLEdgeConnectionResolverStr(e cg.GraphQLEdge) string {
-	data := struct {
-		From         string
-		FromCodeName string
-		To           string
-		ToCodeName   string
-		Var          string
-	}{
-		From:         e.From,
-		To:           e.To,
-		FromCodeName: e.FromCodeName,
-		ToCodeName:   e.ToCodeName,
-		Var: strings.ToLower(string(e.FromCodeName[0]) +
-			string(e.ToCodeName[0])),
-	}
-	template := "// {{.FromCodeName}}To{{.ToCodeName}}ConnectionArgs are the " +
+// GQLEdgeConnectionResolverData is the data of the
+// graphql/gql_edge_connection_resolver template.
+type GQLEdgeConnectionResolverData struct {
+	From         string
+	FromCodeName string
+	To           string
+	ToCodeName   string
+	Var          string
+}
+
+var gQLEdgeConnectionResolverTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_edge_connection_resolver",
+	Data: GQLEdgeConnectionResolverData{},
+	Text: "// {{.FromCodeName}}To{{.ToCodeName}}ConnectionArgs are the " +
 		"graphql connection args.\n" +
 		"type {{.FromCodeName}}To{{.ToCodeName}}ConnectionArgs struct {\n" +
 		"\tFirst *int32\n" +
@@ -181,44 +195,40 @@ func GetGQLEdgeConnectionResolverStr(e cg.GraphQLEdge) string {
 		"endCursor:   encodeCursor({{.Var}}.to - 1),\n" +
 		"hasNextPage: {{.Var}}.to < len({{.Var}}.ids),\n" +
 		"}\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge_connection_resolver_type", data, nil)
-}
+		"}\n",
+})
 
-// GetGQLEdgeEdgeResolverStr writes the resolver type for the edge.
-func GetGQLEdgeEdgeResolverStr(e cg.GraphQLEdge) string {
-	fields := []cg.GraphQLField{}
-	timeFields := []cg.GraphQLField{}
-	for _, f := range e.Fields {
-		if f.CodeType == "graphql.Time" {
-			timeFields = append(timeFields, f)
-			continue
-		}
-		fields = append(fields, f)
-	}
-	data := struct {
-		From         string
-		FromCodeName string
-		To           string
-		ToCodeName   string
-		Var          string
-		Fields       []cg.GraphQLField
-		TimeFields   []cg.GraphQLField
-		TotalName    string
-		IsReverse    bool
-	}{
+// GetGQLEdgeConnectionResolverStr writes the resolver connection type.
+func GetGQLEdgeConnectionResolverStr(e cg.GraphQLEdge) (string, error) {
+	data := GQLEdgeConnectionResolverData{
 		From:         e.From,
 		To:           e.To,
 		FromCodeName: e.FromCodeName,
 		ToCodeName:   e.ToCodeName,
 		Var: strings.ToLower(string(e.FromCodeName[0]) +
 			string(e.ToCodeName[0])),
-		Fields:     fields,
-		TimeFields: timeFields,
-		TotalName:  e.TotalName,
-		IsReverse:  e.IsReverse,
 	}
-	template := "// {{.FromCodeName}}To{{.ToCodeName}}EdgeResolver is the " +
+	return gQLEdgeConnectionResolverTemplate.Exec(data)
+}
+
+// GQLEdgeEdgeResolverData is the data of the graphql/gql_edge_edge_resolver
+// template.
+type GQLEdgeEdgeResolverData struct {
+	From         string
+	FromCodeName string
+	To           string
+	ToCodeName   string
+	Var          string
+	Fields       []cg.GraphQLField
+	TimeFields   []cg.GraphQLField
+	TotalName    string
+	IsReverse    bool
+}
+
+var gQLEdgeEdgeResolverTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_edge_edge_resolver",
+	Data: GQLEdgeEdgeResolverData{},
+	Text: "// {{.FromCodeName}}To{{.ToCodeName}}EdgeResolver is the " +
 		"graphql edge resolver.\n" +
 		"type {{.FromCodeName}}To{{.ToCodeName}}EdgeResolver struct {\n" +
 		"\tcursor graphql.ID\n" +
@@ -307,6 +317,31 @@ func GetGQLEdgeEdgeResolverStr(e cg.GraphQLEdge) string {
 		"\treturn &graphql.Time{Time: timeValue}, nil\n" +
 		"}\n" +
 		"\n" +
-		"{{end}}"
-	return cg.ExecTemplate(template, "edge_edge_resolver_type", data, nil)
+		"{{end}}",
+})
+
+// GetGQLEdgeEdgeResolverStr writes the resolver type for the edge.
+func GetGQLEdgeEdgeResolverStr(e cg.GraphQLEdge) (string, error) {
+	fields := []cg.GraphQLField{}
+	timeFields := []cg.GraphQLField{}
+	for _, f := range e.Fields {
+		if f.CodeType == "graphql.Time" {
+			timeFields = append(timeFields, f)
+			continue
+		}
+		fields = append(fields, f)
+	}
+	data := GQLEdgeEdgeResolverData{
+		From:         e.From,
+		To:           e.To,
+		FromCodeName: e.FromCodeName,
+		ToCodeName:   e.ToCodeName,
+		Var: strings.ToLower(string(e.FromCodeName[0]) +
+			string(e.ToCodeName[0])),
+		Fields:     fields,
+		TimeFields: timeFields,
+		TotalName:  e.TotalName,
+		IsReverse:  e.IsReverse,
+	}
+	return gQLEdgeEdgeResolverTemplate.Exec(data)
 }
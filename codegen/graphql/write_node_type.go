@@ -1,126 +1,139 @@
 package graphql
 
-import (
-	"crypto/md5"
-	"encoding/hex"
-	"fmt"
-	"go/format"
-	cg "splits-go-schema-codegen/codegen"
-	"strings"
-)
+import cg "splits-go-schema-codegen/codegen"
 
 // WriteGraphQLNodeType writes the graphql base node type.
 func WriteGraphQLNodeType(
 	schemas []cg.Schema,
 	schema cg.GraphQLSchema,
-	manualParts []string,
+	manualParts []cg.ManualSection,
 	packageName string,
-) string {
-
-	getManualPart := initManualPart(manualParts)
+) (string, error) {
+	manual := cg.NewManualParts(manualParts)
 
 	// Use templates to generate the node
-	sections := []string{}
-	sections = append(sections, GetGQLNodeFileHeaderCommentStr())
-	sections = append(sections, GetGQLNodePackageStr(packageName))
-	sections = append(sections, GetGQLNodeImportStr(getManualPart()))
-	sections = append(sections, GetGQLNodeExtraFunctionsStr(getManualPart()))
-	sections = append(sections, GetGQLNodeGeneratedFunctionsTagStr())
-	sections = append(sections, GetGQLNodeInterfaceAndResolverStr(schema))
-	sections = append(sections, GetGQLNodeRootQueryStr(schema))
-	result := strings.Join(sections, "\n")
-	res, err := format.Source([]byte(result))
+	sections := cg.Sections{}
+	sections.Add(GetGQLNodeFileHeaderCommentStr())
+	sections.Add(GetGQLNodePackageStr(packageName))
+	sections.Add(GetGQLNodeImportStr(
+		manual.Get(cg.ManualImports)))
+	sections.Add(GetGQLNodeExtraFunctionsStr(
+		manual.Get(cg.ManualFunctions)))
+	sections.Add(GetGQLNodeGeneratedFunctionsTagStr())
+	sections.Add(GetGQLNodeInterfaceAndResolverStr(schema))
+	sections.Add(GetGQLNodeRootQueryStr(schema))
+	if err := manual.Err(); err != nil {
+		return "", err
+	}
+	result, err := sections.Join("\n")
 	if err != nil {
-		fmt.Println(result)
-		panic(err)
+		return "", err
 	}
+	res, err := cg.FormatSource(result)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
 
-	signatureRes := []byte(cg.ReplaceAllStringSubmatchFunc(
-		cg.ManualExtractor,
-		string(res),
-		func(groups []string) string {
-			return cg.StartManual + groups[2] + cg.EndManual
-		},
-	))
-
-	// Generate the MD5 signature
-	sum := md5.Sum([]byte(signatureRes))
-	signature := hex.EncodeToString([]byte(sum[:]))
+var gQLNodeFileHeaderCommentTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_node_file_header_comment",
+	Text: "// Autogenerated node type - regenerate with splits-go-schema-" +
+		"codegen\n// Force regeneration over hand edits with " +
+		"generate --force.\n",
+})
 
-	// Add the signature to the top of the file
-	return "// @SignedSource (" + signature + ")\n" + string(res)
+// GetGQLNodeFileHeaderCommentStr generates an autogenerated tag.
+func GetGQLNodeFileHeaderCommentStr() (string, error) {
+	return gQLNodeFileHeaderCommentTemplate.Exec(nil)
 }
 
-// GetGQLNodeFileHeaderCommentStr generates an autogenerated tag.
-func GetGQLNodeFileHeaderCommentStr() string {
-	data := struct{}{}
-	template := "// Autogenerated node type - regenerate with splits-go-schema-" +
-		"codegen\n// Force autogen by deleting the @SignedSource line.\n"
-	return cg.ExecTemplate(template, "node_type_file_header_comment", data, nil)
+// GQLNodePackageData is the data of the graphql/gql_node_package template.
+type GQLNodePackageData struct {
+	Package string
 }
 
+var gQLNodePackageTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_node_package",
+	Data: GQLNodePackageData{},
+	Text: "package {{.Package}}\n",
+})
+
 // GetGQLNodePackageStr generates the package string.
-func GetGQLNodePackageStr(packageName string) string {
-	data := struct {
-		Package string
-	}{
+func GetGQLNodePackageStr(packageName string) (string, error) {
+	data := GQLNodePackageData{
 		Package: packageName,
 	}
-	template := "package {{.Package}}\n"
-	return cg.ExecTemplate(template, "node_type_package_string", data, nil)
+	return gQLNodePackageTemplate.Exec(data)
 }
 
-// GetGQLNodeImportStr generates the import block.
-func GetGQLNodeImportStr(manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
-		ManualPart: manualPart,
-	}
-	template := "import (\n" +
+// GQLNodeImportData is the data of the graphql/gql_node_import template.
+type GQLNodeImportData struct {
+	ManualPart string
+}
+
+var gQLNodeImportTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_node_import",
+	Data: GQLNodeImportData{},
+	Text: "import (\n" +
 		"\t\"context\"\n" +
 		"\t\"errors\"\n" +
 		"\n" +
 		"\tgraphql \"github.com/neelance/graphql-go\"\n" +
 		"\n" +
-		cg.StartManual + "\n" +
+		cg.StartManualSection(cg.ManualImports) + "\n" +
 		"{{.ManualPart}}\n" +
 		cg.EndManual + "\n" +
-		")\n"
-	return cg.ExecTemplate(template, "node_type_import_string", data, nil)
+		")\n",
+})
+
+// GetGQLNodeImportStr generates the import block.
+func GetGQLNodeImportStr(manualPart string) (string, error) {
+	data := GQLNodeImportData{
+		ManualPart: manualPart,
+	}
+	return gQLNodeImportTemplate.Exec(data)
 }
 
+// GQLNodeExtraFunctionsData is the data of the graphql/gql_node_extra_functions
+// template.
+type GQLNodeExtraFunctionsData struct {
+	ManualPart string
+}
+
+var gQLNodeExtraFunctionsTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_node_extra_functions",
+	Data: GQLNodeExtraFunctionsData{},
+	Text: cg.StartManualSection(cg.ManualFunctions) + "\n" +
+		"{{.ManualPart}}\n" +
+		cg.EndManual + "\n",
+})
+
 // GetGQLNodeExtraFunctionsStr adds a manual sections for user defined
 // functions.
-func GetGQLNodeExtraFunctionsStr(manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
+func GetGQLNodeExtraFunctionsStr(manualPart string) (string, error) {
+	data := GQLNodeExtraFunctionsData{
 		ManualPart: manualPart,
 	}
-	template :=
-		cg.StartManual + "\n" +
-			"{{.ManualPart}}\n" +
-			cg.EndManual + "\n"
-	return cg.ExecTemplate(template, "node_type_extra_functions", data, nil)
+	return gQLNodeExtraFunctionsTemplate.Exec(data)
 }
 
 // GetGQLNodeGeneratedFunctionsTagStr writes a generated functions tagline.
-func GetGQLNodeGeneratedFunctionsTagStr() string {
-	return "// === GENERATED FUNCTIONS === \n"
+func GetGQLNodeGeneratedFunctionsTagStr() (string, error) {
+	return "// === GENERATED FUNCTIONS === \n", nil
 }
 
-// GetGQLNodeInterfaceAndResolverStr writes the node interface and resolver
-// types.
-func GetGQLNodeInterfaceAndResolverStr(s cg.GraphQLSchema) string {
-	data := struct {
-		Nodes []cg.GraphQLNode
-		Edges []cg.GraphQLEdge
-	}{
-		Nodes: s.Nodes,
-		Edges: s.Edges,
-	}
-	template := "// Node interface represents a generic node in the graph.\n" +
+// GQLNodeInterfaceAndResolverData is the data of the
+// graphql/gql_node_interface_and_resolver template.
+type GQLNodeInterfaceAndResolverData struct {
+	Nodes []cg.GraphQLNode
+	Edges []cg.GraphQLEdge
+}
+
+var gQLNodeInterfaceAndResolverTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_node_interface_and_resolver",
+	Data: GQLNodeInterfaceAndResolverData{},
+	Text: "// Node interface represents a generic node in the graph.\n" +
 		"type Node interface {\n" +
 		"\tID(context.Context) (graphql.ID, error)\n" +
 		"}\n" +
@@ -139,20 +152,29 @@ func GetGQLNodeInterfaceAndResolverStr(s cg.GraphQLSchema) string {
 		"\treturn res, ok\n" +
 		"}\n" +
 		"\n" +
-		"{{end}}"
-	return cg.ExecTemplate(template, "node_type_parse_schema", data, nil)
-}
+		"{{end}}",
+})
 
-// GetGQLNodeRootQueryStr generates the function that is the node root query.
-func GetGQLNodeRootQueryStr(s cg.GraphQLSchema) string {
-	data := struct {
-		Nodes []cg.GraphQLNode
-		Edges []cg.GraphQLEdge
-	}{
+// GetGQLNodeInterfaceAndResolverStr writes the node interface and resolver
+// types.
+func GetGQLNodeInterfaceAndResolverStr(s cg.GraphQLSchema) (string, error) {
+	data := GQLNodeInterfaceAndResolverData{
 		Nodes: s.Nodes,
 		Edges: s.Edges,
 	}
-	template := "// Node is the root query resolver for a specific node.\n" +
+	return gQLNodeInterfaceAndResolverTemplate.Exec(data)
+}
+
+// GQLNodeRootQueryData is the data of the graphql/gql_node_root_query template.
+type GQLNodeRootQueryData struct {
+	Nodes []cg.GraphQLNode
+	Edges []cg.GraphQLEdge
+}
+
+var gQLNodeRootQueryTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_node_root_query",
+	Data: GQLNodeRootQueryData{},
+	Text: "// Node is the root query resolver for a specific node.\n" +
 		"func (r *Resolver) Node(ctx context.Context, args idArg) " +
 		"(*NodeResolver, error) {\n" +
 		"\t\n" +
@@ -169,6 +191,14 @@ func GetGQLNodeRootQueryStr(s cg.GraphQLSchema) string {
 		"{{end}}" +
 		"\t}\n" +
 		"\treturn nil, errors.New(\"invalid node type: \" + kind)" +
-		"}\n"
-	return cg.ExecTemplate(template, "node_type_root_query", data, nil)
+		"}\n",
+})
+
+// GetGQLNodeRootQueryStr generates the function that is the node root query.
+func GetGQLNodeRootQueryStr(s cg.GraphQLSchema) (string, error) {
+	data := GQLNodeRootQueryData{
+		Nodes: s.Nodes,
+		Edges: s.Edges,
+	}
+	return gQLNodeRootQueryTemplate.Exec(data)
 }
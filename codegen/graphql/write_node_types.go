@@ -1,10 +1,6 @@
 package graphql
 
 import (
-	"crypto/md5"
-	"encoding/hex"
-	"fmt"
-	"go/format"
 	cg "splits-go-schema-codegen/codegen"
 	"strings"
 )
@@ -12,72 +8,78 @@ import (
 // WriteGQLNodeResolverType writes the graphql node resolvers.
 func WriteGQLNodeResolverType(
 	node cg.GraphQLNode,
-	manualParts []string,
+	manualParts []cg.ManualSection,
 	packageName string,
-) string {
-
-	getManualPart := initManualPart(manualParts)
+) (string, error) {
+	manual := cg.NewManualParts(manualParts)
 
 	// Use templates to generate the node
-	sections := []string{}
-	sections = append(sections, GetGQLNodeResolverFileHeaderCommentStr())
-	sections = append(sections, GetGQLNodeResolverPackageStr(packageName))
-	sections = append(sections, GetGQLNodeResolverImportStr(getManualPart()))
-	sections = append(sections, GetGQLNodeResolverExtraFunctionsStr(
-		getManualPart()))
-	sections = append(sections, GetGQLNodeResolverGeneratedFunctionsTagStr())
-	sections = append(sections, GetGQLNodeResolverStr(node))
-	sections = append(sections, GetGQLNodeEdgeResolverStr(node))
-	result := strings.Join(sections, "\n")
-	res, err := format.Source([]byte(result))
+	sections := cg.Sections{}
+	sections.Add(GetGQLNodeResolverFileHeaderCommentStr())
+	sections.Add(GetGQLNodeResolverPackageStr(packageName))
+	sections.Add(GetGQLNodeResolverImportStr(
+		manual.Get(cg.ManualImports)))
+	sections.Add(GetGQLNodeResolverExtraFunctionsStr(
+		manual.Get(cg.ManualFunctions)))
+	sections.Add(GetGQLNodeResolverGeneratedFunctionsTagStr())
+	sections.Add(GetGQLNodeResolverStr(node))
+	sections.Add(GetGQLNodeEdgeResolverStr(node))
+	if err := manual.Err(); err != nil {
+		return "", err
+	}
+	result, err := sections.Join("\n")
 	if err != nil {
-		fmt.Println(result)
-		panic(err)
+		return "", err
 	}
+	res, err := cg.FormatSource(result)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
 
-	signatureRes := []byte(cg.ReplaceAllStringSubmatchFunc(
-		cg.ManualExtractor,
-		string(res),
-		func(groups []string) string {
-			return cg.StartManual + groups[2] + cg.EndManual
-		},
-	))
-
-	// Generate the MD5 signature
-	sum := md5.Sum([]byte(signatureRes))
-	signature := hex.EncodeToString([]byte(sum[:]))
+var gQLNodeResolverFileHeaderCommentTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_node_resolver_file_header_comment",
+	Text: "// Autogenerated node type - regenerate with splits-go-schema-" +
+		"codegen\n// Force regeneration over hand edits with " +
+		"generate --force.\n",
+})
 
-	// Add the signature to the top of the file
-	return "// @SignedSource (" + signature + ")\n" + string(res)
+// GetGQLNodeResolverFileHeaderCommentStr generates an autogenerated tag.
+func GetGQLNodeResolverFileHeaderCommentStr() (string, error) {
+	return gQLNodeResolverFileHeaderCommentTemplate.Exec(nil)
 }
 
-// GetGQLNodeResolverFileHeaderCommentStr generates an autogenerated tag.
-func GetGQLNodeResolverFileHeaderCommentStr() string {
-	data := struct{}{}
-	template := "// Autogenerated node type - regenerate with splits-go-schema-" +
-		"codegen\n// Force autogen by deleting the @SignedSource line.\n"
-	return cg.ExecTemplate(template, "node_type_file_header_comment", data, nil)
+// GQLNodeResolverPackageData is the data of the
+// graphql/gql_node_resolver_package template.
+type GQLNodeResolverPackageData struct {
+	Package string
 }
 
+var gQLNodeResolverPackageTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_node_resolver_package",
+	Data: GQLNodeResolverPackageData{},
+	Text: "package {{.Package}}\n",
+})
+
 // GetGQLNodeResolverPackageStr generates the package string.
-func GetGQLNodeResolverPackageStr(packageName string) string {
-	data := struct {
-		Package string
-	}{
+func GetGQLNodeResolverPackageStr(packageName string) (string, error) {
+	data := GQLNodeResolverPackageData{
 		Package: packageName,
 	}
-	template := "package {{.Package}}\n"
-	return cg.ExecTemplate(template, "node_type_package_string", data, nil)
+	return gQLNodeResolverPackageTemplate.Exec(data)
 }
 
-// GetGQLNodeResolverImportStr generates the import block.
-func GetGQLNodeResolverImportStr(manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
-		ManualPart: manualPart,
-	}
-	template := "import (\n" +
+// GQLNodeResolverImportData is the data of the graphql/gql_node_resolver_import
+// template.
+type GQLNodeResolverImportData struct {
+	ManualPart string
+}
+
+var gQLNodeResolverImportTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_node_resolver_import",
+	Data: GQLNodeResolverImportData{},
+	Text: "import (\n" +
 		"\t\"bytes\"\n" +
 		"\t\"context\"\n" +
 		"\t\"splits-go-api/constants\"\n" +
@@ -86,62 +88,62 @@ func GetGQLNodeResolverImportStr(manualPart string) string {
 		"\tgraphql \"github.com/neelance/graphql-go\"\n" +
 		"\tdataloader \"gopkg.in/nicksrandall/dataloader.v2\"\n" +
 		"\n" +
-		cg.StartManual + "\n" +
+		cg.StartManualSection(cg.ManualImports) + "\n" +
 		"{{.ManualPart}}\n" +
 		cg.EndManual + "\n" +
-		")\n"
-	return cg.ExecTemplate(template, "node_type_import_string", data, nil)
+		")\n",
+})
+
+// GetGQLNodeResolverImportStr generates the import block.
+func GetGQLNodeResolverImportStr(manualPart string) (string, error) {
+	data := GQLNodeResolverImportData{
+		ManualPart: manualPart,
+	}
+	return gQLNodeResolverImportTemplate.Exec(data)
+}
+
+// GQLNodeResolverExtraFunctionsData is the data of the
+// graphql/gql_node_resolver_extra_functions template.
+type GQLNodeResolverExtraFunctionsData struct {
+	ManualPart string
 }
 
+var gQLNodeResolverExtraFunctionsTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_node_resolver_extra_functions",
+	Data: GQLNodeResolverExtraFunctionsData{},
+	Text: cg.StartManualSection(cg.ManualFunctions) + "\n" +
+		"{{.ManualPart}}\n" +
+		cg.EndManual + "\n",
+})
+
 // GetGQLNodeResolverExtraFunctionsStr adds a manual sections for user defined
 // functions.
-func GetGQLNodeResolverExtraFunctionsStr(manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
+func GetGQLNodeResolverExtraFunctionsStr(manualPart string) (string, error) {
+	data := GQLNodeResolverExtraFunctionsData{
 		ManualPart: manualPart,
 	}
-	template :=
-		cg.StartManual + "\n" +
-			"{{.ManualPart}}\n" +
-			cg.EndManual + "\n"
-	return cg.ExecTemplate(template, "node_type_extra_functions", data, nil)
+	return gQLNodeResolverExtraFunctionsTemplate.Exec(data)
 }
 
 // GetGQLNodeResolverGeneratedFunctionsTagStr writes a generated functions
 // tagline.
-func GetGQLNodeResolverGeneratedFunctionsTagStr() string {
-	return "// === GENERATED FUNCTIONS === \n"
+func GetGQLNodeResolverGeneratedFunctionsTagStr() (string, error) {
+	return "// === GENERATED FUNCTIONS === \n", nil
 }
 
-// GetGQLNodeResolverStr writes the resolver type.
-func GetGQLNodeResolverStr(n cg.GraphQLNode) string {
-	fields := []cg.GraphQLField{}
-	timeFields := []cg.GraphQLField{}
-	for _, f := range n.Fields {
-		if f.CodeName == "ID" {
-			continue
-		}
-		if f.CodeType == "graphql.Time" {
-			timeFields = append(timeFields, f)
-			continue
-		}
-		fields = append(fields, f)
-	}
-	data := struct {
-		Node       cg.GraphQLNode
-		Fields     []cg.GraphQLField
-		TimeFields []cg.GraphQLField
-		Name       string
-		Var        string
-	}{
-		Node:       n,
-		Fields:     fields,
-		TimeFields: timeFields,
-		Name:       n.CodeName,
-		Var:        strings.ToLower(string(n.CodeName[0])),
-	}
-	template := "// {{.Name}}Resolver for resolving {{.Name}} nodes.\n" +
+// GQLNodeResolverData is the data of the graphql/gql_node_resolver template.
+type GQLNodeResolverData struct {
+	Node       cg.GraphQLNode
+	Fields     []cg.GraphQLField
+	TimeFields []cg.GraphQLField
+	Name       string
+	Var        string
+}
+
+var gQLNodeResolverTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_node_resolver",
+	Data: GQLNodeResolverData{},
+	Text: "// {{.Name}}Resolver for resolving {{.Name}} nodes.\n" +
 		"type {{.Name}}Resolver struct {\n" +
 		"\tid string\n" +
 		"}\n" +
@@ -261,25 +263,46 @@ func GetGQLNodeResolverStr(n cg.GraphQLNode) string {
 		"\treturn &graphql.Time{Time: timeValue}, nil\n" +
 		"}\n" +
 		"\n" +
-		"{{end}}"
-	return cg.ExecTemplate(template, "node_resolver_type", data, nil)
-}
+		"{{end}}",
+})
 
-// GetGQLNodeEdgeResolverStr writes the resolvers for the edges.
-func GetGQLNodeEdgeResolverStr(n cg.GraphQLNode) string {
-	edges := n.Edges
-	data := struct {
-		Node  cg.GraphQLNode
-		Edges []cg.GraphQLEdge
-		Name  string
-		Var   string
-	}{
-		Node:  n,
-		Edges: edges,
-		Name:  n.CodeName,
-		Var:   strings.ToLower(string(n.CodeName[0])),
+// GetGQLNodeResolverStr writes the resolver type.
+func GetGQLNodeResolverStr(n cg.GraphQLNode) (string, error) {
+	fields := []cg.GraphQLField{}
+	timeFields := []cg.GraphQLField{}
+	for _, f := range n.Fields {
+		if f.CodeName == "ID" {
+			continue
+		}
+		if f.CodeType == "graphql.Time" {
+			timeFields = append(timeFields, f)
+			continue
+		}
+		fields = append(fields, f)
+	}
+	data := GQLNodeResolverData{
+		Node:       n,
+		Fields:     fields,
+		TimeFields: timeFields,
+		Name:       n.CodeName,
+		Var:        strings.ToLower(string(n.CodeName[0])),
 	}
-	template := "// =====================================================================" +
+	return gQLNodeResolverTemplate.Exec(data)
+}
+
+// GQLNodeEdgeResolverData is the data of the graphql/gql_node_edge_resolver
+// template.
+type GQLNodeEdgeResolverData struct {
+	Node  cg.GraphQLNode
+	Edges []cg.GraphQLEdge
+	Name  string
+	Var   string
+}
+
+var gQLNodeEdgeResolverTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/gql_node_edge_resolver",
+	Data: GQLNodeEdgeResolverData{},
+	Text: "// =====================================================================" +
 		"========\n" +
 		"// Edges\n" +
 		"// =====================================================================" +
@@ -305,7 +328,7 @@ func GetGQLNodeEdgeResolverStr(n cg.GraphQLNode) string {
 		"\t}\n" +
 		"\tdl := ctx.Value(constants.DataloaderKey).(*dataloader.Loader)\n" +
 		"\tthunk := dl.Load(ctx, muxField(\"{{.FromCodeName}}" +
-		"{{.ToCodeName}}\", id, " +
+		"{{.FieldCodeName}}\", id, " +
 		"orderBy.String()))\n" +
 		"\tpreIDList, err := thunk()\n" +
 		"\tif err != nil {\n" +
@@ -355,6 +378,17 @@ func GetGQLNodeEdgeResolverStr(n cg.GraphQLNode) string {
 		"\t\tto:     to,\n" +
 		"\t}, nil\n" +
 		"}\n" +
-		"{{end}}"
-	return cg.ExecTemplate(template, "node_edge_resolver_type", data, nil)
+		"{{end}}",
+})
+
+// GetGQLNodeEdgeResolverStr writes the resolvers for the edges.
+func GetGQLNodeEdgeResolverStr(n cg.GraphQLNode) (string, error) {
+	edges := n.Edges
+	data := GQLNodeEdgeResolverData{
+		Node:  n,
+		Edges: edges,
+		Name:  n.CodeName,
+		Var:   strings.ToLower(string(n.CodeName[0])),
+	}
+	return gQLNodeEdgeResolverTemplate.Exec(data)
 }
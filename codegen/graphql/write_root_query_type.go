@@ -1,125 +1,140 @@
 package graphql
 
-import (
-	"crypto/md5"
-	"encoding/hex"
-	"fmt"
-	"go/format"
-	cg "splits-go-schema-codegen/codegen"
-	"strings"
-)
+import cg "splits-go-schema-codegen/codegen"
 
 // WriteRootQueryType writes the graphql base root query.
 func WriteRootQueryType(
 	schemas []cg.Schema,
 	schema cg.GraphQLSchema,
-	manualParts []string,
+	manualParts []cg.ManualSection,
 	packageName string,
-) string {
-
-	getManualPart := initManualPart(manualParts)
+) (string, error) {
+	manual := cg.NewManualParts(manualParts)
 
 	// Use templates to generate the node
-	sections := []string{}
-	sections = append(sections, GetRootQueryTypeFileHeaderCommentStr())
-	sections = append(sections, GetRootQueryTypePackageStr(packageName))
-	sections = append(sections, GetRootQueryTypeImportStr(getManualPart()))
-	sections = append(sections, GetRootQueryTypeExtraFunctionsStr(getManualPart()))
-	sections = append(sections, GetRootQueryTypeGeneratedFunctionsTagStr())
-	sections = append(sections, GetRootQueryTypeStr(schema))
-	result := strings.Join(sections, "\n")
-	res, err := format.Source([]byte(result))
+	sections := cg.Sections{}
+	sections.Add(GetRootQueryTypeFileHeaderCommentStr())
+	sections.Add(GetRootQueryTypePackageStr(packageName))
+	sections.Add(GetRootQueryTypeImportStr(
+		manual.Get(cg.ManualImports)))
+	sections.Add(GetRootQueryTypeExtraFunctionsStr(
+		manual.Get(cg.ManualFunctions)))
+	sections.Add(GetRootQueryTypeGeneratedFunctionsTagStr())
+	sections.Add(GetRootQueryTypeStr(schema))
+	if err := manual.Err(); err != nil {
+		return "", err
+	}
+	result, err := sections.Join("\n")
 	if err != nil {
-		fmt.Println(result)
-		panic(err)
+		return "", err
 	}
+	res, err := cg.FormatSource(result)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
 
-	signatureRes := []byte(cg.ReplaceAllStringSubmatchFunc(
-		cg.ManualExtractor,
-		string(res),
-		func(groups []string) string {
-			return cg.StartManual + groups[2] + cg.EndManual
-		},
-	))
-
-	// Generate the MD5 signature
-	sum := md5.Sum([]byte(signatureRes))
-	signature := hex.EncodeToString([]byte(sum[:]))
+var rootQueryTypeFileHeaderCommentTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/root_query_type_file_header_comment",
+	Text: "// Autogenerated node type - regenerate with splits-go-schema-" +
+		"codegen\n// Force regeneration over hand edits with " +
+		"generate --force.\n",
+})
 
-	// Add the signature to the top of the file
-	return "// @SignedSource (" + signature + ")\n" + string(res)
+// GetRootQueryTypeFileHeaderCommentStr generates an autogenerated tag.
+func GetRootQueryTypeFileHeaderCommentStr() (string, error) {
+	return rootQueryTypeFileHeaderCommentTemplate.Exec(nil)
 }
 
-// GetRootQueryTypeFileHeaderCommentStr generates an autogenerated tag.
-func GetRootQueryTypeFileHeaderCommentStr() string {
-	data := struct{}{}
-	template := "// Autogenerated node type - regenerate with splits-go-schema-" +
-		"codegen\n// Force autogen by deleting the @SignedSource line.\n"
-	return cg.ExecTemplate(template, "node_type_file_header_comment", data, nil)
+// RootQueryTypePackageData is the data of the graphql/root_query_type_package
+// template.
+type RootQueryTypePackageData struct {
+	Package string
 }
 
+var rootQueryTypePackageTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/root_query_type_package",
+	Data: RootQueryTypePackageData{},
+	Text: "package {{.Package}}\n",
+})
+
 // GetRootQueryTypePackageStr generates the package string.
-func GetRootQueryTypePackageStr(packageName string) string {
-	data := struct {
-		Package string
-	}{
+func GetRootQueryTypePackageStr(packageName string) (string, error) {
+	data := RootQueryTypePackageData{
 		Package: packageName,
 	}
-	template := "package {{.Package}}\n"
-	return cg.ExecTemplate(template, "node_type_package_string", data, nil)
+	return rootQueryTypePackageTemplate.Exec(data)
 }
 
-// GetRootQueryTypeImportStr generates the import block.
-func GetRootQueryTypeImportStr(manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
-		ManualPart: manualPart,
-	}
-	template := "import (\n" +
+// RootQueryTypeImportData is the data of the graphql/root_query_type_import
+// template.
+type RootQueryTypeImportData struct {
+	ManualPart string
+}
+
+var rootQueryTypeImportTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/root_query_type_import",
+	Data: RootQueryTypeImportData{},
+	Text: "import (\n" +
 		"\t\"context\"\n" +
 		"\t\"splits-go-api/constants\"\n" +
 		"\n" +
 		"\tdataloader \"gopkg.in/nicksrandall/dataloader.v2\"\n" +
 		"\n" +
-		cg.StartManual + "\n" +
+		cg.StartManualSection(cg.ManualImports) + "\n" +
 		"{{.ManualPart}}\n" +
 		cg.EndManual + "\n" +
-		")\n"
-	return cg.ExecTemplate(template, "node_type_import_string", data, nil)
+		")\n",
+})
+
+// GetRootQueryTypeImportStr generates the import block.
+func GetRootQueryTypeImportStr(manualPart string) (string, error) {
+	data := RootQueryTypeImportData{
+		ManualPart: manualPart,
+	}
+	return rootQueryTypeImportTemplate.Exec(data)
+}
+
+// RootQueryTypeExtraFunctionsData is the data of the
+// graphql/root_query_type_extra_functions template.
+type RootQueryTypeExtraFunctionsData struct {
+	ManualPart string
 }
 
+var rootQueryTypeExtraFunctionsTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/root_query_type_extra_functions",
+	Data: RootQueryTypeExtraFunctionsData{},
+	Text: cg.StartManualSection(cg.ManualFunctions) + "\n" +
+		"{{.ManualPart}}\n" +
+		cg.EndManual + "\n",
+})
+
 // GetRootQueryTypeExtraFunctionsStr adds a manual sections for user defined
 // functions.
-func GetRootQueryTypeExtraFunctionsStr(manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
+func GetRootQueryTypeExtraFunctionsStr(manualPart string) (string, error) {
+	data := RootQueryTypeExtraFunctionsData{
 		ManualPart: manualPart,
 	}
-	template :=
-		cg.StartManual + "\n" +
-			"{{.ManualPart}}\n" +
-			cg.EndManual + "\n"
-	return cg.ExecTemplate(template, "node_type_extra_functions", data, nil)
+	return rootQueryTypeExtraFunctionsTemplate.Exec(data)
 }
 
 // GetRootQueryTypeGeneratedFunctionsTagStr writes a generated functions
 // tagline.
-func GetRootQueryTypeGeneratedFunctionsTagStr() string {
-	return "// === GENERATED FUNCTIONS === \n"
+func GetRootQueryTypeGeneratedFunctionsTagStr() (string, error) {
+	return "// === GENERATED FUNCTIONS === \n", nil
 }
 
-// GetRootQueryTypeStr writes the node interface and resolver types.
-func GetRootQueryTypeStr(s cg.GraphQLSchema) string {
-	data := struct {
-		Nodes []cg.GraphQLNode
-		Edges []cg.GraphQLEdge
-	}{
-		Nodes: s.Nodes,
-		Edges: s.Edges,
-	}
-	template := "// Resolver root for graphql queries.\n" +
+// RootQueryTypeData is the data of the graphql/root_query_type template.
+type RootQueryTypeData struct {
+	Nodes []cg.GraphQLNode
+	Edges []cg.GraphQLEdge
+}
+
+var rootQueryTypeTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/root_query_type",
+	Data: RootQueryTypeData{},
+	Text: "// Resolver root for graphql queries.\n" +
 		"type Resolver struct {}\n" +
 		"\n" +
 		"{{range .Nodes}}" +
@@ -144,6 +159,14 @@ func GetRootQueryTypeStr(s cg.GraphQLSchema) string {
 		"\treturn &{{.CodeName}}Resolver{verifiedID.(string)}, nil\n" +
 		"}\n" +
 		"\n" +
-		"{{end}}"
-	return cg.ExecTemplate(template, "root_query_type", data, nil)
+		"{{end}}",
+})
+
+// GetRootQueryTypeStr writes the node interface and resolver types.
+func GetRootQueryTypeStr(s cg.GraphQLSchema) (string, error) {
+	data := RootQueryTypeData{
+		Nodes: s.Nodes,
+		Edges: s.Edges,
+	}
+	return rootQueryTypeTemplate.Exec(data)
 }
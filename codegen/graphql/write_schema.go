@@ -5,10 +5,6 @@
 package graphql
 
 import (
-	"crypto/md5"
-	"encoding/hex"
-	"fmt"
-	"go/format"
 	cg "splits-go-schema-codegen/codegen"
 	"strings"
 	t "text/template"
@@ -18,136 +14,149 @@ import (
 func WriteGraphQLSchema(
 	schemas []cg.Schema,
 	schema cg.GraphQLSchema,
-	manualParts []string,
+	manualParts []cg.ManualSection,
 	packageName string,
-) string {
-
-	getManualPart := initManualPart(manualParts)
+) (string, error) {
+	manual := cg.NewManualParts(manualParts)
 
 	// Use templates to generate the node
-	sections := []string{}
-	sections = append(sections, GetSchemaFileHeaderCommentStr())
-	sections = append(sections, GetSchemaPackageStr(packageName))
-	sections = append(sections, GetSchemaImportStr(getManualPart()))
-	sections = append(sections, GetSchemaExtraFunctionsStr(getManualPart()))
-	sections = append(sections, GetSchemaGeneratedFunctionsTagStr())
-	sections = append(sections, GetSchemaParseSchemaStr())
-	sections = append(sections, GetSchemaStringStr(schema))
-	result := strings.Join(sections, "\n")
-	res, err := format.Source([]byte(result))
+	sections := cg.Sections{}
+	sections.Add(GetSchemaFileHeaderCommentStr())
+	sections.Add(GetSchemaPackageStr(packageName))
+	sections.Add(GetSchemaImportStr(
+		manual.Get(cg.ManualImports)))
+	sections.Add(GetSchemaExtraFunctionsStr(
+		manual.Get(cg.ManualFunctions)))
+	sections.Add(GetSchemaGeneratedFunctionsTagStr())
+	sections.Add(GetSchemaParseSchemaStr())
+	sections.Add(GetSchemaStringStr(schema))
+	if err := manual.Err(); err != nil {
+		return "", err
+	}
+	result, err := sections.Join("\n")
 	if err != nil {
-		fmt.Println(result)
-		panic(err)
+		return "", err
 	}
+	res, err := cg.FormatSource(result)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
 
-	signatureRes := []byte(cg.ReplaceAllStringSubmatchFunc(
-		cg.ManualExtractor,
-		string(res),
-		func(groups []string) string {
-			return cg.StartManual + groups[2] + cg.EndManual
-		},
-	))
-
-	// Generate the MD5 signature
-	sum := md5.Sum([]byte(signatureRes))
-	signature := hex.EncodeToString([]byte(sum[:]))
+var schemaFileHeaderCommentTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/schema_file_header_comment",
+	Text: "// Autogenerated schema - regenerate with splits-go-schema-" +
+		"codegen\n// Force regeneration over hand edits with " +
+		"generate --force.\n",
+})
 
-	// Add the signature to the top of the file
-	return "// @SignedSource (" + signature + ")\n" + string(res)
+// GetSchemaFileHeaderCommentStr generates an autogenerated tag.
+func GetSchemaFileHeaderCommentStr() (string, error) {
+	return schemaFileHeaderCommentTemplate.Exec(nil)
 }
 
-// GetSchemaFileHeaderCommentStr generates an autogenerated tag.
-func GetSchemaFileHeaderCommentStr() string {
-	data := struct{}{}
-	template := "// Autogenerated schema - regenerate with splits-go-schema-" +
-		"codegen\n// Force autogen by deleting the @SignedSource line.\n"
-	return cg.ExecTemplate(template, "schema_file_header_comment", data, nil)
+// SchemaPackageData is the data of the graphql/schema_package template.
+type SchemaPackageData struct {
+	Package string
 }
 
+var schemaPackageTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/schema_package",
+	Data: SchemaPackageData{},
+	Text: "package {{.Package}}\n",
+})
+
 // GetSchemaPackageStr generates the package string.
-func GetSchemaPackageStr(packageName string) string {
-	data := struct {
-		Package string
-	}{
+func GetSchemaPackageStr(packageName string) (string, error) {
+	data := SchemaPackageData{
 		Package: packageName,
 	}
-	template := "package {{.Package}}\n"
-	return cg.ExecTemplate(template, "schema_package_string", data, nil)
+	return schemaPackageTemplate.Exec(data)
 }
 
-// GetSchemaImportStr generates the import block.
-func GetSchemaImportStr(manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
-		ManualPart: manualPart,
-	}
-	template := "import (\n" +
+// SchemaImportData is the data of the graphql/schema_import template.
+type SchemaImportData struct {
+	ManualPart string
+}
+
+var schemaImportTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/schema_import",
+	Data: SchemaImportData{},
+	Text: "import (\n" +
 		"\t\"splits-go-api/api/graphql/resolvers\"\n" +
 		"\n" +
 		"\tgraphql \"github.com/neelance/graphql-go\"\n" +
 		"\n" +
-		cg.StartManual + "\n" +
+		cg.StartManualSection(cg.ManualImports) + "\n" +
 		"{{.ManualPart}}\n" +
 		cg.EndManual + "\n" +
-		")\n"
-	return cg.ExecTemplate(template, "schema_import_string", data, nil)
+		")\n",
+})
+
+// GetSchemaImportStr generates the import block.
+func GetSchemaImportStr(manualPart string) (string, error) {
+	data := SchemaImportData{
+		ManualPart: manualPart,
+	}
+	return schemaImportTemplate.Exec(data)
 }
 
+// SchemaExtraFunctionsData is the data of the graphql/schema_extra_functions
+// template.
+type SchemaExtraFunctionsData struct {
+	ManualPart string
+}
+
+var schemaExtraFunctionsTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/schema_extra_functions",
+	Data: SchemaExtraFunctionsData{},
+	Text: cg.StartManualSection(cg.ManualFunctions) + "\n" +
+		"{{.ManualPart}}\n" +
+		cg.EndManual + "\n",
+})
+
 // GetSchemaExtraFunctionsStr adds a manual sections for user defined functions.
-func GetSchemaExtraFunctionsStr(manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
+func GetSchemaExtraFunctionsStr(manualPart string) (string, error) {
+	data := SchemaExtraFunctionsData{
 		ManualPart: manualPart,
 	}
-	template :=
-		cg.StartManual + "\n" +
-			"{{.ManualPart}}\n" +
-			cg.EndManual + "\n"
-	return cg.ExecTemplate(template, "schema_extra_functions", data, nil)
+	return schemaExtraFunctionsTemplate.Exec(data)
 }
 
 // GetSchemaGeneratedFunctionsTagStr writes a generated functions tagline.
-func GetSchemaGeneratedFunctionsTagStr() string {
-	return "// === GENERATED FUNCTIONS === \n"
+func GetSchemaGeneratedFunctionsTagStr() (string, error) {
+	return "// === GENERATED FUNCTIONS === \n", nil
 }
 
-// GetSchemaParseSchemaStr writes the parse schema function.
-func GetSchemaParseSchemaStr() string {
-	template := "var schema *graphql.Schema\n" +
+var schemaParseSchemaTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/schema_parse_schema",
+	Text: "var schema *graphql.Schema\n" +
 		"\n" +
 		"// ParseSchema at startup to check for schema issues.\n" +
 		"func ParseSchema() {\n" +
 		"\tschema = graphql.MustParseSchema(Schema, &resolvers.Resolver{})\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "schema_parse_schema", nil, nil)
-}
+		"}\n",
+})
 
-// GetSchemaStringStr returns the string form of the graphql schema.
-func GetSchemaStringStr(s cg.GraphQLSchema) string {
+// GetSchemaParseSchemaStr writes the parse schema function.
+func GetSchemaParseSchemaStr() (string, error) {
+	return schemaParseSchemaTemplate.Exec(nil)
+}
 
-	edges := []cg.GraphQLEdge{}
-	edgeMap := map[string]bool{}
-	for _, e := range s.Edges {
-		name := e.From + e.TotalName + e.To
-		if _, ok := edgeMap[name]; !ok {
-			edges = append(edges, e)
-			edgeMap[name] = true
-		}
-	}
+// SchemaStringData is the data of the graphql/schema_string template.
+type SchemaStringData struct {
+	Nodes []cg.GraphQLNode
+	Edges []cg.GraphQLEdge
+}
 
-	data := struct {
-		Nodes []cg.GraphQLNode
-		Edges []cg.GraphQLEdge
-	}{
-		Nodes: s.Nodes,
-		Edges: edges,
-	}
-	funcMap := t.FuncMap{
+var schemaStringTemplate = cg.NewTemplate(cg.Template{
+	Name: "graphql/schema_string",
+	Data: SchemaStringData{},
+	Funcs: t.FuncMap{
 		"ToLower": strings.ToLower,
-	}
-	template := "// Schema of the graphql api.\n" +
+	},
+	Text: "// Schema of the graphql api.\n" +
 		"var Schema = `\n" +
 		"\n" +
 		"scalar Time\n" +
@@ -218,6 +227,25 @@ func GetSchemaStringStr(s cg.GraphQLSchema) string {
 		"\thasNextPage: Boolean!\n" +
 		"\thasPreviousPage: Boolean!\n" +
 		"}\n" +
-		"` + resolvers.MutationSchema\n"
-	return cg.ExecTemplate(template, "schema_string", data, funcMap)
+		"` + resolvers.MutationSchema\n",
+})
+
+// GetSchemaStringStr returns the string form of the graphql schema.
+func GetSchemaStringStr(s cg.GraphQLSchema) (string, error) {
+
+	edges := []cg.GraphQLEdge{}
+	edgeMap := map[string]bool{}
+	for _, e := range s.Edges {
+		name := e.From + "To" + e.To
+		if _, ok := edgeMap[name]; !ok {
+			edges = append(edges, e)
+			edgeMap[name] = true
+		}
+	}
+
+	data := SchemaStringData{
+		Nodes: s.Nodes,
+		Edges: edges,
+	}
+	return schemaStringTemplate.Exec(data)
 }
@@ -0,0 +1,266 @@
+// Hashing what generated files are rendered from, so a generation run can
+// skip the files whose inputs did not change since they were generated.
+
+package codegen
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"splits-go-api/privacy"
+	"strings"
+)
+
+// schemaDefinition is the normalized definition of a schema. Schemas are
+// referred to by name and privacy policies by their name, so the definition
+// only changes when something that ends up in the generated code does.
+type schemaDefinition struct {
+	Name            string            `json:"name"`
+	Fields          []fieldDefinition `json:"fields"`
+	Edges           []edgeDefinition  `json:"edges"`
+	DeletionPrivacy string            `json:"deletion_privacy"`
+	GraphQLNode     *GraphQLNode      `json:"graphql_node"`
+}
+
+type fieldDefinition struct {
+	Name          string        `json:"name"`
+	CodeName      string        `json:"code_name"`
+	Type          FieldType     `json:"type"`
+	DefaultValue  string        `json:"default_value"`
+	ExampleValue  string        `json:"example_value"`
+	Unique        bool          `json:"unique"`
+	Indexed       bool          `json:"indexed"`
+	CanOrderBy    bool          `json:"can_order_by"`
+	Privacy       string        `json:"privacy"`
+	WritePrivacy  string        `json:"write_privacy"`
+	RWritePrivacy string        `json:"r_write_privacy"`
+	GQLField      *GraphQLField `json:"graphql_field"`
+}
+
+type edgeDefinition struct {
+	Name            string            `json:"name"`
+	CodeName        string            `json:"code_name"`
+	From            string            `json:"from"`
+	To              string            `json:"to"`
+	ForwardsName    string            `json:"forwards_name"`
+	BackwardsName   string            `json:"backwards_name"`
+	Fields          []fieldDefinition `json:"fields"`
+	Privacy         string            `json:"privacy"`
+	ReversePrivacy  string            `json:"reverse_privacy"`
+	WritePrivacy    string            `json:"write_privacy"`
+	DeletionPrivacy string            `json:"deletion_privacy"`
+	GQLEdge         *GraphQLEdge      `json:"graphql_edge"`
+}
+
+// SchemaHash returns the hash of the normalized definition of the schema:
+// its fields, edges, privacy policy names and graphql metadata.
+func SchemaHash(s Schema) string {
+	definition := schemaDefinition{
+		Name:            s.GetName(),
+		Fields:          []fieldDefinition{},
+		Edges:           []edgeDefinition{},
+		DeletionPrivacy: policyName(s.GetDeletionPrivacy()),
+		GraphQLNode:     s.GetGraphQLNode(),
+	}
+	for _, f := range s.GetFields() {
+		definition.Fields = append(definition.Fields, fieldDefinition{
+			Name:         f.Name,
+			CodeName:     f.CodeName,
+			Type:         f.Type,
+			DefaultValue: f.DefaultValue,
+			ExampleValue: f.ExampleValue,
+			Unique:       f.Unique,
+			Indexed:      f.Indexed,
+			CanOrderBy:   f.CanOrderBy,
+			Privacy:      policyName(f.Privacy),
+			WritePrivacy: policyName(f.WritePrivacy),
+			GQLField:     f.GQLField,
+		})
+	}
+	for _, e := range s.GetEdges() {
+		edge := edgeDefinition{
+			Name:            e.Name,
+			CodeName:        e.CodeName,
+			From:            schemaName(e.FromNode),
+			To:              schemaName(e.ToNode),
+			ForwardsName:    e.ForwardsName,
+			BackwardsName:   e.BackwardsName,
+			Fields:          []fieldDefinition{},
+			Privacy:         policyName(e.Privacy),
+			ReversePrivacy:  policyName(e.ReversePrivacy),
+			WritePrivacy:    policyName(e.WritePrivacy),
+			DeletionPrivacy: policyName(e.DeletionPrivacy),
+			GQLEdge:         e.GQLEdge,
+		}
+		for _, f := range e.Fields {
+			edge.Fields = append(edge.Fields, fieldDefinition{
+				Name:          f.Name,
+				CodeName:      f.CodeName,
+				Type:          f.Type,
+				DefaultValue:  f.DefaultValue,
+				ExampleValue:  f.ExampleValue,
+				Unique:        f.Unique,
+				Indexed:       f.Indexed,
+				Privacy:       policyName(f.Privacy),
+				WritePrivacy:  policyName(f.WritePrivacy),
+				RWritePrivacy: policyName(f.RWritePrivacy),
+				GQLField:      f.GQLField,
+			})
+		}
+		definition.Edges = append(definition.Edges, edge)
+	}
+	content, err := json.Marshal(definition)
+	if err != nil {
+		// The definition only holds strings, bools and plain structs
+		panic(err)
+	}
+	return HashContent(string(content))
+}
+
+func policyName(p privacy.Policy) string {
+	if p == nil {
+		return ""
+	}
+	return p.GetName()
+}
+
+func schemaName(s Schema) string {
+	if s == nil {
+		return ""
+	}
+	return s.GetName()
+}
+
+// Inputs hashes what the files of a run are rendered from: the generator
+// version, the output settings of the config, the template overrides and the
+// schema definitions.
+type Inputs struct {
+	base    string              // Generator version, config and templates
+	schemas map[string]string   // Definition hash of every schema
+	related map[string][]string // Schemas connected to a schema by an edge
+}
+
+// NewInputs hashes the inputs of a run over the schemas.
+func NewInputs(config Config, schemas []Schema) Inputs {
+	// Only the output settings end up in the generated code
+	config.Root = ""
+	config.Layers = nil
+	config.StateDir = ""
+	config.SchemaFiles = nil
+	config.Templates = ""
+	config.Workers = 0
+	content, _ := json.Marshal(config)
+
+	inputs := Inputs{
+		base: Version + "\n" + HashContent(string(content)) + "\n" +
+			TemplateSetHash(),
+		schemas: map[string]string{},
+		related: map[string][]string{},
+	}
+	for _, s := range schemas {
+		inputs.schemas[s.GetName()] = SchemaHash(s)
+		for _, e := range s.GetEdges() {
+			from, to := s.GetName(), schemaName(e.ToNode)
+			inputs.related[from] = append(inputs.related[from], to)
+			inputs.related[to] = append(inputs.related[to], from)
+		}
+	}
+	return inputs
+}
+
+// Hash returns the hash of the inputs of a file. Files of a schema are
+// rendered from the schema and the schemas its edges connect it to, through
+// the edge pointers. Files generated from every schema are rendered from all
+// of them.
+func (in Inputs) Hash(source Source) string {
+	names := []string{}
+	if source.Schema == "" {
+		for name := range in.schemas {
+			names = append(names, name)
+		}
+	} else {
+		names = append(names, source.Schema)
+		names = append(names, in.related[source.Schema]...)
+	}
+	sort.Strings(names)
+
+	lines := []string{in.base}
+	for i, name := range names {
+		if i > 0 && names[i-1] == name {
+			continue
+		}
+		lines = append(lines, name+" "+in.schemas[name])
+	}
+	return HashContent(strings.Join(lines, "\n"))
+}
+
+// Changes are what a run needs to render in a layer, compared to the manifest.
+type Changes struct {
+	All       bool     // Whether every file of the layer is rendered again
+	Schemas   []string // Schemas whose files are rendered again
+	Unchanged []File   // Files of the other schemas, as they are on disk
+}
+
+// Changes compares the files of the layer in the manifest against the inputs
+// of the run. The files of a schema are only rendered again if the inputs of
+// any of them changed, or any of them was removed or edited outside of its
+// manual sections. A schema without files in the layer is rendered again
+// unless the files generated from every schema are unchanged, which means it
+// already had no files when they were rendered.
+func (m Manifest) Changes(
+	root string,
+	layer string,
+	schemas []Schema,
+	inputs Inputs,
+) (Changes, error) {
+	changes := Changes{}
+	owned := map[string]int{}
+	unchanged := map[string][]File{}
+	changed := map[string]bool{}
+	for _, e := range m.Files {
+		if e.Layer != layer {
+			continue
+		}
+		owned[e.Schema]++
+		source := Source{Schema: e.Schema, Edge: e.Edge}
+		path := filepath.Join(root, e.Path)
+		content, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return changes, err
+		}
+		if err != nil || e.Inputs != inputs.Hash(source) ||
+			!e.Matches(string(content)) {
+			changed[e.Schema] = true
+			continue
+		}
+		unchanged[e.Schema] = append(unchanged[e.Schema], File{
+			Path:    path,
+			Layer:   layer,
+			Source:  source,
+			Content: string(content),
+			Inputs:  e.Inputs,
+		})
+	}
+
+	// Files generated from every schema are only recorded with their inputs
+	// when every schema was rendered
+	shared := owned[""] > 0 && !changed[""]
+	if changed[""] {
+		changes.All = true
+		return changes, nil
+	}
+	for _, s := range schemas {
+		name := s.GetName()
+		if changed[name] || owned[name] == 0 && !shared {
+			changes.Schemas = append(changes.Schemas, name)
+			continue
+		}
+		changes.Unchanged = append(changes.Unchanged, unchanged[name]...)
+	}
+	if shared {
+		changes.Unchanged = append(changes.Unchanged, unchanged[""]...)
+	}
+	return changes, nil
+}
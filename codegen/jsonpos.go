@@ -0,0 +1,151 @@
+// Parsing json while keeping the position of every value, so errors in schema
+// files can point at the line they come from.
+
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonValue is a parsed json value along with its offset in the input. The
+// value is a string, json.Number, bool, nil, []*jsonValue or *jsonObject.
+type jsonValue struct {
+	offset int64
+	value  interface{}
+	plain  string // Text of a yaml plain scalar that is a number or a bool
+}
+
+// jsonObject is a parsed json object that keeps the order of its keys.
+type jsonObject struct {
+	keys       []string
+	values     map[string]*jsonValue
+	keyOffsets map[string]int64
+}
+
+// jsonError is an error at an offset of the input.
+type jsonError struct {
+	offset int64
+	msg    string
+}
+
+func (e jsonError) Error() string {
+	return e.msg
+}
+
+// parseJSON parses the content into a tree of positioned values.
+func parseJSON(content []byte) (*jsonValue, error) {
+	p := jsonParser{
+		content: content,
+		decoder: json.NewDecoder(bytes.NewReader(content)),
+	}
+	p.decoder.UseNumber()
+	value, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.decoder.Token(); err != io.EOF {
+		return nil, jsonError{p.start(), "unexpected content after the " +
+			"top-level value"}
+	}
+	return value, nil
+}
+
+type jsonParser struct {
+	content []byte
+	decoder *json.Decoder
+}
+
+// start returns the offset of the next token, skipping the separators.
+func (p *jsonParser) start() int64 {
+	offset := p.decoder.InputOffset()
+	for offset < int64(len(p.content)) {
+		switch p.content[offset] {
+		case ' ', '\t', '\r', '\n', ':', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+func (p *jsonParser) token() (int64, json.Token, error) {
+	offset := p.start()
+	token, err := p.decoder.Token()
+	if err != nil {
+		switch e := err.(type) {
+		case *json.SyntaxError:
+			// The offset is past the byte that caused the error
+			return offset, nil, jsonError{e.Offset - 1, e.Error()}
+		}
+		if err == io.EOF {
+			return offset, nil, jsonError{offset, "unexpected end of file"}
+		}
+		return offset, nil, jsonError{offset, err.Error()}
+	}
+	return offset, token, nil
+}
+
+func (p *jsonParser) parse() (*jsonValue, error) {
+	offset, token, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		object := &jsonObject{
+			values:     map[string]*jsonValue{},
+			keyOffsets: map[string]int64{},
+		}
+		for p.decoder.More() {
+			keyOffset, key, err := p.token()
+			if err != nil {
+				return nil, err
+			}
+			name := key.(string)
+			if _, ok := object.values[name]; ok {
+				return nil, jsonError{keyOffset, fmt.Sprintf("duplicate key %q",
+					name)}
+			}
+			value, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			object.keys = append(object.keys, name)
+			object.values[name] = value
+			object.keyOffsets[name] = keyOffset
+		}
+		if _, _, err := p.token(); err != nil {
+			return nil, err
+		}
+		return &jsonValue{offset: offset, value: object}, nil
+	case json.Delim('['):
+		array := []*jsonValue{}
+		for p.decoder.More() {
+			value, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		if _, _, err := p.token(); err != nil {
+			return nil, err
+		}
+		return &jsonValue{offset: offset, value: array}, nil
+	}
+	return &jsonValue{offset: offset, value: token}, nil
+}
+
+// position returns the line and column of an offset, both starting at 1.
+func position(content []byte, offset int64) (int, int) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
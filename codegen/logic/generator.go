@@ -0,0 +1,79 @@
+// The built in generator of the logic layer. It is registered when the package
+// is imported.
+
+package logic
+
+import (
+	"path/filepath"
+	cg "splits-go-schema-codegen/codegen"
+	"strings"
+)
+
+func init() {
+	cg.RegisterGenerator(Generator{})
+}
+
+// Generator is the built in generator of the logic layer.
+type Generator struct{}
+
+// Name returns the name of the logic layer.
+func (Generator) Name() string {
+	return cg.LayerLogic
+}
+
+// Dirs returns the logic package.
+func (Generator) Dirs(config cg.Config) []string {
+	return []string{config.LogicDir()}
+}
+
+// Validate checks the logic files on disk and returns their manual sections.
+func (g Generator) Validate(
+	config cg.Config,
+	schemas []cg.Schema,
+	owned map[string]cg.ManifestEntry,
+	mergeFlag bool,
+	forceFlag bool,
+) (map[string][]cg.ManualSection, error) {
+	return cg.ValidateOwnedFiles(g.Dirs(config), owned, mergeFlag, forceFlag)
+}
+
+// Render renders the selected files of the logic layer in memory, carrying
+// over the manual sections keyed by path.
+func (Generator) Render(
+	config cg.Config,
+	schemas []cg.Schema,
+	filter cg.Filter,
+	manualParts map[string][]cg.ManualSection,
+) ([]cg.File, error) {
+	dir := config.LogicDir()
+	packageName := config.Logic.Package
+	r := cg.Renderer{Layer: cg.LayerLogic, Workers: config.Workers}
+
+	// Generate the node and edge definition code
+	for _, s := range schemas {
+		s := s
+		if filter.SelectsSchema(s.GetName()) {
+			filePath := filepath.Join(dir, strings.ToLower(s.GetName())+".go")
+			manualPart := manualParts[filePath]
+			r.Render(filePath, cg.Source{Schema: s.GetName()},
+				func() (string, error) {
+					return WriteSchemaLogicNode(s, manualPart, packageName)
+				})
+		}
+
+		// Generate edge definitions
+		for _, e := range s.GetEdges() {
+			e := e
+			if !filter.SelectsEdge(s.GetName(), e.Name, e.CodeName) {
+				continue
+			}
+			edgeFilePath := filepath.Join(dir, strings.ToLower(e.Name)+".go")
+			manualPart := manualParts[edgeFilePath]
+			r.Render(edgeFilePath, cg.Source{Schema: s.GetName(), Edge: e.Name},
+				func() (string, error) {
+					return WriteSchemaLogicEdge(s, e, manualPart, packageName)
+				})
+		}
+	}
+	return r.Result()
+}
@@ -5,181 +5,172 @@
 package logic
 
 import (
-	"crypto/md5"
-	"encoding/hex"
-	"fmt"
-	"go/format"
 	"splits-go-api/privacy"
 	cg "splits-go-schema-codegen/codegen"
 	"strings"
 )
 
-func initManualPart(manualParts []string) func() string {
-	index := 0
-	return func() string {
-		if manualParts == nil {
-			return ""
-		}
-		if index >= len(manualParts) {
-			return ""
-		}
-		index++
-		return strings.Replace(manualParts[index-1], "%", "%%", -1)
-	}
-}
-
 // WriteSchemaLogicNode writes the logic for a node.
 func WriteSchemaLogicNode(
 	s cg.Schema,
-	manualParts []string,
+	manualParts []cg.ManualSection,
 	packageName string,
-) string {
-
-	getManualPart := initManualPart(manualParts)
+) (string, error) {
+	manual := cg.NewManualParts(manualParts)
 
 	// Use templates to generate the node
-	sections := make([]string, 0, 11)
-	sections = append(sections, GetFileHeaderCommentStr(s))
-	sections = append(sections, GetPackageStr(s, packageName))
-	sections = append(sections, GetNodeImportStr(s, getManualPart()))
-	sections = append(sections, GetExtraFunctionsStr(s, getManualPart()))
-	sections = append(sections, GetGeneratedFunctionsTagStr())
-	sections = append(sections, GetNodeAuthMap(s))
-	sections = append(sections, GetNodeFieldQueryStr(s))
-	sections = append(sections, GetNodeGetByIDStr(s))
-	sections = append(sections, GetNodeGetByIDBatchStr(s))
-	sections = append(sections, GetNodeConnectedNodesStr(s))
-	sections = append(sections, GetNodeWriteFieldQueryStr(s))
-	sections = append(sections, GetUpdateNodeGetByIDStr(s))
-	sections = append(sections, GetDeleteNodeByIDStr(s))
-	result := strings.Join(sections, "\n")
-	res, err := format.Source([]byte(result))
+	sections := cg.Sections{}
+	sections.Add(GetFileHeaderCommentStr(s))
+	sections.Add(GetPackageStr(s, packageName))
+	sections.Add(GetNodeImportStr(
+		s, manual.Get(cg.ManualImports)))
+	sections.Add(GetExtraFunctionsStr(
+		s, manual.Get(cg.ManualFunctions)))
+	sections.Add(GetGeneratedFunctionsTagStr())
+	sections.Add(GetNodeAuthMap(s))
+	sections.Add(GetNodeFieldQueryStr(s))
+	sections.Add(GetNodeGetByIDStr(s))
+	sections.Add(GetNodeGetByIDBatchStr(s))
+	sections.Add(GetNodeConnectedNodesStr(s))
+	sections.Add(GetNodeWriteFieldQueryStr(s))
+	sections.Add(GetUpdateNodeGetByIDStr(s))
+	sections.Add(GetDeleteNodeByIDStr(s))
+	if err := manual.Err(); err != nil {
+		return "", err
+	}
+	result, err := sections.Join("\n")
 	if err != nil {
-		fmt.Println(result)
-		panic(err)
+		return "", err
 	}
-
-	signatureRes := []byte(cg.ReplaceAllStringSubmatchFunc(
-		cg.ManualExtractor,
-		string(res),
-		func(groups []string) string {
-			return cg.StartManual + groups[2] + cg.EndManual
-		},
-	))
-
-	// Generate the MD5 signature
-	sum := md5.Sum([]byte(signatureRes))
-	signature := hex.EncodeToString([]byte(sum[:]))
-
-	// Add the signature to the top of the file
-	return "// @SignedSource (" + signature + ")\n" + string(res)
+	res, err := cg.FormatSource(result)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
 }
 
 // WriteSchemaLogicEdge writes the logic for an edge.
 func WriteSchemaLogicEdge(
 	s cg.Schema,
 	e cg.EdgeStruct,
-	manualParts []string,
+	manualParts []cg.ManualSection,
 	packageName string,
-) string {
-	getManualPart := initManualPart(manualParts)
+) (string, error) {
+	manual := cg.NewManualParts(manualParts)
 
 	// Use templates to generate the node
-	sections := make([]string, 0, 11)
-	sections = append(sections, GetFileHeaderCommentStr(s))
-	sections = append(sections, GetPackageStr(s, packageName))
-	sections = append(sections, GetEdgeImportStr(s, getManualPart()))
-	sections = append(sections, GetExtraFunctionsStr(s, getManualPart()))
-	sections = append(sections, GetGeneratedFunctionsTagStr())
-	sections = append(sections, GetEdgeAuthMap(s, e))
-	sections = append(sections, GetEdgeFieldQueryStr(s, e))
-	sections = append(sections, GetEdgeGetByIDStr(s, e))
-	sections = append(sections, GetEdgeGetByIDBatcherStr(s, e))
-	sections = append(sections, GetEdgeGetByIDsStr(s, e))
-	sections = append(sections, GetEdgeGetByIDsBatcherStr(s, e))
-	sections = append(sections, GetEdgeWriteFieldQueryStr(s, e))
-	sections = append(sections, GetUpdateEdgeGetByIDStr(s, e))
-	sections = append(sections, GetUpdateEdgeGetByIDsStr(s, e))
-	sections = append(sections, GetDeleteEdgeByIDStr(s, e))
-	sections = append(sections, GetDeleteEdgeByIDsStr(s, e))
-	result := strings.Join(sections, "\n")
-	res, err := format.Source([]byte(result))
+	sections := cg.Sections{}
+	sections.Add(GetFileHeaderCommentStr(s))
+	sections.Add(GetPackageStr(s, packageName))
+	sections.Add(GetEdgeImportStr(
+		s, manual.Get(cg.ManualImports)))
+	sections.Add(GetExtraFunctionsStr(
+		s, manual.Get(cg.ManualFunctions)))
+	sections.Add(GetGeneratedFunctionsTagStr())
+	sections.Add(GetEdgeAuthMap(s, e))
+	sections.Add(GetEdgeFieldQueryStr(s, e))
+	sections.Add(GetEdgeGetByIDStr(s, e))
+	sections.Add(GetEdgeGetByIDBatcherStr(s, e))
+	sections.Add(GetEdgeGetByIDsStr(s, e))
+	sections.Add(GetEdgeGetByIDsBatcherStr(s, e))
+	sections.Add(GetEdgeWriteFieldQueryStr(s, e))
+	sections.Add(GetUpdateEdgeGetByIDStr(s, e))
+	sections.Add(GetUpdateEdgeGetByIDsStr(s, e))
+	sections.Add(GetDeleteEdgeByIDStr(s, e))
+	sections.Add(GetDeleteEdgeByIDsStr(s, e))
+	if err := manual.Err(); err != nil {
+		return "", err
+	}
+	result, err := sections.Join("\n")
 	if err != nil {
-		fmt.Println(result)
-		panic(err)
+		return "", err
 	}
+	res, err := cg.FormatSource(result)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
 
-	signatureRes := []byte(cg.ReplaceAllStringSubmatchFunc(
-		cg.ManualExtractor,
-		string(res),
-		func(groups []string) string {
-			return cg.StartManual + groups[2] + cg.EndManual
-		},
-	))
-
-	// Generate the MD5 signature
-	sum := md5.Sum([]byte(signatureRes))
-	signature := hex.EncodeToString([]byte(sum[:]))
-
-	// Add the signature to the top of the file
-	return "// @SignedSource (" + signature + ")\n" + string(res)
+// FileHeaderCommentData is the data of the logic/file_header_comment template.
+type FileHeaderCommentData struct {
+	Name string
 }
 
+var fileHeaderCommentTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/file_header_comment",
+	Data: FileHeaderCommentData{},
+	Text: "// Autogenerated {{.Name}} - regenerate with splits-go-schema-" +
+		"codegen\n// Force regeneration over hand edits with " +
+		"generate --force.\n",
+})
+
 // GetFileHeaderCommentStr generates an autogenerated tag.
-func GetFileHeaderCommentStr(s cg.Schema) string {
-	data := struct {
-		Name string
-	}{
+func GetFileHeaderCommentStr(s cg.Schema) (string, error) {
+	data := FileHeaderCommentData{
 		Name: s.GetName(),
 	}
-	template := "// Autogenerated {{.Name}} - regenerate with splits-go-schema-" +
-		"codegen\n// Force autogen by deleting the @SignedSource line.\n"
-	return cg.ExecTemplate(template, "file_header_comment", data, nil)
+	return fileHeaderCommentTemplate.Exec(data)
 }
 
+// PackageData is the data of the logic/package template.
+type PackageData struct {
+	Package string
+}
+
+var packageTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/package",
+	Data: PackageData{},
+	Text: "package {{.Package}}\n",
+})
+
 // GetPackageStr generates the package string.
-func GetPackageStr(s cg.Schema, packageName string) string {
-	data := struct {
-		Package string
-	}{
+func GetPackageStr(s cg.Schema, packageName string) (string, error) {
+	data := PackageData{
 		Package: packageName,
 	}
-	template := "package {{.Package}}\n"
-	return cg.ExecTemplate(template, "package_string", data, nil)
+	return packageTemplate.Exec(data)
 }
 
+// ExtraFunctionsData is the data of the logic/extra_functions template.
+type ExtraFunctionsData struct {
+	ManualPart string
+}
+
+var extraFunctionsTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/extra_functions",
+	Data: ExtraFunctionsData{},
+	Text: cg.StartManualSection(cg.ManualFunctions) + "\n" +
+		"{{.ManualPart}}\n" +
+		cg.EndManual + "\n",
+})
+
 // GetExtraFunctionsStr adds a manual sections for user defined functions.
-func GetExtraFunctionsStr(s cg.Schema, manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
+func GetExtraFunctionsStr(s cg.Schema, manualPart string) (string, error) {
+	data := ExtraFunctionsData{
 		ManualPart: manualPart,
 	}
-	template :=
-		cg.StartManual + "\n" +
-			"{{.ManualPart}}\n" +
-			cg.EndManual + "\n"
-
-	return cg.ExecTemplate(template, "extra_functions", data, nil)
+	return extraFunctionsTemplate.Exec(data)
 }
 
 // GetGeneratedFunctionsTagStr writes a generated functions tagline.
-func GetGeneratedFunctionsTagStr() string {
-	return "// === GENERATED FUNCTIONS === \n"
+func GetGeneratedFunctionsTagStr() (string, error) {
+	return "// === GENERATED FUNCTIONS === \n", nil
 }
 
 // =============================================================================
 // Node
 // =============================================================================
 
-// GetNodeImportStr generates the import block.
-func GetNodeImportStr(s cg.Schema, manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
-		ManualPart: manualPart,
-	}
-	template := "import (\n" +
+// NodeImportData is the data of the logic/node_import template.
+type NodeImportData struct {
+	ManualPart string
+}
+
+var nodeImportTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/node_import",
+	Data: NodeImportData{},
+	Text: "import (\n" +
 		"\t\"splits-go-api/auth/contexts\"\n" +
 		"\t\"splits-go-api/constants\"\n" +
 		"\t\"splits-go-api/db\"\n" +
@@ -193,25 +184,31 @@ func GetNodeImportStr(s cg.Schema, manualPart string) string {
 		"\t\"errors\"\n" +
 		"\t\"time\"\n" +
 		"\n" +
-		cg.StartManual + "\n" +
+		cg.StartManualSection(cg.ManualImports) + "\n" +
 		"{{.ManualPart}}\n" +
 		cg.EndManual + "\n" +
-		")\n"
-	return cg.ExecTemplate(template, "node_import_string", data, nil)
-}
+		")\n",
+})
 
-// GetNodeAuthMap generates the mapping of fields to auth policies
-func GetNodeAuthMap(s cg.Schema) string {
-	data := struct {
-		Name            string
-		Fields          []cg.FieldStruct
-		DeletionPrivacy privacy.Policy
-	}{
-		Name:            s.GetName(),
-		Fields:          s.GetFields(),
-		DeletionPrivacy: s.GetDeletionPrivacy(),
+// GetNodeImportStr generates the import block.
+func GetNodeImportStr(s cg.Schema, manualPart string) (string, error) {
+	data := NodeImportData{
+		ManualPart: manualPart,
 	}
-	template := "// {{.Name}}AuthMap maps a field to the corresponding read " +
+	return nodeImportTemplate.Exec(data)
+}
+
+// NodeAuthMapData is the data of the logic/node_auth_map template.
+type NodeAuthMapData struct {
+	Name            string
+	Fields          []cg.FieldStruct
+	DeletionPrivacy privacy.Policy
+}
+
+var nodeAuthMapTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/node_auth_map",
+	Data: NodeAuthMapData{},
+	Text: "// {{.Name}}AuthMap maps a field to the corresponding read " +
 		"privacy policy.\n" +
 		"var {{.Name}}AuthMap = map[string]privacy.Policy{\n" +
 		"{{range .Fields}}" +
@@ -229,22 +226,29 @@ func GetNodeAuthMap(s cg.Schema) string {
 		"\n" +
 		"// {{.Name}}DeleteAuth is the privacy policy for deleting the node.\n" +
 		"var {{.Name}}DeleteAuth = privacy.{{.DeletionPrivacy.GetName}}\n" +
-		"\n"
-	return cg.ExecTemplate(template, "node_auth_map", data, nil)
-}
+		"\n",
+})
 
-// GetNodeFieldQueryStr creates a function that generates a query for the
-// specified fields.
-func GetNodeFieldQueryStr(s cg.Schema) string {
-	fields := s.GetFields()
-	data := struct {
-		Name   string
-		Fields []cg.FieldStruct
-	}{
-		Name:   s.GetName(),
-		Fields: fields,
+// GetNodeAuthMap generates the mapping of fields to auth policies
+func GetNodeAuthMap(s cg.Schema) (string, error) {
+	data := NodeAuthMapData{
+		Name:            s.GetName(),
+		Fields:          s.GetFields(),
+		DeletionPrivacy: s.GetDeletionPrivacy(),
 	}
-	template := "func create{{.Name}}FieldQuery(\n" +
+	return nodeAuthMapTemplate.Exec(data)
+}
+
+// NodeFieldQueryData is the data of the logic/node_field_query template.
+type NodeFieldQueryData struct {
+	Name   string
+	Fields []cg.FieldStruct
+}
+
+var nodeFieldQueryTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/node_field_query",
+	Data: NodeFieldQueryData{},
+	Text: "func create{{.Name}}FieldQuery(\n" +
 		"\tconn *db.Conn,\n" +
 		"\tvc contexts.ViewerContext,\n" +
 		"\tparams context.Context,\n" +
@@ -292,22 +296,30 @@ func GetNodeFieldQueryStr(s cg.Schema) string {
 		"\t\t}\n" +
 		"\t}\n" +
 		"\treturn q, fieldCheck, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "node_field_query", data, nil)
-}
+		"}\n",
+})
 
-// GetNodeGetByIDStr gets the function that retrieves fields by the id of the
-// node.
-func GetNodeGetByIDStr(s cg.Schema) string {
+// GetNodeFieldQueryStr creates a function that generates a query for the
+// specified fields.
+func GetNodeFieldQueryStr(s cg.Schema) (string, error) {
 	fields := s.GetFields()
-	data := struct {
-		Name   string
-		Fields []cg.FieldStruct
-	}{
+	data := NodeFieldQueryData{
 		Name:   s.GetName(),
 		Fields: fields,
 	}
-	template := "// Get{{.Name}}ByID retrives the fields of a specific " +
+	return nodeFieldQueryTemplate.Exec(data)
+}
+
+// NodeGetByIDData is the data of the logic/node_get_by_id template.
+type NodeGetByIDData struct {
+	Name   string
+	Fields []cg.FieldStruct
+}
+
+var nodeGetByIDTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/node_get_by_id",
+	Data: NodeGetByIDData{},
+	Text: "// Get{{.Name}}ByID retrives the fields of a specific " +
 		"{{.Name}}.\n" +
 		"// If there is insufficient authorization, the field will return null.\n" +
 		"func Get{{.Name}}ByID(\n" +
@@ -349,21 +361,30 @@ func GetNodeGetByIDStr(s cg.Schema) string {
 		"\tresults := util.RemoveUnauthedFields(row, fieldCheck)\n" +
 		"\n" +
 		"\treturn results, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "node_by_id", data, nil)
-}
+		"}\n",
+})
 
-// GetNodeGetByIDBatchStr generates the GetByID batcher
-func GetNodeGetByIDBatchStr(s cg.Schema) string {
+// GetNodeGetByIDStr gets the function that retrieves fields by the id of the
+// node.
+func GetNodeGetByIDStr(s cg.Schema) (string, error) {
 	fields := s.GetFields()
-	data := struct {
-		Name   string
-		Fields []cg.FieldStruct
-	}{
+	data := NodeGetByIDData{
 		Name:   s.GetName(),
 		Fields: fields,
 	}
-	template := "// Get{{.Name}}ByIDBatcher wraps the Get{{.Name}}ByID request " +
+	return nodeGetByIDTemplate.Exec(data)
+}
+
+// NodeGetByIDBatchData is the data of the logic/node_get_by_id_batch template.
+type NodeGetByIDBatchData struct {
+	Name   string
+	Fields []cg.FieldStruct
+}
+
+var nodeGetByIDBatchTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/node_get_by_id_batch",
+	Data: NodeGetByIDBatchData{},
+	Text: "// Get{{.Name}}ByIDBatcher wraps the Get{{.Name}}ByID request " +
 		"to be batched later.\n" +
 		"func Get{{.Name}}ByIDBatcher(\n" +
 		"\tconn *db.Conn,\n" +
@@ -393,81 +414,39 @@ func GetNodeGetByIDBatchStr(s cg.Schema) string {
 		"\t\treturn util.RemoveUnauthedFields(row, fieldCheck)\n" +
 		"\t}\n" +
 		"\treturn batcher, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "node_by_id_batch", data, nil)
-}
+		"}\n",
+})
 
-// GetNodeConnectedNodesStr generates the function that gets connected
-// corresponding node ids. This also writes the corresponding batch wrapper.
-func GetNodeConnectedNodesStr(s cg.Schema) string {
-
-	type NamePrivacyPair struct {
-		Name      string
-		QueryName string
-		Privacy   privacy.Policy
-		Fields    []cg.FieldStruct
-		OrderBy   string
+// GetNodeGetByIDBatchStr generates the GetByID batcher
+func GetNodeGetByIDBatchStr(s cg.Schema) (string, error) {
+	fields := s.GetFields()
+	data := NodeGetByIDBatchData{
+		Name:   s.GetName(),
+		Fields: fields,
 	}
+	return nodeGetByIDBatchTemplate.Exec(data)
+}
 
-	// Extract the edge name to the node name
-	edges := map[string]NamePrivacyPair{}
-	for _, e := range s.GetEdges() {
-		var orderBy string
-		if e.ToNode.GetName() == s.GetName() { // group->user
-			if e.GQLEdge != nil {
-				orderBy = e.GQLEdge.ReverseOrderBy
-			}
-			edges[e.CodeName] = NamePrivacyPair{e.BackwardsName,
-				e.FromNode.GetName(),
-				e.ReversePrivacy,
-				e.FromNode.GetFields(),
-				orderBy,
-			}
-		} else if e.FromNode.GetName() == s.GetName() {
-			if e.GQLEdge != nil {
-				orderBy = e.GQLEdge.OrderBy
-			}
-			edges[e.CodeName] = NamePrivacyPair{e.ForwardsName,
-				e.ToNode.GetName(),
-				e.Privacy,
-				e.ToNode.GetFields(),
-				orderBy,
-			}
-		}
-	}
-	for _, e := range s.GetEdgePointers() {
-		var orderBy string
-		if e.ToNode.GetName() == s.GetName() { // group->user
-			if e.GQLEdge != nil {
-				orderBy = e.GQLEdge.ReverseOrderBy
-			}
-			edges[e.CodeName] = NamePrivacyPair{e.BackwardsName,
-				e.FromNode.GetName(),
-				e.ReversePrivacy,
-				e.FromNode.GetFields(),
-				orderBy,
-			}
-		} else if e.FromNode.GetName() == s.GetName() {
-			if e.GQLEdge != nil {
-				orderBy = e.GQLEdge.OrderBy
-			}
-			edges[e.CodeName] = NamePrivacyPair{e.ForwardsName,
-				e.ToNode.GetName(),
-				e.Privacy,
-				e.ToNode.GetFields(),
-				orderBy,
-			}
-		}
-	}
+// NamePrivacyPair is an edge from a node to a connected node.
+type NamePrivacyPair struct {
+	Name      string
+	QueryName string
+	Privacy   privacy.Policy
+	Fields    []cg.FieldStruct
+	OrderBy   string
+}
 
-	data := struct {
-		Name       string
-		EdgeToNode map[string]NamePrivacyPair
-	}{
-		Name:       s.GetName(),
-		EdgeToNode: edges,
-	}
-	template := "{{range $edgeName, $value := .EdgeToNode}}" +
+// NodeConnectedNodesData is the data of the logic/node_connected_nodes
+// template.
+type NodeConnectedNodesData struct {
+	Name       string
+	EdgeToNode map[string]NamePrivacyPair
+}
+
+var nodeConnectedNodesTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/node_connected_nodes",
+	Data: NodeConnectedNodesData{},
+	Text: "{{range $edgeName, $value := .EdgeToNode}}" +
 		"// Get{{$.Name}}{{$value.Name}} retrieves the ids of connected " +
 		"{{$value.Name}}s.\n" +
 		"func Get{{$.Name}}{{$value.Name}}(\n" +
@@ -557,23 +536,82 @@ func GetNodeConnectedNodesStr(s cg.Schema) string {
 		"\t}\n" +
 		"\treturn batcher, nil\n" +
 		"}\n\n" +
-		"{{end}}"
+		"{{end}}",
+})
 
-	return cg.ExecTemplate(template, "node_connected_nodes", data, nil)
-}
+// GetNodeConnectedNodesStr generates the function that gets connected
+// corresponding node ids. This also writes the corresponding batch wrapper.
+func GetNodeConnectedNodesStr(s cg.Schema) (string, error) {
 
-// GetNodeWriteFieldQueryStr creates a function that generates a query for the
-// modifying specified fields.
-func GetNodeWriteFieldQueryStr(s cg.Schema) string {
-	fields := s.GetFields()
-	data := struct {
-		Name   string
-		Fields []cg.FieldStruct
-	}{
-		Name:   s.GetName(),
-		Fields: fields,
+	// Extract the edge name to the node name
+	edges := map[string]NamePrivacyPair{}
+	for _, e := range s.GetEdges() {
+		var orderBy string
+		if e.ToNode.GetName() == s.GetName() { // group->user
+			if e.GQLEdge != nil {
+				orderBy = e.GQLEdge.ReverseOrderBy
+			}
+			edges[e.CodeName] = NamePrivacyPair{e.BackwardsName,
+				e.FromNode.GetName(),
+				e.ReversePrivacy,
+				e.FromNode.GetFields(),
+				orderBy,
+			}
+		} else if e.FromNode.GetName() == s.GetName() {
+			if e.GQLEdge != nil {
+				orderBy = e.GQLEdge.OrderBy
+			}
+			edges[e.CodeName] = NamePrivacyPair{e.ForwardsName,
+				e.ToNode.GetName(),
+				e.Privacy,
+				e.ToNode.GetFields(),
+				orderBy,
+			}
+		}
 	}
-	template := "func create{{.Name}}WriteFieldQuery(\n" +
+	for _, e := range cg.SortedEdgePointers(s) {
+		var orderBy string
+		if e.ToNode.GetName() == s.GetName() { // group->user
+			if e.GQLEdge != nil {
+				orderBy = e.GQLEdge.ReverseOrderBy
+			}
+			edges[e.CodeName] = NamePrivacyPair{e.BackwardsName,
+				e.FromNode.GetName(),
+				e.ReversePrivacy,
+				e.FromNode.GetFields(),
+				orderBy,
+			}
+		} else if e.FromNode.GetName() == s.GetName() {
+			if e.GQLEdge != nil {
+				orderBy = e.GQLEdge.OrderBy
+			}
+			edges[e.CodeName] = NamePrivacyPair{e.ForwardsName,
+				e.ToNode.GetName(),
+				e.Privacy,
+				e.ToNode.GetFields(),
+				orderBy,
+			}
+		}
+	}
+
+	data := NodeConnectedNodesData{
+		Name:       s.GetName(),
+		EdgeToNode: edges,
+	}
+	return nodeConnectedNodesTemplate.Exec(data)
+}
+
+// NodeWriteFieldQueryData is the data of the logic/node_write_field_query
+// template.
+type NodeWriteFieldQueryData struct {
+	Name   string
+	Fields []cg.FieldStruct
+}
+
+var nodeWriteFieldQueryTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/node_write_field_query",
+	Data: NodeWriteFieldQueryData{},
+	Text: "func create{{.Name}}WriteFieldQuery(\n" +
 		"\tconn *db.Conn,\n" +
 		"\tvc contexts.ViewerContext,\n" +
 		"\tparams context.Context,\n" +
@@ -621,22 +659,31 @@ func GetNodeWriteFieldQueryStr(s cg.Schema) string {
 		"\t\t}\n" +
 		"\t}\n" +
 		"\treturn q, mutatedFields, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "node_write_field_query", data, nil)
-}
+		"}\n",
+})
 
-// GetUpdateNodeGetByIDStr gets the function that updates fields by the id of
-// the node.
-func GetUpdateNodeGetByIDStr(s cg.Schema) string {
+// GetNodeWriteFieldQueryStr creates a function that generates a query for the
+// modifying specified fields.
+func GetNodeWriteFieldQueryStr(s cg.Schema) (string, error) {
 	fields := s.GetFields()
-	data := struct {
-		Name   string
-		Fields []cg.FieldStruct
-	}{
+	data := NodeWriteFieldQueryData{
 		Name:   s.GetName(),
 		Fields: fields,
 	}
-	template := "// Update{{.Name}}ByID updates the fields of a specific " +
+	return nodeWriteFieldQueryTemplate.Exec(data)
+}
+
+// UpdateNodeGetByIDData is the data of the logic/update_node_get_by_id
+// template.
+type UpdateNodeGetByIDData struct {
+	Name   string
+	Fields []cg.FieldStruct
+}
+
+var updateNodeGetByIDTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/update_node_get_by_id",
+	Data: UpdateNodeGetByIDData{},
+	Text: "// Update{{.Name}}ByID updates the fields of a specific " +
 		"{{.Name}}.\n" +
 		"// If there is insufficient authorization, the field will not be " +
 		"returned.\n" +
@@ -682,18 +729,29 @@ func GetUpdateNodeGetByIDStr(s cg.Schema) string {
 		"\t}\n" +
 		"\n" +
 		"\treturn mutatedFields, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "node_write_by_id", data, nil)
-}
+		"}\n",
+})
 
-// GetDeleteNodeByIDStr deletes a node by its id.
-func GetDeleteNodeByIDStr(s cg.Schema) string {
-	data := struct {
-		Name string
-	}{
-		Name: s.GetName(),
+// GetUpdateNodeGetByIDStr gets the function that updates fields by the id of
+// the node.
+func GetUpdateNodeGetByIDStr(s cg.Schema) (string, error) {
+	fields := s.GetFields()
+	data := UpdateNodeGetByIDData{
+		Name:   s.GetName(),
+		Fields: fields,
 	}
-	template := "// Delete{{.Name}}ByID deletes the node and its corresponding " +
+	return updateNodeGetByIDTemplate.Exec(data)
+}
+
+// DeleteNodeByIDData is the data of the logic/delete_node_by_id template.
+type DeleteNodeByIDData struct {
+	Name string
+}
+
+var deleteNodeByIDTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/delete_node_by_id",
+	Data: DeleteNodeByIDData{},
+	Text: "// Delete{{.Name}}ByID deletes the node and its corresponding " +
 		"edges.\n" +
 		"// Auth is also respected, otherwise no action will take place.\n" +
 		"func Delete{{.Name}}ByID(\n" +
@@ -729,22 +787,30 @@ func GetDeleteNodeByIDStr(s cg.Schema) string {
 		"\t\treturn nil\n" +
 		"\t}\n" +
 		"\t return errors.New(\"could not delete {{.Name}}: \" + id)\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "node_delete_by_id", data, nil)
+		"}\n",
+})
+
+// GetDeleteNodeByIDStr deletes a node by its id.
+func GetDeleteNodeByIDStr(s cg.Schema) (string, error) {
+	data := DeleteNodeByIDData{
+		Name: s.GetName(),
+	}
+	return deleteNodeByIDTemplate.Exec(data)
 }
 
 // =============================================================================
 // Edges
 // =============================================================================
 
-// GetEdgeImportStr generates the import block.
-func GetEdgeImportStr(s cg.Schema, manualPart string) string {
-	data := struct {
-		ManualPart string
-	}{
-		ManualPart: manualPart,
-	}
-	template := "import (\n" +
+// EdgeImportData is the data of the logic/edge_import template.
+type EdgeImportData struct {
+	ManualPart string
+}
+
+var edgeImportTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/edge_import",
+	Data: EdgeImportData{},
+	Text: "import (\n" +
 		"\t\"splits-go-api/auth/contexts\"\n" +
 		"\t\"splits-go-api/constants\"\n" +
 		"\t\"splits-go-api/db\"\n" +
@@ -758,25 +824,31 @@ func GetEdgeImportStr(s cg.Schema, manualPart string) string {
 		"\t\"errors\"\n" +
 		"\t\"time\"\n" +
 		"\n" +
-		cg.StartManual + "\n" +
+		cg.StartManualSection(cg.ManualImports) + "\n" +
 		"{{.ManualPart}}\n" +
 		cg.EndManual + "\n" +
-		")\n"
-	return cg.ExecTemplate(template, "edge_import_string", data, nil)
-}
+		")\n",
+})
 
-// GetEdgeAuthMap generates the mapping of fields to auth policies
-func GetEdgeAuthMap(s cg.Schema, e cg.EdgeStruct) string {
-	data := struct {
-		Name            string
-		Fields          []cg.EdgeFieldStruct
-		DeletionPrivacy privacy.Policy
-	}{
-		Name:            e.CodeName,
-		Fields:          e.Fields,
-		DeletionPrivacy: e.DeletionPrivacy,
+// GetEdgeImportStr generates the import block.
+func GetEdgeImportStr(s cg.Schema, manualPart string) (string, error) {
+	data := EdgeImportData{
+		ManualPart: manualPart,
 	}
-	template := "// {{.Name}}AuthMap maps a field to the corresponding read " +
+	return edgeImportTemplate.Exec(data)
+}
+
+// EdgeAuthMapData is the data of the logic/edge_auth_map template.
+type EdgeAuthMapData struct {
+	Name            string
+	Fields          []cg.EdgeFieldStruct
+	DeletionPrivacy privacy.Policy
+}
+
+var edgeAuthMapTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/edge_auth_map",
+	Data: EdgeAuthMapData{},
+	Text: "// {{.Name}}AuthMap maps a field to the corresponding read " +
 		"privacy policy.\n" +
 		"var {{.Name}}AuthMap = map[string]privacy.Policy{\n" +
 		"{{range .Fields}}" +
@@ -794,29 +866,31 @@ func GetEdgeAuthMap(s cg.Schema, e cg.EdgeStruct) string {
 		"\n" +
 		"// {{.Name}}DeleteAuth is the privacy policy for deleting the node.\n" +
 		"var {{.Name}}DeleteAuth = privacy.{{.DeletionPrivacy.GetName}}\n" +
-		"\n"
-	return cg.ExecTemplate(template, "edge_auth_map", data, nil)
+		"\n",
+})
+
+// GetEdgeAuthMap generates the mapping of fields to auth policies
+func GetEdgeAuthMap(s cg.Schema, e cg.EdgeStruct) (string, error) {
+	data := EdgeAuthMapData{
+		Name:            e.CodeName,
+		Fields:          e.Fields,
+		DeletionPrivacy: e.DeletionPrivacy,
+	}
+	return edgeAuthMapTemplate.Exec(data)
 }
 
-// GetEdgeFieldQueryStr creates a function that generates a query for the
-// specified fields.
-func GetEdgeFieldQueryStr(s cg.Schema, e cg.EdgeStruct) string {
-	fields := e.Fields
-	fromVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
-	toVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
+// EdgeFieldQueryData is the data of the logic/edge_field_query template.
+type EdgeFieldQueryData struct {
+	Name    string
+	Fields  []cg.EdgeFieldStruct
+	FromVar string
+	ToVar   string
+}
 
-	data := struct {
-		Name    string
-		Fields  []cg.EdgeFieldStruct
-		FromVar string
-		ToVar   string
-	}{
-		Name:    e.CodeName,
-		Fields:  fields,
-		FromVar: fromVar,
-		ToVar:   toVar,
-	}
-	template := "func create{{.Name}}FieldQuery(\n" +
+var edgeFieldQueryTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/edge_field_query",
+	Data: EdgeFieldQueryData{},
+	Text: "func create{{.Name}}FieldQuery(\n" +
 		"\tconn *db.Conn,\n" +
 		"\tvc contexts.ViewerContext,\n" +
 		"\tparams context.Context,\n" +
@@ -866,32 +940,39 @@ func GetEdgeFieldQueryStr(s cg.Schema, e cg.EdgeStruct) string {
 		"\t\t}\n" +
 		"\t}\n" +
 		"\treturn q, fieldCheck, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge_field_query", data, nil)
-}
+		"}\n",
+})
 
-// GetEdgeGetByIDStr generates the the function that retrieves edge fields.
-func GetEdgeGetByIDStr(s cg.Schema, e cg.EdgeStruct) string {
+// GetEdgeFieldQueryStr creates a function that generates a query for the
+// specified fields.
+func GetEdgeFieldQueryStr(s cg.Schema, e cg.EdgeStruct) (string, error) {
 	fields := e.Fields
 	fromVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
 	toVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
 
-	data := struct {
-		Name    string
-		Fields  []cg.EdgeFieldStruct
-		From    string
-		To      string
-		FromVar string
-		ToVar   string
-	}{
+	data := EdgeFieldQueryData{
 		Name:    e.CodeName,
 		Fields:  fields,
-		From:    e.FromNode.GetName(),
-		To:      e.ToNode.GetName(),
 		FromVar: fromVar,
 		ToVar:   toVar,
 	}
-	template := "// Get{{.Name}}ByID retrives the fields of a specific " +
+	return edgeFieldQueryTemplate.Exec(data)
+}
+
+// EdgeGetByIDData is the data of the logic/edge_get_by_id template.
+type EdgeGetByIDData struct {
+	Name    string
+	Fields  []cg.EdgeFieldStruct
+	From    string
+	To      string
+	FromVar string
+	ToVar   string
+}
+
+var edgeGetByIDTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/edge_get_by_id",
+	Data: EdgeGetByIDData{},
+	Text: "// Get{{.Name}}ByID retrives the fields of a specific " +
 		"{{.Name}}.\n" +
 		"// If there is insufficient authorization, the field will return null.\n" +
 		"func Get{{.Name}}ByID(\n" +
@@ -951,24 +1032,16 @@ func GetEdgeGetByIDStr(s cg.Schema, e cg.EdgeStruct) string {
 		"\tresults := util.RemoveUnauthedFields(row, fieldCheck)\n" +
 		"\n" +
 		"\treturn results, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge_by_id", data, nil)
-}
+		"}\n",
+})
 
-// GetEdgeGetByIDBatcherStr creates the batcher function for GetEdgeByID
-func GetEdgeGetByIDBatcherStr(s cg.Schema, e cg.EdgeStruct) string {
+// GetEdgeGetByIDStr generates the the function that retrieves edge fields.
+func GetEdgeGetByIDStr(s cg.Schema, e cg.EdgeStruct) (string, error) {
 	fields := e.Fields
 	fromVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
 	toVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
 
-	data := struct {
-		Name    string
-		Fields  []cg.EdgeFieldStruct
-		From    string
-		To      string
-		FromVar string
-		ToVar   string
-	}{
+	data := EdgeGetByIDData{
 		Name:    e.CodeName,
 		Fields:  fields,
 		From:    e.FromNode.GetName(),
@@ -976,7 +1049,24 @@ func GetEdgeGetByIDBatcherStr(s cg.Schema, e cg.EdgeStruct) string {
 		FromVar: fromVar,
 		ToVar:   toVar,
 	}
-	template := "// Get{{.Name}}ByIDBatcher wraps the Get{{.Name}}ByID to be " +
+	return edgeGetByIDTemplate.Exec(data)
+}
+
+// EdgeGetByIDBatcherData is the data of the logic/edge_get_by_id_batcher
+// template.
+type EdgeGetByIDBatcherData struct {
+	Name    string
+	Fields  []cg.EdgeFieldStruct
+	From    string
+	To      string
+	FromVar string
+	ToVar   string
+}
+
+var edgeGetByIDBatcherTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/edge_get_by_id_batcher",
+	Data: EdgeGetByIDBatcherData{},
+	Text: "// Get{{.Name}}ByIDBatcher wraps the Get{{.Name}}ByID to be " +
 		"batched later.\n" +
 		"func Get{{.Name}}ByIDBatcher(\n" +
 		"\tconn *db.Conn,\n" +
@@ -1021,34 +1111,40 @@ func GetEdgeGetByIDBatcherStr(s cg.Schema, e cg.EdgeStruct) string {
 		"\t\treturn util.RemoveUnauthedFields(row, fieldCheck)\n" +
 		"\t}\n" +
 		"\treturn batcher, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge_by_id_batcher", data, nil)
-}
+		"}\n",
+})
 
-// GetEdgeGetByIDsStr generates the function that gets fields on an edge.
-func GetEdgeGetByIDsStr(s cg.Schema, e cg.EdgeStruct) string {
+// GetEdgeGetByIDBatcherStr creates the batcher function for GetEdgeByID
+func GetEdgeGetByIDBatcherStr(s cg.Schema, e cg.EdgeStruct) (string, error) {
 	fields := e.Fields
-	fromIDVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
-	toIDVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
-	fromNode := e.FromNode.GetName()
-	toNode := e.ToNode.GetName()
+	fromVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
+	toVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
 
-	data := struct {
-		Name      string
-		Fields    []cg.EdgeFieldStruct
-		FromIDVar string
-		ToIDVar   string
-		FromNode  string
-		ToNode    string
-	}{
-		Name:      e.CodeName,
-		Fields:    fields,
-		FromIDVar: fromIDVar,
-		ToIDVar:   toIDVar,
-		FromNode:  fromNode,
-		ToNode:    toNode,
+	data := EdgeGetByIDBatcherData{
+		Name:    e.CodeName,
+		Fields:  fields,
+		From:    e.FromNode.GetName(),
+		To:      e.ToNode.GetName(),
+		FromVar: fromVar,
+		ToVar:   toVar,
 	}
-	template := "// Get{{.Name}}ByIDs retrives the fields of a specific " +
+	return edgeGetByIDBatcherTemplate.Exec(data)
+}
+
+// EdgeGetByIDsData is the data of the logic/edge_get_by_ids template.
+type EdgeGetByIDsData struct {
+	Name      string
+	Fields    []cg.EdgeFieldStruct
+	FromIDVar string
+	ToIDVar   string
+	FromNode  string
+	ToNode    string
+}
+
+var edgeGetByIDsTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/edge_get_by_ids",
+	Data: EdgeGetByIDsData{},
+	Text: "// Get{{.Name}}ByIDs retrives the fields of a specific " +
 		"{{.Name}}.\n" +
 		"// If there is insufficient authorization, the field will return null.\n" +
 		"func Get{{.Name}}ByIDs(\n" +
@@ -1108,26 +1204,18 @@ func GetEdgeGetByIDsStr(s cg.Schema, e cg.EdgeStruct) string {
 		"\tresults := util.RemoveUnauthedFields(row, fieldCheck)\n" +
 		"\n" +
 		"\treturn results, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge_by_ids", data, nil)
-}
+		"}\n",
+})
 
-// GetEdgeGetByIDsBatcherStr creates the batcher function for GetEdgeByIDs
-func GetEdgeGetByIDsBatcherStr(s cg.Schema, e cg.EdgeStruct) string {
+// GetEdgeGetByIDsStr generates the function that gets fields on an edge.
+func GetEdgeGetByIDsStr(s cg.Schema, e cg.EdgeStruct) (string, error) {
 	fields := e.Fields
 	fromIDVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
 	toIDVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
 	fromNode := e.FromNode.GetName()
 	toNode := e.ToNode.GetName()
 
-	data := struct {
-		Name      string
-		Fields    []cg.EdgeFieldStruct
-		FromIDVar string
-		ToIDVar   string
-		FromNode  string
-		ToNode    string
-	}{
+	data := EdgeGetByIDsData{
 		Name:      e.CodeName,
 		Fields:    fields,
 		FromIDVar: fromIDVar,
@@ -1135,7 +1223,24 @@ func GetEdgeGetByIDsBatcherStr(s cg.Schema, e cg.EdgeStruct) string {
 		FromNode:  fromNode,
 		ToNode:    toNode,
 	}
-	template := "// Get{{.Name}}ByIDsBatcher wraps the Get{{.Name}}ByIDs to be " +
+	return edgeGetByIDsTemplate.Exec(data)
+}
+
+// EdgeGetByIDsBatcherData is the data of the logic/edge_get_by_ids_batcher
+// template.
+type EdgeGetByIDsBatcherData struct {
+	Name      string
+	Fields    []cg.EdgeFieldStruct
+	FromIDVar string
+	ToIDVar   string
+	FromNode  string
+	ToNode    string
+}
+
+var edgeGetByIDsBatcherTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/edge_get_by_ids_batcher",
+	Data: EdgeGetByIDsBatcherData{},
+	Text: "// Get{{.Name}}ByIDsBatcher wraps the Get{{.Name}}ByIDs to be " +
 		"batched later.\n" +
 		"// If there is insufficient authorization, the field will return null.\n" +
 		"func Get{{.Name}}ByIDsBatcher(\n" +
@@ -1181,29 +1286,41 @@ func GetEdgeGetByIDsBatcherStr(s cg.Schema, e cg.EdgeStruct) string {
 		"\t\treturn util.RemoveUnauthedFields(row, fieldCheck)\n" +
 		"\t}\n" +
 		"\treturn batcher, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge_by_ids_batcher", data, nil)
-}
+		"}\n",
+})
 
-// GetEdgeWriteFieldQueryStr creates a function that generates a query for the
-// updating of fields.
-func GetEdgeWriteFieldQueryStr(s cg.Schema, e cg.EdgeStruct) string {
+// GetEdgeGetByIDsBatcherStr creates the batcher function for GetEdgeByIDs
+func GetEdgeGetByIDsBatcherStr(s cg.Schema, e cg.EdgeStruct) (string, error) {
 	fields := e.Fields
-	fromVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
-	toVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
+	fromIDVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
+	toIDVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
+	fromNode := e.FromNode.GetName()
+	toNode := e.ToNode.GetName()
 
-	data := struct {
-		Name    string
-		Fields  []cg.EdgeFieldStruct
-		FromVar string
-		ToVar   string
-	}{
-		Name:    e.CodeName,
-		Fields:  fields,
-		FromVar: fromVar,
-		ToVar:   toVar,
+	data := EdgeGetByIDsBatcherData{
+		Name:      e.CodeName,
+		Fields:    fields,
+		FromIDVar: fromIDVar,
+		ToIDVar:   toIDVar,
+		FromNode:  fromNode,
+		ToNode:    toNode,
 	}
-	template := "func create{{.Name}}WriteFieldQuery(\n" +
+	return edgeGetByIDsBatcherTemplate.Exec(data)
+}
+
+// EdgeWriteFieldQueryData is the data of the logic/edge_write_field_query
+// template.
+type EdgeWriteFieldQueryData struct {
+	Name    string
+	Fields  []cg.EdgeFieldStruct
+	FromVar string
+	ToVar   string
+}
+
+var edgeWriteFieldQueryTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/edge_write_field_query",
+	Data: EdgeWriteFieldQueryData{},
+	Text: "func create{{.Name}}WriteFieldQuery(\n" +
 		"\tconn *db.Conn,\n" +
 		"\tvc contexts.ViewerContext,\n" +
 		"\tparams context.Context,\n" +
@@ -1250,32 +1367,40 @@ func GetEdgeWriteFieldQueryStr(s cg.Schema, e cg.EdgeStruct) string {
 		"\t\t}\n" +
 		"\t}\n" +
 		"\treturn q, mutatedFields, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge_write_field_query", data, nil)
-}
+		"}\n",
+})
 
-// GetUpdateEdgeGetByIDStr generates the the function that updates edge fields.
-func GetUpdateEdgeGetByIDStr(s cg.Schema, e cg.EdgeStruct) string {
+// GetEdgeWriteFieldQueryStr creates a function that generates a query for the
+// updating of fields.
+func GetEdgeWriteFieldQueryStr(s cg.Schema, e cg.EdgeStruct) (string, error) {
 	fields := e.Fields
 	fromVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
 	toVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
 
-	data := struct {
-		Name    string
-		Fields  []cg.EdgeFieldStruct
-		From    string
-		To      string
-		FromVar string
-		ToVar   string
-	}{
+	data := EdgeWriteFieldQueryData{
 		Name:    e.CodeName,
 		Fields:  fields,
-		From:    e.FromNode.GetName(),
-		To:      e.ToNode.GetName(),
 		FromVar: fromVar,
 		ToVar:   toVar,
 	}
-	template := "// Update{{.Name}}ByID updates the fields of a specific " +
+	return edgeWriteFieldQueryTemplate.Exec(data)
+}
+
+// UpdateEdgeGetByIDData is the data of the logic/update_edge_get_by_id
+// template.
+type UpdateEdgeGetByIDData struct {
+	Name    string
+	Fields  []cg.EdgeFieldStruct
+	From    string
+	To      string
+	FromVar string
+	ToVar   string
+}
+
+var updateEdgeGetByIDTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/update_edge_get_by_id",
+	Data: UpdateEdgeGetByIDData{},
+	Text: "// Update{{.Name}}ByID updates the fields of a specific " +
 		"{{.Name}}.\n" +
 		"// If there is insufficient authorization, the field will not be " +
 		"mutated\n" +
@@ -1328,35 +1453,41 @@ func GetUpdateEdgeGetByIDStr(s cg.Schema, e cg.EdgeStruct) string {
 		"\t}\n" +
 		"\n" +
 		"\treturn mutatedFields, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge_write_by_id", data, nil)
-}
+		"}\n",
+})
 
-// GetUpdateEdgeGetByIDsStr generates the function that updates fields on an
-// edge.
-func GetUpdateEdgeGetByIDsStr(s cg.Schema, e cg.EdgeStruct) string {
+// GetUpdateEdgeGetByIDStr generates the the function that updates edge fields.
+func GetUpdateEdgeGetByIDStr(s cg.Schema, e cg.EdgeStruct) (string, error) {
 	fields := e.Fields
-	fromIDVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
-	toIDVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
-	fromNode := e.FromNode.GetName()
-	toNode := e.ToNode.GetName()
+	fromVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
+	toVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
 
-	data := struct {
-		Name      string
-		Fields    []cg.EdgeFieldStruct
-		FromIDVar string
-		ToIDVar   string
-		FromNode  string
-		ToNode    string
-	}{
-		Name:      e.CodeName,
-		Fields:    fields,
-		FromIDVar: fromIDVar,
-		ToIDVar:   toIDVar,
-		FromNode:  fromNode,
-		ToNode:    toNode,
+	data := UpdateEdgeGetByIDData{
+		Name:    e.CodeName,
+		Fields:  fields,
+		From:    e.FromNode.GetName(),
+		To:      e.ToNode.GetName(),
+		FromVar: fromVar,
+		ToVar:   toVar,
 	}
-	template := "// Update{{.Name}}ByIDs updates the fields of a specific " +
+	return updateEdgeGetByIDTemplate.Exec(data)
+}
+
+// UpdateEdgeGetByIDsData is the data of the logic/update_edge_get_by_ids
+// template.
+type UpdateEdgeGetByIDsData struct {
+	Name      string
+	Fields    []cg.EdgeFieldStruct
+	FromIDVar string
+	ToIDVar   string
+	FromNode  string
+	ToNode    string
+}
+
+var updateEdgeGetByIDsTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/update_edge_get_by_ids",
+	Data: UpdateEdgeGetByIDsData{},
+	Text: "// Update{{.Name}}ByIDs updates the fields of a specific " +
 		"{{.Name}}.\n" +
 		"// If there is insufficient authorization, the field will not be " +
 		"mutated.\n" +
@@ -1409,31 +1540,42 @@ func GetUpdateEdgeGetByIDsStr(s cg.Schema, e cg.EdgeStruct) string {
 		"\t}\n" +
 		"\n" +
 		"\treturn mutatedFields, nil\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge_write_by_ids", data, nil)
-}
+		"}\n",
+})
 
-// GetDeleteEdgeByIDStr deletes an edge by its id.
-func GetDeleteEdgeByIDStr(s cg.Schema, e cg.EdgeStruct) string {
+// GetUpdateEdgeGetByIDsStr generates the function that updates fields on an
+// edge.
+func GetUpdateEdgeGetByIDsStr(s cg.Schema, e cg.EdgeStruct) (string, error) {
+	fields := e.Fields
 	fromIDVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
 	toIDVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
 	fromNode := e.FromNode.GetName()
 	toNode := e.ToNode.GetName()
 
-	data := struct {
-		Name      string
-		FromIDVar string
-		ToIDVar   string
-		FromNode  string
-		ToNode    string
-	}{
+	data := UpdateEdgeGetByIDsData{
 		Name:      e.CodeName,
+		Fields:    fields,
 		FromIDVar: fromIDVar,
 		ToIDVar:   toIDVar,
 		FromNode:  fromNode,
 		ToNode:    toNode,
 	}
-	template := "// Delete{{.Name}}ByID deletes the edge.\n" +
+	return updateEdgeGetByIDsTemplate.Exec(data)
+}
+
+// DeleteEdgeByIDData is the data of the logic/delete_edge_by_id template.
+type DeleteEdgeByIDData struct {
+	Name      string
+	FromIDVar string
+	ToIDVar   string
+	FromNode  string
+	ToNode    string
+}
+
+var deleteEdgeByIDTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/delete_edge_by_id",
+	Data: DeleteEdgeByIDData{},
+	Text: "// Delete{{.Name}}ByID deletes the edge.\n" +
 		"// Auth is also respected, otherwise no action will take place.\n" +
 		"func Delete{{.Name}}ByID(\n" +
 		"\tconn *db.Conn,\n" +
@@ -1486,31 +1628,39 @@ func GetDeleteEdgeByIDStr(s cg.Schema, e cg.EdgeStruct) string {
 		"\t\treturn nil\n" +
 		"\t}\n" +
 		"\t return errors.New(\"could not delete {{.Name}}: \" + id)\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge_delete_by_id", data, nil)
-}
+		"}\n",
+})
 
-// GetDeleteEdgeByIDsStr deletes an edge by connected ids.
-func GetDeleteEdgeByIDsStr(s cg.Schema, e cg.EdgeStruct) string {
+// GetDeleteEdgeByIDStr deletes an edge by its id.
+func GetDeleteEdgeByIDStr(s cg.Schema, e cg.EdgeStruct) (string, error) {
 	fromIDVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
 	toIDVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
 	fromNode := e.FromNode.GetName()
 	toNode := e.ToNode.GetName()
 
-	data := struct {
-		Name      string
-		FromIDVar string
-		ToIDVar   string
-		FromNode  string
-		ToNode    string
-	}{
+	data := DeleteEdgeByIDData{
 		Name:      e.CodeName,
 		FromIDVar: fromIDVar,
 		ToIDVar:   toIDVar,
 		FromNode:  fromNode,
 		ToNode:    toNode,
 	}
-	template := "// Delete{{.Name}}ByIDs deletes the edge.\n" +
+	return deleteEdgeByIDTemplate.Exec(data)
+}
+
+// DeleteEdgeByIDsData is the data of the logic/delete_edge_by_ids template.
+type DeleteEdgeByIDsData struct {
+	Name      string
+	FromIDVar string
+	ToIDVar   string
+	FromNode  string
+	ToNode    string
+}
+
+var deleteEdgeByIDsTemplate = cg.NewTemplate(cg.Template{
+	Name: "logic/delete_edge_by_ids",
+	Data: DeleteEdgeByIDsData{},
+	Text: "// Delete{{.Name}}ByIDs deletes the edge.\n" +
 		"// Auth is also respected, otherwise no action will take place.\n" +
 		"func Delete{{.Name}}ByIDs(\n" +
 		"\tconn *db.Conn,\n" +
@@ -1550,6 +1700,22 @@ func GetDeleteEdgeByIDsStr(s cg.Schema, e cg.EdgeStruct) string {
 		"\t}\n" +
 		"\t return errors.New(\"could not delete {{.Name}}: \" + {{.FromIDVar}} " +
 		" + \":\" + {{.ToIDVar}})\n" +
-		"}\n"
-	return cg.ExecTemplate(template, "edge_delete_by_ids", data, nil)
+		"}\n",
+})
+
+// GetDeleteEdgeByIDsStr deletes an edge by connected ids.
+func GetDeleteEdgeByIDsStr(s cg.Schema, e cg.EdgeStruct) (string, error) {
+	fromIDVar := strings.ToLower(string(e.FromNode.GetName()[0])) + "id"
+	toIDVar := strings.ToLower(string(e.ToNode.GetName()[0])) + "id"
+	fromNode := e.FromNode.GetName()
+	toNode := e.ToNode.GetName()
+
+	data := DeleteEdgeByIDsData{
+		Name:      e.CodeName,
+		FromIDVar: fromIDVar,
+		ToIDVar:   toIDVar,
+		FromNode:  fromNode,
+		ToNode:    toNode,
+	}
+	return deleteEdgeByIDsTemplate.Exec(data)
 }
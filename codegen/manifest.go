@@ -0,0 +1,290 @@
+// The manifest of files owned by the generator. It records what every file was
+// generated from and the hashes of its content, and is the source of truth for
+// which files are generated and whether they were edited by hand.
+
+package codegen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestVersion is the version of the manifest format.
+const ManifestVersion = 1
+
+// ManifestFile is the name of the manifest in the state dir.
+const ManifestFile = "manifest.json"
+
+// Manifest lists the files owned by the generator.
+type Manifest struct {
+	Version int             `json:"version"`
+	Files   []ManifestEntry `json:"files"`
+}
+
+// ManifestEntry is a file owned by the generator.
+type ManifestEntry struct {
+	Path             string   `json:"path"`  // Path relative to the root
+	Layer            string   `json:"layer"` // Layer the file belongs to
+	Schema           string   `json:"schema,omitempty"`
+	Edge             string   `json:"edge,omitempty"`
+	GeneratorVersion string   `json:"generator_version"`
+	Inputs           string   `json:"inputs,omitempty"`
+	Hash             string   `json:"hash"`             // See CanonicalContent
+	Merged           string   `json:"merged,omitempty"` // See IsMerged
+	ManualSections   []string `json:"manual_sections,omitempty"`
+}
+
+// newManifestEntry creates the entry of a rendered file.
+func newManifestEntry(root string, f File) ManifestEntry {
+	rel, err := filepath.Rel(root, f.Path)
+	if err != nil {
+		rel = f.Path
+	}
+	// Merged edits are still edits, so the rendered content is recorded
+	content := f.Content
+	if f.Generated != "" {
+		content = f.Generated
+	}
+	entry := ManifestEntry{
+		Path:             rel,
+		Layer:            f.Layer,
+		Schema:           f.Source.Schema,
+		Edge:             f.Source.Edge,
+		GeneratorVersion: Version,
+		Inputs:           f.Inputs,
+		Hash:             HashContent(CanonicalContent(content)),
+	}
+	for _, s := range ExtractManualSections(content) {
+		entry.ManualSections = append(entry.ManualSections,
+			HashContent(s.Content))
+	}
+	// The content of a merge without conflicts is recorded as well, so the
+	// next runs merge it again rather than take it for an edit
+	if f.Generated != "" && len(findConflicts(splitLines(f.Content))) == 0 {
+		merged := HashContent(CanonicalContent(f.Content))
+		if merged != entry.Hash {
+			entry.Merged = merged
+		}
+	}
+	return entry
+}
+
+// Matches returns whether the content on disk still has the generated content
+// recorded in the entry. Manual sections may have changed. Generators before
+// 1.1.0 hashed the content with only its manual sections left empty.
+func (e ManifestEntry) Matches(content string) bool {
+	return HashContent(CanonicalContent(content)) == e.Hash ||
+		HashContent(stripManualSectionsV1(content)) == e.Hash
+}
+
+// IsMerged returns whether the content on disk is still the content written by
+// a merge without conflicts, whose edits are then merged on every run.
+func (e ManifestEntry) IsMerged(content string) bool {
+	return e.Merged != "" && HashContent(CanonicalContent(content)) == e.Merged
+}
+
+// HashContent returns the hex encoded sha256 of the content.
+func HashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadManifest reads the manifest in the state dir. A missing manifest is
+// returned as an empty one.
+func LoadManifest(stateDir string) (Manifest, error) {
+	manifest := Manifest{Version: ManifestVersion}
+	path := filepath.Join(stateDir, ManifestFile)
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	} else if err != nil {
+		return manifest, err
+	}
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		return manifest, fmt.Errorf("invalid manifest %s: %v", path, err)
+	}
+	if manifest.Version > ManifestVersion {
+		return manifest, fmt.Errorf("manifest %s has version %d, but this "+
+			"generator only understands up to version %d", path,
+			manifest.Version, ManifestVersion)
+	}
+	return manifest, nil
+}
+
+// NewManifest creates the manifest for the rendered files. Entries of the
+// previous manifest for files that were not rendered are kept if keep returns
+// true, such as for layers that were not rendered or orphans left on disk.
+func NewManifest(
+	root string,
+	files []File,
+	previous Manifest,
+	keep func(e ManifestEntry) bool,
+) Manifest {
+	manifest := Manifest{Version: ManifestVersion}
+	rendered := map[string]bool{}
+	for _, f := range files {
+		entry := newManifestEntry(root, f)
+		rendered[entry.Path] = true
+		manifest.Files = append(manifest.Files, entry)
+	}
+	for _, e := range previous.Files {
+		if !rendered[e.Path] && keep(e) {
+			manifest.Files = append(manifest.Files, e)
+		}
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+	return manifest
+}
+
+// Without returns the manifest without the entries of the paths.
+func (m Manifest) Without(root string, paths []string) Manifest {
+	removed := map[string]bool{}
+	for _, path := range paths {
+		removed[path] = true
+	}
+	manifest := Manifest{Version: ManifestVersion}
+	for _, e := range m.Files {
+		if !removed[filepath.Join(root, e.Path)] {
+			manifest.Files = append(manifest.Files, e)
+		}
+	}
+	return manifest
+}
+
+// Owned returns the entries of the layer keyed by their path on disk. Entries
+// without a hash, from an older manifest, are left out.
+func (m Manifest) Owned(root string, layer string) map[string]ManifestEntry {
+	owned := map[string]ManifestEntry{}
+	for _, e := range m.Files {
+		if e.Hash != "" && (layer == "" || e.Layer == layer) {
+			owned[filepath.Join(root, e.Path)] = e
+		}
+	}
+	return owned
+}
+
+// File returns the manifest as a file in the state dir, so it is written along
+// with the generated files.
+func (m Manifest) File(stateDir string) (File, error) {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return File{}, err
+	}
+	return File{
+		Path:    filepath.Join(stateDir, ManifestFile),
+		Content: string(content) + "\n",
+	}, nil
+}
+
+// Orphan is a generated file on disk that no schema renders anymore.
+type Orphan struct {
+	Path       string // Path relative to the root
+	Layer      string // Layer of the file, empty if it is not in the manifest
+	ManualCode bool   // Whether the manual sections of the file contain code
+}
+
+// FindOrphans returns the files owned by the enabled layers that were not
+// rendered. Without a manifest, from before it existed, the signed files in the
+// output directories that were not rendered are orphans instead.
+func FindOrphans(
+	root string,
+	manifest Manifest,
+	layers []string,
+	dirs []string,
+	files []File,
+) ([]Orphan, error) {
+	rendered := map[string]bool{}
+	for _, f := range files {
+		rendered[f.Path] = true
+	}
+
+	paths := map[string]string{}
+	for _, e := range manifest.Files {
+		path := filepath.Join(root, e.Path)
+		if rendered[path] || !containsString(layers, e.Layer) {
+			continue
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		paths[path] = e.Layer
+	}
+	if len(manifest.Files) == 0 {
+		extra, err := FindExtraFiles(dirs, files)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range extra {
+			paths[path] = ""
+		}
+	}
+
+	orphans := []Orphan{}
+	for path, layer := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		orphans = append(orphans, Orphan{
+			Path:       rel,
+			Layer:      layer,
+			ManualCode: hasManualCode(string(content)),
+		})
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].Path < orphans[j].Path
+	})
+	return orphans, nil
+}
+
+// SplitOrphans splits the orphans into the paths to remove and the orphans
+// that are kept. Orphans with code in their manual sections are always kept.
+func SplitOrphans(
+	root string,
+	orphans []Orphan,
+	remove bool,
+) ([]string, []Orphan) {
+	paths := []string{}
+	kept := []Orphan{}
+	for _, o := range orphans {
+		if remove && !o.ManualCode {
+			paths = append(paths, filepath.Join(root, o.Path))
+		} else {
+			kept = append(kept, o)
+		}
+	}
+	return paths, kept
+}
+
+// hasManualCode returns whether any manual section contains code.
+func hasManualCode(content string) bool {
+	for _, s := range ExtractManualSections(content) {
+		if strings.TrimSpace(s.Content) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package codegen
+
+import "testing"
+
+func TestManifestEntryAcceptsCleanMerge(t *testing.T) {
+	generated := "package p\n\nfunc A() {}\n"
+	merged := "package p\n\n// Edited\nfunc A() {}\n"
+	entry := newManifestEntry("/root", File{Path: "/root/p.go",
+		Content: merged, Generated: generated})
+	owned := map[string]ManifestEntry{"/root/p.go": entry}
+
+	for content, want := range map[string]SignatureStatus{
+		generated:                SignatureValid,
+		merged:                   SignatureMerged,
+		merged + "// Edited too": SignatureMismatch,
+	} {
+		status := contentSignatureStatus("/root/p.go", content, owned)
+		if status != want {
+			t.Errorf("%q: got %s, expected %s", content, status, want)
+		}
+	}
+
+	conflicted := "package p\n\n" + ConflictStart + "\n// Edited\n" +
+		ConflictSeparator + "\n// Generated\n" + ConflictEnd + "\n"
+	entry = newManifestEntry("/root", File{Path: "/root/p.go",
+		Content: conflicted, Generated: generated})
+	if entry.Merged != "" {
+		t.Errorf("a merge with conflicts was recorded as %s", entry.Merged)
+	}
+}
@@ -0,0 +1,280 @@
+// Merging the edits made to generated files outside of their manual sections
+// with the newly generated content. The content generated by the last run is
+// kept in the state dir as the base of a three-way merge, so edits that do not
+// touch what the generator changed are carried over, and the others are left
+// to resolve between conflict markers.
+
+package codegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Conflict markers written around the edits that could not be merged. The
+// base is left out when there is no content of the last run to merge with.
+const (
+	ConflictStart     = "<<<<<<< edited"
+	ConflictBase      = "||||||| generated before"
+	ConflictSeparator = "======="
+	ConflictEnd       = ">>>>>>> generated"
+)
+
+// Conflict is a block of conflict markers in merged content.
+type Conflict struct {
+	Line  int `json:"line"`  // Line of the start marker, numbered from 1
+	Lines int `json:"lines"` // Number of lines of the block, markers included
+}
+
+// MergedFile is a file whose edits on disk were merged with the rendered
+// content.
+type MergedFile struct {
+	Path      string     // Path of the file on disk
+	Base      bool       // Whether the content of the last run was found
+	Conflicts []Conflict // Edits that could not be merged
+}
+
+// Merge merges the edits made to the base into the generated content. Changes
+// made by one side only are taken from it, and the same change made by both
+// sides once. Other changes are written between conflict markers, with the
+// edited lines first. The conflicts returned include the ones left unresolved
+// from an earlier merge.
+func Merge(base string, edited string, generated string) (string, []Conflict) {
+	return merge(splitLines(base), true, edited, generated)
+}
+
+// MergeWithoutBase merges edited and generated content when the content they
+// both come from is unknown. Every difference between them is a conflict.
+func MergeWithoutBase(edited string, generated string) (string, []Conflict) {
+	return merge(nil, false, edited, generated)
+}
+
+func merge(
+	base []string,
+	hasBase bool,
+	edited string,
+	generated string,
+) (string, []Conflict) {
+	x := splitLines(edited)
+	y := splitLines(generated)
+	if !hasBase {
+		// Without a base, only the lines of both sides are stable
+		base = x
+	}
+	xMatch := matchLines(base, x)
+	yMatch := matchLines(base, y)
+
+	m := merger{hasBase: hasBase}
+	i, a, b := 0, 0, 0
+	for {
+		// The lines of the base kept by both sides split the content into
+		// chunks, which each side may have changed
+		j := i
+		for j < len(base) && (xMatch[j] < 0 || yMatch[j] < 0) {
+			j++
+		}
+		aEnd, bEnd := len(x), len(y)
+		if j < len(base) {
+			aEnd, bEnd = xMatch[j], yMatch[j]
+		}
+		m.chunk(base[i:j], x[a:aEnd], y[b:bEnd])
+		if j == len(base) {
+			break
+		}
+		m.lines = append(m.lines, base[j])
+		i, a, b = j+1, aEnd+1, bEnd+1
+	}
+
+	content := strings.Join(m.lines, "\n")
+	if len(m.lines) > 0 && strings.HasSuffix(generated, "\n") {
+		content += "\n"
+	}
+	return content, findConflicts(m.lines)
+}
+
+// findConflicts returns the blocks of conflict markers in the lines.
+func findConflicts(lines []string) []Conflict {
+	conflicts := []Conflict{}
+	start := -1
+	for i, l := range lines {
+		switch {
+		case l == ConflictStart:
+			start = i
+		case l == ConflictEnd && start >= 0:
+			conflicts = append(conflicts, Conflict{
+				Line:  start + 1,
+				Lines: i - start + 1,
+			})
+			start = -1
+		}
+	}
+	return conflicts
+}
+
+// merger collects the lines of merged content.
+type merger struct {
+	hasBase bool
+	lines   []string
+}
+
+// chunk adds a chunk of the base as changed by each side.
+func (m *merger) chunk(base []string, edited []string, generated []string) {
+	switch {
+	case m.hasBase && equalLines(edited, base):
+		m.lines = append(m.lines, generated...)
+	case m.hasBase && equalLines(generated, base):
+		m.lines = append(m.lines, edited...)
+	case equalLines(edited, generated):
+		m.lines = append(m.lines, edited...)
+	default:
+		m.lines = append(m.lines, ConflictStart)
+		m.lines = append(m.lines, edited...)
+		if m.hasBase {
+			m.lines = append(m.lines, ConflictBase)
+			m.lines = append(m.lines, base...)
+		}
+		m.lines = append(m.lines, ConflictSeparator)
+		m.lines = append(m.lines, generated...)
+		m.lines = append(m.lines, ConflictEnd)
+	}
+}
+
+// matchLines returns the line of b each line of a is kept as, -1 for the
+// lines that were removed.
+func matchLines(a []string, b []string) []int {
+	match := make([]int, len(a))
+	x, y := 0, 0
+	for _, op := range diffOps(a, b) {
+		switch op {
+		case DiffEqual:
+			match[x] = y
+			x++
+			y++
+		case DiffDelete:
+			match[x] = -1
+			x++
+		case DiffInsert:
+			y++
+		}
+	}
+	return match
+}
+
+func equalLines(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeEdits merges the edits made outside of the manual sections of the
+// rendered files on disk with their rendered content, against the content of
+// the last run. The rendered content of the merged files is kept in
+// Generated, so the manifest still tells the edits apart. Files that still
+// have the content of an earlier merge are merged again, and the other edited
+// files only if all is set.
+func MergeEdits(
+	root string,
+	stateDir string,
+	files []File,
+	owned map[string]ManifestEntry,
+	all bool,
+) ([]File, []MergedFile, error) {
+	merged := []MergedFile{}
+	result := make([]File, 0, len(files))
+	for _, f := range files {
+		status, err := signatureStatus(f.Path, owned)
+		if err != nil {
+			return nil, nil, err
+		}
+		if status != SignatureMerged && (status != SignatureMismatch || !all) {
+			result = append(result, f)
+			continue
+		}
+		edited, err := ioutil.ReadFile(f.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		base, found, err := readBase(root, stateDir, f.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		m := MergedFile{Path: f.Path, Base: found}
+		content := ""
+		if found {
+			content, m.Conflicts = Merge(base, string(edited), f.Content)
+		} else {
+			content, m.Conflicts = MergeWithoutBase(string(edited), f.Content)
+		}
+		f.Generated = f.Content
+		f.Content = content
+		result = append(result, f)
+		merged = append(merged, m)
+	}
+	return result, merged, nil
+}
+
+// BaseFiles returns the files that keep the rendered content of the files in
+// the state dir, as the base of the next merge.
+func BaseFiles(root string, stateDir string, files []File) ([]File, error) {
+	bases := []File{}
+	for _, f := range files {
+		path, err := basePath(root, stateDir, f.Path)
+		if err != nil {
+			return nil, err
+		}
+		content := f.Content
+		if f.Generated != "" {
+			content = f.Generated
+		}
+		bases = append(bases, File{Path: path, Content: content})
+	}
+	return bases, nil
+}
+
+// ExistingBases returns the paths of the bases of the files that are on disk.
+func ExistingBases(root string, stateDir string, paths []string) []string {
+	bases := []string{}
+	for _, p := range paths {
+		path, err := basePath(root, stateDir, p)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			bases = append(bases, path)
+		}
+	}
+	return bases
+}
+
+// readBase returns the content of the file written by the last run, and
+// whether it was found.
+func readBase(root string, stateDir string, path string) (string, bool, error) {
+	base, err := basePath(root, stateDir, path)
+	if err != nil {
+		return "", false, err
+	}
+	content, err := ioutil.ReadFile(base)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	return string(content), true, nil
+}
+
+// basePath returns the path of the base of a file in the state dir.
+func basePath(root string, stateDir string, path string) (string, error) {
+	rel, err := relToRoot(root, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, baseDir, rel), nil
+}
@@ -0,0 +1,202 @@
+// Ownership of the generated files on disk. Every layer checks the files it
+// owns the same way before rendering, so edits made outside of the manual
+// sections are never overwritten unless asked to.
+
+package codegen
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CodeEdited is the code of the error of a file that was edited outside of its
+// manual sections since it was generated.
+const CodeEdited = ErrorCode("edited-outside-manual-sections")
+
+// ValidateOwnedFiles checks the files a layer owns under its directories, see
+// OwnedFiles and ValidateFiles.
+func ValidateOwnedFiles(
+	dirs []string,
+	owned map[string]ManifestEntry,
+	merge bool,
+	force bool,
+) (map[string][]ManualSection, error) {
+	paths, err := OwnedFiles(dirs, owned)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateFiles(paths, owned, merge, force)
+}
+
+// OwnedFiles returns the paths of the files a layer owns, sorted. They are the
+// files in the manifest, or without one, the signed files under the
+// directories. Hidden directories, such as the state dir, are left out. The
+// rendered files that are not among them are checked by ValidateTargetFiles.
+func OwnedFiles(
+	dirs []string,
+	owned map[string]ManifestEntry,
+) ([]string, error) {
+	paths := []string{}
+	if len(owned) > 0 {
+		for path := range owned {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		return paths, nil
+	}
+	return UntrackedFiles(dirs, owned)
+}
+
+// UntrackedFiles returns the paths of the signed files under the directories
+// that are not in owned, sorted. Hidden directories are left out.
+func UntrackedFiles(
+	dirs []string,
+	owned map[string]ManifestEntry,
+) ([]string, error) {
+	paths := []string{}
+	found := map[string]bool{}
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(
+			path string,
+			info os.FileInfo,
+			err error,
+		) error {
+			if os.IsNotExist(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != dir && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if _, ok := owned[path]; ok || found[path] {
+				return nil
+			}
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if VerifySignature(path, string(content)) != SignatureUnsigned {
+				found[path] = true
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ValidateFiles checks that the files on disk were not edited outside of their
+// manual sections since they were generated, and returns their manual
+// sections keyed by path. Files in owned are checked against the manifest,
+// the others against their signature. Every edited file is reported, unless
+// merge or force is set, as their edits are then merged or overwritten. Files
+// that still have the content of an earlier merge are merged again instead.
+func ValidateFiles(
+	paths []string,
+	owned map[string]ManifestEntry,
+	merge bool,
+	force bool,
+) (map[string][]ManualSection, error) {
+	errs := Errors{}
+	manualParts := map[string][]ManualSection{}
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			errs.Add(err, Error{File: path})
+			continue
+		}
+		manualParts[path] = ExtractManualSections(string(content))
+
+		status := contentSignatureStatus(path, string(content), owned)
+		if status == SignatureMismatch && !merge && !force {
+			errs.Add(errors.New("was edited outside of its manual sections "+
+				"since it was generated"), Error{File: path, Code: CodeEdited})
+		}
+	}
+	return manualParts, errs.Err()
+}
+
+// ValidateTargetFiles checks the files on disk that the rendered files replace
+// but that were not validated before rendering, such as a file missing from
+// the manifest, the same way as ValidateFiles. It returns the manual sections
+// of the ones with code in them, which have to be rendered again to carry them
+// over.
+func ValidateTargetFiles(
+	files []File,
+	validated map[string][]ManualSection,
+	owned map[string]ManifestEntry,
+	merge bool,
+) (map[string][]ManualSection, error) {
+	paths := []string{}
+	for _, f := range files {
+		if _, ok := validated[f.Path]; !ok {
+			paths = append(paths, f.Path)
+		}
+	}
+	manualParts, err := ValidateFiles(paths, owned, merge, false)
+	if err != nil {
+		return nil, err
+	}
+	withCode := map[string][]ManualSection{}
+	for path, sections := range manualParts {
+		for _, s := range sections {
+			if strings.TrimSpace(s.Content) != "" {
+				withCode[path] = sections
+				break
+			}
+		}
+	}
+	return withCode, nil
+}
+
+// signatureStatus returns the signature status of the file on disk.
+func signatureStatus(
+	path string,
+	owned map[string]ManifestEntry,
+) (SignatureStatus, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return SignatureNone, nil
+	} else if err != nil {
+		return "", err
+	}
+	return contentSignatureStatus(path, string(content), owned), nil
+}
+
+// contentSignatureStatus returns the signature status of the content of a
+// file. Files in the manifest are checked against it, other files against
+// their signature. A file is outdated if either says it was generated by
+// another generator, and merged if it has the content of an earlier merge.
+func contentSignatureStatus(
+	path string,
+	content string,
+	owned map[string]ManifestEntry,
+) SignatureStatus {
+	status := VerifySignature(path, content)
+	entry, ok := owned[path]
+	switch {
+	case !ok:
+		return status
+	case !entry.Matches(content) && entry.IsMerged(content):
+		return SignatureMerged
+	case !entry.Matches(content):
+		return SignatureMismatch
+	case status == SignatureOutdated || entry.GeneratorVersion != Version:
+		return SignatureOutdated
+	}
+	return SignatureValid
+}
@@ -0,0 +1,57 @@
+package codegen
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateTargetFilesChecksFilesMissingFromManifest(t *testing.T) {
+	content := Sign("a.go", "package p\n\n"+StartManualSection("code")+
+		"\n"+EndManual+"\n")
+	root, cleanup := FixtureRoot(t, map[string]string{
+		"manual.go": content[:len(content)-len(EndManual)-1] +
+			"func Kept() {}\n" + EndManual + "\n",
+		"edited.go": content + "// Edited\n",
+	})
+	defer cleanup()
+	manual := filepath.Join(root, "manual.go")
+	edited := filepath.Join(root, "edited.go")
+	files := []File{{Path: manual}, {Path: edited},
+		{Path: filepath.Join(root, "new.go")}}
+
+	_, err := ValidateTargetFiles(files, nil, nil, false)
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 1 || errs[0].File != edited ||
+		errs[0].Code != CodeEdited {
+		t.Fatalf("expected %s to be edited, got %v", edited, err)
+	}
+
+	manualParts, err := ValidateTargetFiles(files, nil, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manualParts) != 1 || len(manualParts[manual]) != 1 ||
+		manualParts[manual][0].Content != "func Kept() {}" {
+		t.Errorf("unexpected manual sections %v", manualParts)
+	}
+}
+
+func TestUntrackedFiles(t *testing.T) {
+	root, cleanup := FixtureRoot(t, map[string]string{
+		"owned.go":           Sign("owned.go", "package p\n"),
+		"untracked.go":       Sign("untracked.go", "package p\n"),
+		"hand.go":            "package p\n",
+		".codegen/base/a.go": Sign("a.go", "package p\n"),
+	})
+	defer cleanup()
+
+	untracked, err := UntrackedFiles([]string{root},
+		map[string]ManifestEntry{filepath.Join(root, "owned.go"): {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(root, "untracked.go")
+	if len(untracked) != 1 || untracked[0] != want {
+		t.Errorf("got %v, expected %s", untracked, want)
+	}
+}
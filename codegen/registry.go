@@ -0,0 +1,68 @@
+// Registry of the schemas that code is generated for, and of the privacy
+// policies schema files can refer to. Schema packages register their schemas
+// when they are initialized, so the generator only needs to import them.
+
+package codegen
+
+import "splits-go-api/privacy"
+
+var registeredSchemas = []Schema{}
+
+// Register adds schemas to the registry, usually from the init function of
+// the package that defines them. Schemas are generated in the order they are
+// registered. Registering two schemas with the same name panics.
+func Register(schemas ...Schema) {
+	for _, s := range schemas {
+		for _, r := range registeredSchemas {
+			if r.GetName() == s.GetName() {
+				panic("codegen: schema " + s.GetName() + " is registered twice")
+			}
+		}
+		registeredSchemas = append(registeredSchemas, s)
+	}
+}
+
+// RegisteredSchemas returns the registered schemas, in the order they were
+// registered.
+func RegisteredSchemas() []Schema {
+	return append([]Schema{}, registeredSchemas...)
+}
+
+var registeredPolicies = map[string]privacy.Policy{}
+
+// RegisterPolicy makes privacy policies available by name to schema files.
+// Policies used by registered schemas are available without registering them.
+func RegisterPolicy(policies ...privacy.Policy) {
+	for _, p := range policies {
+		registeredPolicies[p.GetName()] = p
+	}
+}
+
+// knownPolicies returns the registered policies, and the policies used by the
+// schemas, keyed by name.
+func knownPolicies(schemas []Schema) map[string]privacy.Policy {
+	policies := map[string]privacy.Policy{}
+	add := func(ps ...privacy.Policy) {
+		for _, p := range ps {
+			if p != nil && p.GetName() != "" {
+				policies[p.GetName()] = p
+			}
+		}
+	}
+	for _, s := range schemas {
+		add(s.GetDeletionPrivacy())
+		for _, f := range s.GetFields() {
+			add(f.Privacy, f.WritePrivacy)
+		}
+		for _, e := range s.GetEdges() {
+			add(e.Privacy, e.ReversePrivacy, e.WritePrivacy, e.DeletionPrivacy)
+			for _, f := range e.Fields {
+				add(f.Privacy, f.WritePrivacy, f.RWritePrivacy)
+			}
+		}
+	}
+	for name, p := range registeredPolicies {
+		policies[name] = p
+	}
+	return policies
+}
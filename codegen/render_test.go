@@ -0,0 +1,167 @@
+package codegen_test
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	cg "splits-go-schema-codegen/codegen"
+
+	// The built in generators register themselves when they are imported
+	_ "splits-go-schema-codegen/codegen/db"
+	_ "splits-go-schema-codegen/codegen/graphql"
+	_ "splits-go-schema-codegen/codegen/logic"
+)
+
+// syntheticSchemas creates count synthetic schemas, each with an edge to the
+// next one.
+func syntheticSchemas(count int) []*cg.DeclaredSchema {
+	declared := []*cg.DeclaredSchema{}
+	for i := 0; i < count; i++ {
+		declared = append(declared, cg.FixtureSchema(fmt.Sprintf("Bench%03d",
+			i)))
+	}
+
+	for i, s := range declared {
+		to := declared[(i+1)%count]
+		cg.AddFixtureEdge(s, to, s.Name+"To"+to.Name, true)
+	}
+	return declared
+}
+
+// schemaList returns the declared schemas as schemas.
+func schemaList(declared []*cg.DeclaredSchema) []cg.Schema {
+	schemas := []cg.Schema{}
+	for _, s := range declared {
+		schemas = append(schemas, s)
+	}
+	return schemas
+}
+
+// render renders every file of the schemas with the workers.
+func render(schemas []cg.Schema, workers int) ([]cg.File, error) {
+	config := cg.DefaultConfig()
+	config.Root = "bench"
+	config.Workers = workers
+	return cg.RenderFiles(config, schemas, cg.Filter{},
+		map[string][]cg.ManualSection{})
+}
+
+// BenchmarkRender compares the render of a few hundred schemas on pools of
+// workers of different sizes, which all have to render the same files.
+func BenchmarkRender(b *testing.B) {
+	schemas := schemaList(syntheticSchemas(300))
+	expected, err := render(schemas, 1)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	counts := map[int]bool{1: true, 2: true, 4: true, 8: true,
+		runtime.GOMAXPROCS(0): true}
+	workers := []int{}
+	for w := range counts {
+		workers = append(workers, w)
+	}
+	sort.Ints(workers)
+	for _, w := range workers {
+		w := w
+		b.Run(fmt.Sprintf("workers=%d", w), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				files, err := render(schemas, w)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(files) != len(expected) {
+					b.Fatalf("rendered %d files, expected %d", len(files),
+						len(expected))
+				}
+			}
+		})
+	}
+}
+
+// TestRenderIsDeterministic renders schemas that several edges point to many
+// times, as the edge pointers are kept in a map whose order changes on every
+// iteration, and the files have to be the same for their signatures to be.
+func TestRenderIsDeterministic(t *testing.T) {
+	declared := syntheticSchemas(4)
+	hub := cg.FixtureSchema("Hub")
+	for _, s := range declared {
+		cg.AddFixtureEdge(s, hub, s.Name+"ToHub", false)
+	}
+	schemas := schemaList(append(declared, hub))
+	if len(hub.GetEdgePointers()) != len(declared) {
+		t.Fatalf("the hub has %d edge pointers, expected %d",
+			len(hub.GetEdgePointers()), len(declared))
+	}
+	err := cg.ValidateSchemas(schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := render(schemas, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for run := 0; run < 20; run++ {
+		files, err := render(schemas, 1+run%4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != len(expected) {
+			t.Fatalf("run %d rendered %d files, expected %d", run, len(files),
+				len(expected))
+		}
+		for i, f := range files {
+			if f.Path != expected[i].Path || f.Content != expected[i].Content {
+				t.Fatalf("run %d rendered %s differently", run, f.Path)
+			}
+		}
+	}
+}
+
+// TestRenderOppositeGraphQLEdges renders two edges between the same schemas in
+// opposite directions, both with their reverse, which share the resolver types
+// of each direction and have a dataloader key each.
+func TestRenderOppositeGraphQLEdges(t *testing.T) {
+	user, group := cg.FixtureSchema("User"), cg.FixtureSchema("Group")
+	cg.AddFixtureEdge(user, group, "UserToGroup", true)
+	cg.AddFixtureEdge(group, user, "GroupToMember", true)
+	schemas := schemaList([]*cg.DeclaredSchema{user, group})
+	err := cg.ValidateSchemas(schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := render(schemas, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edgeFiles := 0
+	for _, f := range files {
+		if strings.Contains(f.Path, "type_edge_") {
+			edgeFiles++
+		}
+		for _, key := range []string{"UserUserToGroup",
+			"UserReverseGroupToMember", "GroupGroupToMember",
+			"GroupReverseUserToGroup"} {
+			if strings.HasSuffix(f.Path, "dataloader_batcher.go") &&
+				!strings.Contains(f.Content, `case "`+key+`":`) {
+				t.Errorf("the dataloader has no case for %s", key)
+			}
+		}
+		for _, connection := range []string{"UserToGroup", "GroupToUser"} {
+			count := strings.Count(f.Content, "type "+connection+
+				"Connection {")
+			if strings.HasSuffix(f.Path, "schema.go") && count != 1 {
+				t.Errorf("the graphql schema declares %sConnection %d times",
+					connection, count)
+			}
+		}
+	}
+	if edgeFiles != 2 {
+		t.Errorf("rendered %d edge resolver files, expected 2", edgeFiles)
+	}
+}
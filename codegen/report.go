@@ -0,0 +1,124 @@
+// The report of a generation run, listing what happened to every file. It is
+// marshalled as the json report of the generate command.
+
+package codegen
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report lists what a generation run did with the files. The path lists are
+// the paths on disk, the orphans and file reports are relative to the root.
+type Report struct {
+	GeneratorVersion string        `json:"generator_version"`
+	Files            []FileReport  `json:"files"`
+	Layers           []LayerReport `json:"layers"`
+
+	Generated []string     `json:"-"` // Files that were written
+	Unchanged []string     `json:"-"` // Files rendered with the same content
+	Skipped   []string     `json:"-"` // Files not rendered, as the inputs are the same
+	Removed   []string     `json:"-"` // Orphaned files that were removed
+	Orphans   []Orphan     `json:"-"` // Orphaned files, removed or not
+	Merged    []MergedFile `json:"-"` // Files whose edits were merged
+	Output    []File       `json:"-"` // Every file with its content after the run
+}
+
+// FileAction is what a generation run did with a file.
+type FileAction string
+
+// Actions of a generation run.
+const (
+	ActionCreated    = FileAction("created")     // Written, new on disk
+	ActionUpdated    = FileAction("updated")     // Written over the disk
+	ActionUnchanged  = FileAction("unchanged")   // Same as on disk
+	ActionSkipped    = FileAction("skipped")     // Not rendered
+	ActionOrphaned   = FileAction("orphaned")    // No longer rendered
+	ActionKeptOrphan = FileAction("kept-orphan") // With manual code
+	ActionMerged     = FileAction("merged")      // Written with edits merged
+)
+
+// SignatureStatus is whether a file on disk still has the content it was
+// generated with, before the run wrote it.
+type SignatureStatus string
+
+// Signature statuses of a file on disk.
+const (
+	SignatureValid    = SignatureStatus("valid")    // Not edited by hand
+	SignatureOutdated = SignatureStatus("outdated") // By another generator
+	SignatureMismatch = SignatureStatus("mismatch") // Edited by hand
+	SignatureMerged   = SignatureStatus("merged")   // Merged by the last run
+	SignatureUnsigned = SignatureStatus("unsigned") // Not owned nor signed
+	SignatureNone     = SignatureStatus("none")     // Not on disk
+)
+
+// FileReport is what a generation run did with a file. Files written over the
+// disk were edited by hand if their signature is a mismatch, which only a
+// merge or force run does. An orphan whose manual sections contain code is
+// kept-orphan, as it is never removed. A merged file lists the conflicts
+// left in it.
+type FileReport struct {
+	Path           string          `json:"path"` // Path relative to the root
+	Layer          string          `json:"layer,omitempty"`
+	Schema         string          `json:"schema,omitempty"`
+	Edge           string          `json:"edge,omitempty"`
+	Action         FileAction      `json:"action"`
+	Signature      SignatureStatus `json:"signature"`
+	ManualSections int             `json:"manual_sections"` // Non-empty ones
+	Removed        bool            `json:"removed,omitempty"`
+	Conflicts      []Conflict      `json:"conflicts,omitempty"`
+}
+
+// LayerReport is how long a layer took to validate and render, and how many
+// of its files were rendered.
+type LayerReport struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration_ns"`
+	Rendered int           `json:"rendered"`
+	Skipped  int           `json:"skipped"`
+}
+
+// countManualCode returns the number of manual sections that contain code.
+func countManualCode(content string) int {
+	count := 0
+	for _, s := range ExtractManualSections(content) {
+		if strings.TrimSpace(s.Content) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// newFileReport creates the report of a file before it is written. The action
+// is set once it is known whether the file was written.
+func newFileReport(
+	root string,
+	f File,
+	owned map[string]ManifestEntry,
+) (FileReport, error) {
+	rel, err := filepath.Rel(root, f.Path)
+	if err != nil {
+		rel = f.Path
+	}
+	signature, err := signatureStatus(f.Path, owned)
+	if err != nil {
+		return FileReport{}, err
+	}
+	return FileReport{
+		Path:           rel,
+		Layer:          f.Layer,
+		Schema:         f.Source.Schema,
+		Edge:           f.Source.Edge,
+		Signature:      signature,
+		ManualSections: countManualCode(f.Content),
+	}, nil
+}
+
+// sortFileReports sorts the file reports by path.
+func sortFileReports(files []FileReport) {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+}
@@ -2,7 +2,10 @@
 
 package codegen
 
-import "splits-go-api/privacy"
+import (
+	"sort"
+	"splits-go-api/privacy"
+)
 
 // Schema interface for code generation.
 type Schema interface {
@@ -14,3 +17,27 @@ type Schema interface {
 	GetDeletionPrivacy() privacy.Policy
 	GetGraphQLNode() *GraphQLNode
 }
+
+// SortedEdgePointers returns the edges to the schema sorted by code name, and
+// by their key in the map for edges with the same code name. The order of the
+// map is random, and the generated code has to be the same on every run for
+// its signature to be.
+func SortedEdgePointers(s Schema) []EdgeStruct {
+	pointers := s.GetEdgePointers()
+	keys := make([]string, 0, len(pointers))
+	for key := range pointers {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := pointers[keys[i]].CodeName, pointers[keys[j]].CodeName
+		if a != b {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+	edges := make([]EdgeStruct, 0, len(keys))
+	for _, key := range keys {
+		edges = append(edges, pointers[key])
+	}
+	return edges
+}
@@ -0,0 +1,542 @@
+// Loading schemas from declarative json or yaml files, as an alternative to
+// defining them in go. The format maps onto FieldStruct, EdgeStruct,
+// EdgeFieldStruct and the graphql structs, with privacy policies referred to by
+// name.
+
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"splits-go-api/privacy"
+)
+
+// SchemaFileVersion is the version of the schema file format.
+const SchemaFileVersion = 1
+
+// DeclaredSchema is a schema loaded from a schema file.
+type DeclaredSchema struct {
+	Name            string
+	Fields          []FieldStruct
+	Edges           []EdgeStruct
+	EdgePointers    map[string]EdgeStruct
+	DeletionPrivacy privacy.Policy
+	GraphQLNode     *GraphQLNode
+	File            string // Path of the file the schema was loaded from
+}
+
+// GetName returns the name of the schema.
+func (s *DeclaredSchema) GetName() string { return s.Name }
+
+// GetFields returns the fields of the schema.
+func (s *DeclaredSchema) GetFields() []FieldStruct { return s.Fields }
+
+// GetEdges returns the edges from the schema.
+func (s *DeclaredSchema) GetEdges() []EdgeStruct { return s.Edges }
+
+// GetEdgePointers returns the edges to the schema, keyed by their code name.
+func (s *DeclaredSchema) GetEdgePointers() map[string]EdgeStruct {
+	return s.EdgePointers
+}
+
+// AddEdgePointer adds an edge to the schema.
+func (s *DeclaredSchema) AddEdgePointer(e EdgeStruct) {
+	s.EdgePointers[e.CodeName] = e
+}
+
+// GetDeletionPrivacy returns the privacy policy for deleting a node.
+func (s *DeclaredSchema) GetDeletionPrivacy() privacy.Policy {
+	return s.DeletionPrivacy
+}
+
+// GetGraphQLNode returns the graphql node of the schema, nil if it is not
+// exposed.
+func (s *DeclaredSchema) GetGraphQLNode() *GraphQLNode { return s.GraphQLNode }
+
+// SchemaFileError is an error at a line of a schema file.
+type SchemaFileError struct {
+	Path   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e SchemaFileError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Msg)
+}
+
+// SchemaFileErrors are all the errors found in the schema files.
+type SchemaFileErrors []SchemaFileError
+
+func (e SchemaFileErrors) Error() string {
+	lines := []string{}
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LoadSchemaFiles loads the schemas in the files matching the glob patterns.
+// Edges may point to the known schemas, which are usually the registered ones,
+// or to schemas in any of the files. The known schemas are returned first,
+// followed by the loaded ones in the order of the files.
+func LoadSchemaFiles(patterns []string, known []Schema) ([]Schema, error) {
+	paths := []string{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema file pattern %s: %v",
+				pattern, err)
+		}
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+
+	l := schemaLoader{
+		schemas:  map[string]Schema{},
+		policies: knownPolicies(known),
+	}
+	for _, s := range known {
+		l.schemas[s.GetName()] = s
+	}
+	loaded := []*DeclaredSchema{}
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		r := &schemaFileReader{loader: &l, path: path, content: content}
+		loaded = append(loaded, r.read()...)
+	}
+	l.resolveEdges()
+	if len(l.errs) > 0 {
+		sort.SliceStable(l.errs, func(i, j int) bool {
+			a, b := l.errs[i], l.errs[j]
+			if a.Path != b.Path {
+				return a.Path < b.Path
+			} else if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Column < b.Column
+		})
+		return nil, l.errs
+	}
+
+	schemas := append([]Schema{}, known...)
+	for _, s := range loaded {
+		schemas = append(schemas, s)
+	}
+	return schemas, nil
+}
+
+// schemaLoader holds the state shared by the schema files being loaded.
+type schemaLoader struct {
+	schemas  map[string]Schema
+	policies map[string]privacy.Policy
+	edges    []pendingEdge
+	errs     SchemaFileErrors
+}
+
+// pendingEdge is an edge whose to node is resolved once every file is read.
+type pendingEdge struct {
+	reader *schemaFileReader
+	schema *DeclaredSchema
+	index  int
+	to     string
+	offset int64
+}
+
+func (l *schemaLoader) resolveEdges() {
+	for _, p := range l.edges {
+		to, ok := l.schemas[p.to]
+		if !ok {
+			p.reader.errorf(p.offset, "edge points to unknown schema %q", p.to)
+			continue
+		}
+		p.schema.Edges[p.index].ToNode = to
+	}
+}
+
+// schemaFileReader reads the schemas of a single file, collecting the errors.
+type schemaFileReader struct {
+	loader  *schemaLoader
+	path    string
+	content []byte
+}
+
+func (r *schemaFileReader) errorf(offset int64, format string, a ...interface{}) {
+	line, column := position(r.content, offset)
+	r.loader.errs = append(r.loader.errs, SchemaFileError{
+		Path:   r.path,
+		Line:   line,
+		Column: column,
+		Msg:    fmt.Sprintf(format, a...),
+	})
+}
+
+func (r *schemaFileReader) read() []*DeclaredSchema {
+	parse := parseJSON
+	if isYAMLFile(r.path) {
+		parse = parseYAML
+	}
+	root, err := parse(r.content)
+	if err != nil {
+		offset := int64(0)
+		if e, ok := err.(jsonError); ok {
+			offset = e.offset
+		}
+		r.errorf(offset, "%s", err.Error())
+		return nil
+	}
+
+	file := r.object(root, "schema file", "version", "schemas")
+	if file == nil {
+		return nil
+	}
+	version, ok := file.values["version"]
+	if !ok {
+		r.errorf(root.offset, "schema file has no version")
+		return nil
+	}
+	n, ok := version.value.(json.Number)
+	if !ok || n.String() != fmt.Sprint(SchemaFileVersion) {
+		r.errorf(version.offset, "unsupported schema file version, this "+
+			"generator only understands version %d", SchemaFileVersion)
+		return nil
+	}
+
+	schemas := []*DeclaredSchema{}
+	for _, v := range r.array(file, "schemas") {
+		s := r.schema(v)
+		if s == nil || s.Name == "" {
+			continue
+		}
+		if _, ok := r.loader.schemas[s.Name]; ok {
+			r.errorf(v.offset, "schema %q is defined more than once", s.Name)
+			continue
+		}
+		r.loader.schemas[s.Name] = s
+		schemas = append(schemas, s)
+	}
+	return schemas
+}
+
+func (r *schemaFileReader) schema(v *jsonValue) *DeclaredSchema {
+	o := r.object(v, "schema", "name", "fields", "edges", "deletion_privacy",
+		"graphql")
+	if o == nil {
+		return nil
+	}
+	s := &DeclaredSchema{
+		Name:            r.requiredString(o, v, "name"),
+		Fields:          []FieldStruct{},
+		Edges:           []EdgeStruct{},
+		EdgePointers:    map[string]EdgeStruct{},
+		DeletionPrivacy: r.policy(o, "deletion_privacy"),
+		File:            r.path,
+	}
+	for _, f := range r.array(o, "fields") {
+		s.Fields = append(s.Fields, r.field(f))
+	}
+	for _, e := range r.array(o, "edges") {
+		s.Edges = append(s.Edges, r.edge(e, s))
+	}
+	if g, ok := o.values["graphql"]; ok {
+		s.GraphQLNode = r.graphQLNode(g)
+	}
+	return s
+}
+
+func (r *schemaFileReader) field(v *jsonValue) FieldStruct {
+	f := *Field()
+	o := r.object(v, "field", "name", "code_name", "type", "default_value",
+		"example_value", "unique", "indexed", "privacy", "write_privacy",
+		"graphql", "can_order_by")
+	if o == nil {
+		return f
+	}
+	f.Name = r.requiredString(o, v, "name")
+	f.CodeName = r.requiredString(o, v, "code_name")
+	f.Type = r.fieldType(o, v)
+	f.DefaultValue = r.string(o, "default_value")
+	f.ExampleValue = r.string(o, "example_value")
+	f.Unique = r.bool(o, "unique")
+	f.Indexed = r.bool(o, "indexed")
+	f.Privacy = r.policy(o, "privacy")
+	f.WritePrivacy = r.policy(o, "write_privacy")
+	f.CanOrderBy = r.bool(o, "can_order_by")
+	if g, ok := o.values["graphql"]; ok {
+		gqlField := r.graphQLField(g)
+		f.GQLField = &gqlField
+	}
+	return f
+}
+
+func (r *schemaFileReader) edge(v *jsonValue, from *DeclaredSchema) EdgeStruct {
+	e := *Edge()
+	o := r.object(v, "edge", "name", "code_name", "fields", "to",
+		"forwards_name", "backwards_name", "privacy", "reverse_privacy",
+		"write_privacy", "deletion_privacy", "graphql")
+	if o == nil {
+		return e
+	}
+	e.Name = r.requiredString(o, v, "name")
+	e.CodeName = r.requiredString(o, v, "code_name")
+	e.FromNode = from
+	e.ForwardsName = r.string(o, "forwards_name")
+	e.BackwardsName = r.string(o, "backwards_name")
+	e.Privacy = r.policy(o, "privacy")
+	e.ReversePrivacy = r.policy(o, "reverse_privacy")
+	e.WritePrivacy = r.policy(o, "write_privacy")
+	e.DeletionPrivacy = r.policy(o, "deletion_privacy")
+	for _, f := range r.array(o, "fields") {
+		e.Fields = append(e.Fields, r.edgeField(f))
+	}
+	if g, ok := o.values["graphql"]; ok {
+		gqlEdge := r.graphQLEdge(g)
+		e.GQLEdge = &gqlEdge
+	}
+
+	// The to node may be defined in a file that is read later
+	to := r.requiredString(o, v, "to")
+	if to != "" {
+		r.loader.edges = append(r.loader.edges, pendingEdge{
+			reader: r,
+			schema: from,
+			index:  len(from.Edges),
+			to:     to,
+			offset: o.values["to"].offset,
+		})
+	}
+	return e
+}
+
+func (r *schemaFileReader) edgeField(v *jsonValue) EdgeFieldStruct {
+	f := *EdgeField()
+	o := r.object(v, "edge field", "name", "code_name", "type",
+		"default_value", "example_value", "unique", "indexed", "privacy",
+		"write_privacy", "reverse_write_privacy", "graphql")
+	if o == nil {
+		return f
+	}
+	f.Name = r.requiredString(o, v, "name")
+	f.CodeName = r.requiredString(o, v, "code_name")
+	f.Type = r.fieldType(o, v)
+	f.DefaultValue = r.string(o, "default_value")
+	f.ExampleValue = r.string(o, "example_value")
+	f.Unique = r.bool(o, "unique")
+	f.Indexed = r.bool(o, "indexed")
+	f.Privacy = r.policy(o, "privacy")
+	f.WritePrivacy = r.policy(o, "write_privacy")
+	f.RWritePrivacy = r.policy(o, "reverse_write_privacy")
+	if g, ok := o.values["graphql"]; ok {
+		gqlField := r.graphQLField(g)
+		f.GQLField = &gqlField
+	}
+	return f
+}
+
+func (r *schemaFileReader) graphQLNode(v *jsonValue) *GraphQLNode {
+	o := r.object(v, "graphql node", "name", "description", "code_name",
+		"fields", "edges")
+	if o == nil {
+		return nil
+	}
+	n := &GraphQLNode{
+		Name:        r.requiredString(o, v, "name"),
+		Description: r.string(o, "description"),
+		CodeName:    r.requiredString(o, v, "code_name"),
+		Fields:      []GraphQLField{},
+		Edges:       []GraphQLEdge{},
+	}
+	for _, f := range r.array(o, "fields") {
+		n.Fields = append(n.Fields, r.graphQLField(f))
+	}
+	for _, e := range r.array(o, "edges") {
+		n.Edges = append(n.Edges, r.graphQLEdge(e))
+	}
+	return n
+}
+
+func (r *schemaFileReader) graphQLField(v *jsonValue) GraphQLField {
+	f := GraphQLField{}
+	o := r.object(v, "graphql field", "name", "type", "description",
+		"code_name", "code_type")
+	if o == nil {
+		return f
+	}
+	f.Name = r.requiredString(o, v, "name")
+	f.Type = r.requiredString(o, v, "type")
+	f.Description = r.string(o, "description")
+	f.CodeName = r.requiredString(o, v, "code_name")
+	f.CodeType = r.requiredString(o, v, "code_type")
+	return f
+}
+
+func (r *schemaFileReader) graphQLEdge(v *jsonValue) GraphQLEdge {
+	e := GraphQLEdge{}
+	values := map[string]*string{
+		"from":                       &e.From,
+		"to":                         &e.To,
+		"field_name":                 &e.FieldName,
+		"field_code_name":            &e.FieldCodeName,
+		"field_resolve_name":         &e.FieldResolveName,
+		"total_name":                 &e.TotalName,
+		"reverse_field_name":         &e.ReverseFieldName,
+		"reverse_field_code_name":    &e.ReverseFieldCodeName,
+		"reverse_field_resolve_name": &e.ReverseFieldResolveName,
+		"description":                &e.Description,
+		"reverse_description":        &e.ReverseDescription,
+		"from_code_name":             &e.FromCodeName,
+		"to_code_name":               &e.ToCodeName,
+		"edge_code_name":             &e.EdgeCodeName,
+		"order_by":                   &e.OrderBy,
+		"reverse_order_by":           &e.ReverseOrderBy,
+	}
+	required := []string{"from", "to", "field_name", "edge_code_name"}
+	keys := []string{"fields", "include_reverse"}
+	names := []string{}
+	for key := range values {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	o := r.object(v, "graphql edge", append(keys, names...)...)
+	if o == nil {
+		return e
+	}
+
+	// In a fixed order, so the errors are reported in the same order
+	for _, key := range names {
+		target := values[key]
+		if containsString(required, key) {
+			*target = r.requiredString(o, v, key)
+		} else {
+			*target = r.string(o, key)
+		}
+	}
+	e.IncludeReverse = r.bool(o, "include_reverse")
+	for _, f := range r.array(o, "fields") {
+		e.Fields = append(e.Fields, r.graphQLField(f))
+	}
+	return e
+}
+
+// object checks that the value is an object with only the allowed keys.
+func (r *schemaFileReader) object(
+	v *jsonValue,
+	what string,
+	allowed ...string,
+) *jsonObject {
+	o, ok := v.value.(*jsonObject)
+	if !ok {
+		r.errorf(v.offset, "%s must be an object", what)
+		return nil
+	}
+	for _, key := range o.keys {
+		if !containsString(allowed, key) {
+			sort.Strings(allowed)
+			r.errorf(o.keyOffsets[key], "unknown key %q in %s (valid keys are "+
+				"%s)", key, what, strings.Join(allowed, ", "))
+		}
+	}
+	return o
+}
+
+func (r *schemaFileReader) array(o *jsonObject, key string) []*jsonValue {
+	v, ok := o.values[key]
+	if !ok {
+		return nil
+	}
+	array, ok := v.value.([]*jsonValue)
+	if !ok {
+		r.errorf(v.offset, "%s must be an array", key)
+		return nil
+	}
+	return array
+}
+
+func (r *schemaFileReader) string(o *jsonObject, key string) string {
+	v, ok := o.values[key]
+	if !ok {
+		return ""
+	}
+	s, ok := v.value.(string)
+	if !ok && v.plain != "" {
+		// An unquoted yaml number or bool, such as a default value
+		s, ok = v.plain, true
+	}
+	if !ok {
+		r.errorf(v.offset, "%s must be a string", key)
+	}
+	return s
+}
+
+func (r *schemaFileReader) requiredString(
+	o *jsonObject,
+	parent *jsonValue,
+	key string,
+) string {
+	if _, ok := o.values[key]; !ok {
+		r.errorf(parent.offset, "missing required key %q", key)
+		return ""
+	}
+	s := r.string(o, key)
+	if s == "" {
+		r.errorf(o.values[key].offset, "%s must not be empty", key)
+	}
+	return s
+}
+
+func (r *schemaFileReader) bool(o *jsonObject, key string) bool {
+	v, ok := o.values[key]
+	if !ok {
+		return false
+	}
+	b, ok := v.value.(bool)
+	if !ok {
+		r.errorf(v.offset, "%s must be true or false", key)
+	}
+	return b
+}
+
+func (r *schemaFileReader) fieldType(o *jsonObject, parent *jsonValue) FieldType {
+	t := FieldType(r.requiredString(o, parent, "type"))
+	if t.IsValid() {
+		return t
+	}
+	if t != "" {
+		r.errorf(o.values["type"].offset, "unknown type %q (valid types are "+
+			"%s, %s, %s, %s)", t, StringType, FloatType, IntType, BoolType)
+	}
+	return t
+}
+
+// policy looks up a privacy policy by name. A missing policy is left unset,
+// as it is by the builders.
+func (r *schemaFileReader) policy(o *jsonObject, key string) privacy.Policy {
+	name := r.string(o, key)
+	if name == "" {
+		return privacy.PolicyStruct{}
+	}
+	p, ok := r.loader.policies[name]
+	if !ok && len(r.loader.policies) == 0 {
+		r.errorf(o.values[key].offset, "unknown privacy policy %q, no "+
+			"policies are registered", name)
+		return privacy.PolicyStruct{}
+	} else if !ok {
+		names := []string{}
+		for n := range r.loader.policies {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		r.errorf(o.values[key].offset, "unknown privacy policy %q (known "+
+			"policies are %s)", name, strings.Join(names, ", "))
+		return privacy.PolicyStruct{}
+	}
+	return p
+}
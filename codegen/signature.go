@@ -0,0 +1,207 @@
+// Signatures of generated files, used to detect files that were edited by
+// hand. Go files are signed in a comment on their first line, JSON files in a
+// key leading their top level object. Files of other kinds are not signed.
+//
+// A signature is the sha256 of the canonical content of the file, along with
+// the version of the generator and the hash of the templates that rendered it:
+//
+//	// @SignedSource sha256:<hash> generator:1.1.0 templates:<hash>
+//
+// Generators before 1.1.0 signed files with an md5, as (<hash>). Those
+// signatures are still verified, so the files they sign are told apart from
+// files edited by hand.
+
+package codegen
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SignatureHeader starts the first line of every signed go file.
+const SignatureHeader = "// @SignedSource"
+
+// jsonSignatureKey is the key of the signature in a signed json file.
+const jsonSignatureKey = `"@SignedSource"`
+
+// Algorithms of the signatures.
+const (
+	signatureAlgorithm       = "sha256"
+	legacySignatureAlgorithm = "md5"
+)
+
+// jsonSignatureExtractor matches the line of the signature of a signed json
+// file, the second one.
+var jsonSignatureExtractor = regexp.MustCompile(`^  "@SignedSource": "([^"]*)",$`)
+
+// legacySignatureExtractor matches the md5 signature of an older generator.
+var legacySignatureExtractor = regexp.MustCompile(`^\(?([a-zA-Z0-9]+)\)?$`)
+
+// Signature is the signature of a generated file.
+type Signature struct {
+	Algorithm string // sha256, or md5 for the signatures of older generators
+	Hash      string // Hash of the canonical content of the file
+	Generator string // Version of the generator that signed the file
+	Templates string // Hash of the templates the file was rendered with
+}
+
+// NewSignature returns the signature of the content by the running generator.
+func NewSignature(content string) Signature {
+	return Signature{
+		Algorithm: signatureAlgorithm,
+		Hash:      HashContent(CanonicalContent(content)),
+		Generator: Version,
+		Templates: TemplateSetHash(),
+	}
+}
+
+// String returns the signature as it is written in a file.
+func (s Signature) String() string {
+	if s.Algorithm == legacySignatureAlgorithm {
+		return "(" + s.Hash + ")"
+	}
+	return s.Algorithm + ":" + s.Hash + " generator:" + s.Generator +
+		" templates:" + s.Templates
+}
+
+// Matches returns whether the signature is the one of the content, the file
+// without its signature.
+func (s Signature) Matches(content string) bool {
+	switch s.Algorithm {
+	case signatureAlgorithm:
+		return s.Hash == HashContent(CanonicalContent(content))
+	case legacySignatureAlgorithm:
+		// The md5 was computed over the writer output before its printf
+		// escaping was resolved, which only the %s of the logic and graphql
+		// layers needed
+		content = stripManualSectionsV1(content)
+		escaped := strings.Replace(content, "%s", "%%s", -1)
+		return s.Hash == md5Hex(escaped) || s.Hash == md5Hex(content)
+	}
+	return false
+}
+
+// Outdated returns whether the signature was written by another version of
+// the generator, or with other templates, than the running one.
+func (s Signature) Outdated() bool {
+	return s.Generator != Version || s.Templates != TemplateSetHash()
+}
+
+// Sign adds the signature of the content to a generated file, by the kind of
+// file its path names.
+func Sign(path string, content string) string {
+	signature := NewSignature(content).String()
+	switch filepath.Ext(path) {
+	case ".go":
+		return SignatureHeader + " " + signature + "\n" + content
+	case ".json":
+		// An empty object has no line to add the key to
+		if !strings.HasPrefix(content, "{\n") ||
+			strings.HasPrefix(content, "{\n}") {
+			return content
+		}
+		return "{\n  " + jsonSignatureKey + ": \"" + signature + "\",\n" +
+			content[len("{\n"):]
+	}
+	return content
+}
+
+// VerifySignature checks the signature of a generated file against its
+// content. A file edited outside of its manual sections is a mismatch, and an
+// unedited file signed by another generator or with other templates is
+// outdated.
+func VerifySignature(path string, content string) SignatureStatus {
+	signature, content, signed := ParseSignature(path, content)
+	switch {
+	case !signed:
+		return SignatureUnsigned
+	case !signature.Matches(content):
+		return SignatureMismatch
+	case signature.Outdated():
+		return SignatureOutdated
+	}
+	return SignatureValid
+}
+
+// ParseSignature returns the signature of a signed file, and its content
+// without it. A signature of an unknown algorithm never matches.
+func ParseSignature(
+	path string,
+	content string,
+) (signature Signature, rest string, signed bool) {
+	value, rest, signed := splitSignature(path, content)
+	if !signed {
+		return Signature{}, content, false
+	}
+	if match := legacySignatureExtractor.FindStringSubmatch(value); match != nil {
+		signature.Algorithm = legacySignatureAlgorithm
+		signature.Hash = match[1]
+		return signature, rest, true
+	}
+	for i, field := range strings.Fields(value) {
+		key, value := field, ""
+		if j := strings.Index(field, ":"); j >= 0 {
+			key, value = field[:j], field[j+1:]
+		}
+		switch {
+		case i == 0:
+			signature.Algorithm, signature.Hash = key, value
+		case key == "generator":
+			signature.Generator = value
+		case key == "templates":
+			signature.Templates = value
+		}
+	}
+	return signature, rest, true
+}
+
+// splitSignature returns the signature of a signed file as it is written, and
+// the content of the file without it.
+func splitSignature(
+	path string,
+	content string,
+) (signature string, rest string, signed bool) {
+	index := strings.Index(content, "\n") + 1
+	firstLine := content[:index]
+	switch filepath.Ext(path) {
+	case ".go":
+		if !strings.HasPrefix(firstLine, SignatureHeader) {
+			return "", content, false
+		}
+		signature = strings.TrimSpace(firstLine[len(SignatureHeader):])
+		return signature, content[index:], true
+	case ".json":
+		end := strings.Index(content[index:], "\n") + 1
+		if strings.TrimSpace(firstLine) != "{" || end == 0 {
+			return "", content, false
+		}
+		line := strings.TrimRight(content[index:index+end], "\r\n")
+		match := jsonSignatureExtractor.FindStringSubmatch(line)
+		if match == nil {
+			return "", content, false
+		}
+		return match[1], firstLine + content[index+end:], true
+	}
+	return "", content, false
+}
+
+// CanonicalContent returns the form of the content signatures and the
+// manifest cover. The manual sections are left empty, lines end with \n, and
+// trailing whitespace is dropped, so neither an edit of the manual sections
+// nor a checkout that changes line endings is taken for a hand edit.
+func CanonicalContent(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+	return StripManualSections(strings.Join(lines, "\n"))
+}
+
+// md5Hex returns the hex encoded md5 of the content.
+func md5Hex(content string) string {
+	sum := md5.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,295 @@
+// Writing generated files all at once, through a staging area, with a backup
+// of the replaced files so a run can be undone.
+
+package codegen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Directories and files inside the state dir.
+const (
+	stagingDir     = "staging"
+	backupDir      = "backup"
+	backupManifest = "backup.json"
+	baseDir        = "base" // Content of the last run, see MergeEdits
+)
+
+// BackupEntry records a file that was written or removed by a run, and
+// whether it existed before.
+type BackupEntry struct {
+	Path    string `json:"path"`              // Path relative to the root
+	Existed bool   `json:"existed"`           // Whether the file was replaced
+	Removed bool   `json:"removed,omitempty"` // Whether the file was removed
+}
+
+// Backup lists the files written by the last run.
+type Backup struct {
+	Files []BackupEntry `json:"files"`
+}
+
+// WriteFiles writes the files and removes the paths under the root as a whole,
+// and returns the paths of the files that were written. Files whose content on
+// disk is already the same are not written again, so their modification time
+// is kept. Every file is first written to a staging area in the state dir,
+// then the files on disk are moved to a backup and the staged files are moved
+// into place. If anything fails, the files already moved are put back, so the
+// root is left as it was. The previous backup is only replaced if anything is
+// written or removed.
+func WriteFiles(
+	root string,
+	stateDir string,
+	files []File,
+	remove []string,
+) ([]string, error) {
+	staging := filepath.Join(stateDir, stagingDir)
+	backup := filepath.Join(stateDir, backupDir)
+	err := os.RemoveAll(staging)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	// A path written twice would overwrite its own backup, so the file that
+	// was replaced could not be put back
+	sources := map[string]string{}
+	for _, f := range files {
+		rel, err := relToRoot(root, f.Path)
+		if err != nil {
+			return nil, err
+		}
+		err = addSource(sources, rel, f.describeSource())
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range remove {
+		rel, err := relToRoot(root, path)
+		if err != nil {
+			return nil, err
+		}
+		err = addSource(sources, rel, "the files to remove")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Stage every changed file, nothing under the root is touched yet
+	written := []string{}
+	entries := []BackupEntry{}
+	for _, f := range files {
+		rel, err := relToRoot(root, f.Path)
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadFile(f.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil && string(content) == f.Content {
+			continue
+		}
+		staged := f
+		staged.Path = filepath.Join(staging, rel)
+		stageErr := staged.Write()
+		if stageErr != nil {
+			return nil, fmt.Errorf("could not stage %s: %v", rel, stageErr)
+		}
+		written = append(written, f.Path)
+		entries = append(entries, BackupEntry{Path: rel, Existed: err == nil})
+	}
+	for _, path := range remove {
+		rel, err := relToRoot(root, path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, BackupEntry{
+			Path:    rel,
+			Existed: true,
+			Removed: true,
+		})
+	}
+	if len(entries) == 0 {
+		return written, nil
+	}
+
+	// Replace the previous backup, and record it before moving any file
+	err = os.RemoveAll(backup)
+	if err != nil {
+		return nil, err
+	}
+	err = os.RemoveAll(filepath.Join(stateDir, backupManifest))
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(backup, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	err = writeBackupManifest(stateDir, Backup{Files: entries})
+	if err != nil {
+		return nil, err
+	}
+
+	// Swap the staged files into place
+	for i, e := range entries {
+		err = swapFile(root, staging, backup, e)
+		if err != nil {
+			// A file whose backup is taken was left in place
+			swapped := entries[:i+1]
+			if os.IsExist(err) {
+				swapped = entries[:i]
+			}
+			rollbackErr := restoreEntries(root, backup, swapped)
+			if rollbackErr != nil {
+				return nil, fmt.Errorf("could not write %s: %v, and rolling "+
+					"back failed: %v (run restore to recover)", e.Path, err,
+					rollbackErr)
+			}
+			os.RemoveAll(filepath.Join(stateDir, backupManifest))
+			return nil, fmt.Errorf("could not write %s, no files were "+
+				"changed: %v", e.Path, err)
+		}
+	}
+	return written, nil
+}
+
+// changedFiles returns the paths of the files whose content on disk is not the
+// same, the ones WriteFiles would write.
+func changedFiles(files []File) ([]string, error) {
+	changed := []string{}
+	for _, f := range files {
+		content, err := ioutil.ReadFile(f.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err != nil || string(content) != f.Content {
+			changed = append(changed, f.Path)
+		}
+	}
+	return changed, nil
+}
+
+// addSource records where the file at the path comes from, and fails if
+// another source already writes it.
+func addSource(sources map[string]string, rel string, source string) error {
+	if previous, ok := sources[rel]; ok {
+		return fmt.Errorf("could not write %s twice, it comes from both %s "+
+			"and %s, no files were changed", rel, previous, source)
+	}
+	sources[rel] = source
+	return nil
+}
+
+// swapFile moves the file on disk to the backup and the staged file into its
+// place. An existing backup is never overwritten, as it may be the only copy
+// of a replaced file, the file is then left alone and an os.ErrExist returned.
+func swapFile(root string, staging string, backup string, e BackupEntry) error {
+	target := filepath.Join(root, e.Path)
+	if e.Existed {
+		saved := filepath.Join(backup, e.Path)
+		_, err := os.Lstat(saved)
+		if err == nil {
+			return &os.PathError{Op: "backup", Path: saved, Err: os.ErrExist}
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		err = moveFile(target, saved)
+		if err != nil {
+			return err
+		}
+	}
+	if e.Removed {
+		return nil
+	}
+	return moveFile(filepath.Join(staging, e.Path), target)
+}
+
+// relToRoot returns the path relative to the root, making sure it is inside.
+func relToRoot(root string, path string) (string, error) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file %s is outside of the root %s", path, root)
+	}
+	return rel, nil
+}
+
+// Restore undoes the last run, putting back the files it replaced or removed
+// and removing the files it created. It returns the paths that were restored.
+func Restore(root string, stateDir string) ([]string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(stateDir, backupManifest))
+	if os.IsNotExist(err) {
+		return nil, errors.New("there is no backup to restore")
+	} else if err != nil {
+		return nil, err
+	}
+	backup := Backup{}
+	err = json.Unmarshal(content, &backup)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %v", err)
+	}
+
+	err = restoreEntries(root, filepath.Join(stateDir, backupDir), backup.Files)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Remove(filepath.Join(stateDir, backupManifest))
+	if err != nil {
+		return nil, err
+	}
+
+	restored := []string{}
+	for _, e := range backup.Files {
+		restored = append(restored, filepath.Join(root, e.Path))
+	}
+	return restored, os.RemoveAll(filepath.Join(stateDir, backupDir))
+}
+
+// restoreEntries puts back the backed up files, and removes the ones that did
+// not exist before. Entries that were not swapped yet are left alone.
+func restoreEntries(root string, backup string, entries []BackupEntry) error {
+	for _, e := range entries {
+		target := filepath.Join(root, e.Path)
+		if !e.Existed {
+			err := os.Remove(target)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		saved := filepath.Join(backup, e.Path)
+		if _, err := os.Stat(saved); os.IsNotExist(err) {
+			continue
+		}
+		err := moveFile(saved, target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBackupManifest(stateDir string, backup Backup) error {
+	content, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(stateDir, backupManifest),
+		append(content, '\n'), 0644)
+}
+
+// moveFile renames a file, creating the directory of the destination.
+func moveFile(from string, to string) error {
+	err := os.MkdirAll(filepath.Dir(to), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
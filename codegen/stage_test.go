@@ -0,0 +1,70 @@
+package codegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFilesRejectsDuplicatePaths(t *testing.T) {
+	root, cleanup := FixtureRoot(t, map[string]string{
+		"resolvers/type_edge.go": "original\n",
+	})
+	defer cleanup()
+	stateDir := filepath.Join(root, ".codegen")
+	path := filepath.Join(root, "resolvers", "type_edge.go")
+
+	_, err := WriteFiles(root, stateDir, []File{
+		{Path: path, Layer: "graphql", Source: Source{Schema: "User",
+			Edge: "Groups"}, Content: "first\n"},
+		{Path: path, Layer: "graphql", Source: Source{Schema: "Group",
+			Edge: "Members"}, Content: "second\n"},
+	}, nil)
+	if err == nil {
+		t.Fatal("expected an error for a path written twice")
+	}
+	for _, source := range []string{"edge Groups", "edge Members"} {
+		if !strings.Contains(err.Error(), source) {
+			t.Errorf("error %q does not name %s", err, source)
+		}
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "original\n" {
+		t.Errorf("file was changed to %q", content)
+	}
+}
+
+func TestSwapFileKeepsExistingBackup(t *testing.T) {
+	root, cleanup := FixtureRoot(t, map[string]string{
+		"a.go": "on disk\n",
+		filepath.Join(".codegen", stagingDir, "a.go"): "staged\n",
+		filepath.Join(".codegen", backupDir, "a.go"):  "backed up\n",
+	})
+	defer cleanup()
+	staging := filepath.Join(root, ".codegen", stagingDir)
+	backup := filepath.Join(root, ".codegen", backupDir)
+
+	err := swapFile(root, staging, backup, BackupEntry{Path: "a.go",
+		Existed: true})
+	if !os.IsExist(err) {
+		t.Fatalf("expected the backup to exist, got %v", err)
+	}
+	for path, want := range map[string]string{
+		filepath.Join(root, "a.go"):   "on disk\n",
+		filepath.Join(backup, "a.go"): "backed up\n",
+	} {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != want {
+			t.Errorf("%s is %q, expected %q", path, content, want)
+		}
+	}
+}
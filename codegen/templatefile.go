@@ -0,0 +1,466 @@
+// Overriding the built in templates with files from a directory. An override
+// is checked against the data of the template before it is used, so a
+// reference to a field that does not exist is caught when it is loaded rather
+// than halfway through a generation run.
+
+package codegen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+// TemplateExt is the extension of template override files.
+const TemplateExt = ".tmpl"
+
+// TemplateError is an error in a template override file.
+type TemplateError struct {
+	Path   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e TemplateError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Msg)
+}
+
+// TemplateErrors are every error found in the template override files.
+type TemplateErrors []TemplateError
+
+func (e TemplateErrors) Error() string {
+	lines := []string{}
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LoadTemplates overrides the built in templates with the files in the
+// directory. A file overrides the template named after its path relative to
+// the directory, without the extension: db/node_query_where.tmpl overrides
+// db/node_query_where. It returns the names of the overridden templates. The
+// overrides of an earlier call are replaced. If any file has an error, the
+// templates are left as they were.
+func LoadTemplates(dir string) ([]string, error) {
+	paths := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, TemplateExt) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	errs := TemplateErrors{}
+	overrides := map[string]*template.Template{}
+	texts := map[string]string{}
+	for _, path := range paths {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.ToSlash(strings.TrimSuffix(rel, TemplateExt))
+		t, ok := registeredTemplates[name]
+		if !ok {
+			errs = append(errs, TemplateError{Path: path, Line: 1,
+				Msg: "there is no template named " + name})
+			continue
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		override, fileErrs := parseOverride(t, path, string(content))
+		if len(fileErrs) > 0 {
+			errs = append(errs, fileErrs...)
+			continue
+		}
+		overrides[name] = override
+		texts[name] = string(content)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	clearOverrides()
+	names := []string{}
+	for name, override := range overrides {
+		registeredTemplates[name].override = override
+		registeredTemplates[name].overrideText = texts[name]
+		names = append(names, name)
+	}
+	resetTemplateSetHash()
+	sort.Strings(names)
+	return names, nil
+}
+
+// clearOverrides drops the overrides of every template.
+func clearOverrides() {
+	for _, t := range registeredTemplates {
+		t.override = nil
+		t.overrideText = ""
+	}
+	resetTemplateSetHash()
+}
+
+// templateErrorRegexp matches the line of a template parse error.
+var templateErrorRegexp = regexp.MustCompile(`^template: [^:]*:(\d+):(?:\d+:)? ?`)
+
+// parseOverride parses an override of the template and checks the fields it
+// refers to against the data of the template.
+func parseOverride(
+	t *Template,
+	path string,
+	content string,
+) (*template.Template, TemplateErrors) {
+	override, err := template.New(t.Name).Funcs(t.Funcs).Parse(content)
+	if err != nil {
+		msg := err.Error()
+		line := 1
+		if m := templateErrorRegexp.FindStringSubmatch(msg); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			msg = msg[len(m[0]):]
+		}
+		return nil, TemplateErrors{{Path: path, Line: line, Msg: msg}}
+	}
+
+	c := fieldChecker{
+		t:       t,
+		path:    path,
+		content: []byte(content),
+		root:    reflect.TypeOf(t.Data),
+	}
+	for _, tree := range override.Templates() {
+		if tree.Tree != nil && tree.Tree.Root != nil {
+			c.walk(tree.Tree.Root, c.root, map[string]reflect.Type{"$": c.root})
+		}
+	}
+	return override, c.errs
+}
+
+// fieldChecker walks the parse tree of a template, following the type of the
+// dot and of the variables, and reports references to fields and methods that
+// do not exist in the data. Values whose type is not known statically, such
+// as the results of most functions, are not checked.
+type fieldChecker struct {
+	t       *Template
+	path    string
+	content []byte
+	root    reflect.Type
+	errs    TemplateErrors
+}
+
+func (c *fieldChecker) walk(
+	node parse.Node,
+	dot reflect.Type,
+	vars map[string]reflect.Type,
+) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.walk(child, dot, vars)
+		}
+	case *parse.ActionNode:
+		c.pipe(n.Pipe, dot, vars)
+	case *parse.TemplateNode:
+		if n.Pipe != nil {
+			c.pipe(n.Pipe, dot, vars)
+		}
+	case *parse.IfNode:
+		c.pipe(n.Pipe, dot, vars)
+		c.walk(n.List, dot, copyVars(vars))
+		c.walk(n.ElseList, dot, copyVars(vars))
+	case *parse.WithNode:
+		typ := c.pipe(n.Pipe, dot, vars)
+		c.walk(n.List, typ, copyVars(vars))
+		c.walk(n.ElseList, dot, copyVars(vars))
+	case *parse.RangeNode:
+		scope := copyVars(vars)
+		typ := c.pipe(n.Pipe, dot, scope)
+		key, elem := rangeTypes(typ)
+		switch len(n.Pipe.Decl) {
+		case 1:
+			scope[n.Pipe.Decl[0].Ident[0]] = elem
+		case 2:
+			scope[n.Pipe.Decl[0].Ident[0]] = key
+			scope[n.Pipe.Decl[1].Ident[0]] = elem
+		}
+		c.walk(n.List, elem, scope)
+		c.walk(n.ElseList, dot, copyVars(vars))
+	}
+}
+
+// pipe checks a pipeline and returns the type of its result, nil if unknown.
+// Variables declared by the pipeline are added to vars.
+func (c *fieldChecker) pipe(
+	pipe *parse.PipeNode,
+	dot reflect.Type,
+	vars map[string]reflect.Type,
+) reflect.Type {
+	var typ reflect.Type
+	for i, cmd := range pipe.Cmds {
+		typ = c.command(cmd, dot, vars, i > 0)
+	}
+	if len(pipe.Decl) == 1 && !pipe.IsAssign {
+		vars[pipe.Decl[0].Ident[0]] = typ
+	}
+	return typ
+}
+
+// command checks a command and returns the type of its result, nil if
+// unknown. A command that is not the first of a pipeline gets an extra final
+// argument, so it can only be a function call.
+func (c *fieldChecker) command(
+	cmd *parse.CommandNode,
+	dot reflect.Type,
+	vars map[string]reflect.Type,
+	piped bool,
+) reflect.Type {
+	types := []reflect.Type{}
+	for _, arg := range cmd.Args {
+		types = append(types, c.arg(arg, dot, vars))
+	}
+	if len(cmd.Args) == 1 && !piped {
+		if _, ok := cmd.Args[0].(*parse.IdentifierNode); !ok {
+			return types[0]
+		}
+	}
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		if fn, ok := c.t.Funcs[ident.Ident]; ok {
+			fnType := reflect.TypeOf(fn)
+			if fnType.Kind() == reflect.Func && fnType.NumOut() > 0 {
+				return fnType.Out(0)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *fieldChecker) arg(
+	node parse.Node,
+	dot reflect.Type,
+	vars map[string]reflect.Type,
+) reflect.Type {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return c.fields(dot, n.Ident, n.Position())
+	case *parse.VariableNode:
+		typ, ok := vars[n.Ident[0]]
+		if !ok {
+			return nil
+		}
+		return c.fields(typ, n.Ident[1:], n.Position())
+	case *parse.ChainNode:
+		typ := c.arg(n.Node, dot, vars)
+		return c.fields(typ, n.Field, n.Position())
+	case *parse.PipeNode:
+		return c.pipe(n, dot, copyVars(vars))
+	}
+	return nil
+}
+
+// fields follows a chain of field and method names from the type, and returns
+// the type at its end, nil if unknown.
+func (c *fieldChecker) fields(
+	typ reflect.Type,
+	names []string,
+	pos parse.Pos,
+) reflect.Type {
+	if len(names) > 0 && c.root == nil && typ == nil {
+		c.errorf(pos, "template %s has no data, so it can not refer to .%s",
+			c.t.Name, strings.Join(names, "."))
+		return nil
+	}
+	for _, name := range names {
+		if typ == nil {
+			return nil
+		}
+		next, ok := fieldType(typ, name)
+		if !ok {
+			c.errorf(pos, "template %s refers to .%s, which is not a field of "+
+				"%s (fields: %s)", c.t.Name, name, typ,
+				strings.Join(fieldNames(typ), ", "))
+			return nil
+		}
+		typ = next
+	}
+	return typ
+}
+
+func (c *fieldChecker) errorf(pos parse.Pos, format string, args ...interface{}) {
+	line, column := position(c.content, int64(pos))
+	c.errs = append(c.errs, TemplateError{
+		Path:   c.path,
+		Line:   line,
+		Column: column,
+		Msg:    fmt.Sprintf(format, args...),
+	})
+}
+
+// fieldType returns the type of a field, method result or map value of the
+// type, and false if it has no such field. Types that can hold anything, such
+// as interface{}, return nil.
+func fieldType(typ reflect.Type, name string) (reflect.Type, bool) {
+	if m, ok := typ.MethodByName(name); ok {
+		return methodResult(m.Type), true
+	}
+	if typ.Kind() != reflect.Ptr && typ.Kind() != reflect.Interface {
+		if m, ok := reflect.PtrTo(typ).MethodByName(name); ok {
+			return methodResult(m.Type), true
+		}
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+		if m, ok := typ.MethodByName(name); ok {
+			return methodResult(m.Type), true
+		}
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		f, ok := typ.FieldByName(name)
+		if !ok || f.PkgPath != "" {
+			return nil, false
+		}
+		return f.Type, true
+	case reflect.Map:
+		return typ.Elem(), true
+	case reflect.Interface:
+		return nil, typ.NumMethod() == 0
+	}
+	return nil, false
+}
+
+func methodResult(fn reflect.Type) reflect.Type {
+	if fn.NumOut() == 0 {
+		return nil
+	}
+	return fn.Out(0)
+}
+
+// fieldNames lists the exported fields and methods of the type.
+func fieldNames(typ reflect.Type) []string {
+	names := []string{}
+	for i := 0; i < typ.NumMethod(); i++ {
+		if typ.Method(i).PkgPath == "" {
+			names = append(names, typ.Method(i).Name)
+		}
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Struct {
+		for i := 0; i < typ.NumField(); i++ {
+			if typ.Field(i).PkgPath == "" {
+				names = append(names, typ.Field(i).Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rangeTypes returns the types of the key and element of ranging over the
+// type, nil if unknown.
+func rangeTypes(typ reflect.Type) (reflect.Type, reflect.Type) {
+	if typ == nil {
+		return nil, nil
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		return reflect.TypeOf(0), typ.Elem()
+	case reflect.Map:
+		return typ.Key(), typ.Elem()
+	case reflect.Chan:
+		return typ.Elem(), nil
+	case reflect.Int:
+		return typ, typ
+	}
+	return nil, nil
+}
+
+func copyVars(vars map[string]reflect.Type) map[string]reflect.Type {
+	scope := map[string]reflect.Type{}
+	for k, v := range vars {
+		scope[k] = v
+	}
+	return scope
+}
+
+// TemplateFields returns the fields the template can refer to, and their
+// types, for documenting its data.
+func TemplateFields(t *Template) []string {
+	typ := reflect.TypeOf(t.Data)
+	if typ == nil {
+		return []string{}
+	}
+	fields := []string{}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath == "" {
+			fields = append(fields, f.Name+" "+f.Type.String())
+		}
+	}
+	return fields
+}
+
+// templateSet caches the hash of the templates, which every signature
+// records. It is reset whenever a template or an override is registered.
+var templateSet struct {
+	sync.Mutex
+	hash string
+}
+
+// TemplateSetHash returns the hash of the text of every template, the
+// override where there is one, so files are rendered again when a template
+// changes, and their signatures tell what they were rendered with.
+func TemplateSetHash() string {
+	templateSet.Lock()
+	defer templateSet.Unlock()
+	if templateSet.hash == "" {
+		lines := []string{}
+		for _, t := range Templates() {
+			text := t.Text
+			if t.override != nil {
+				text = t.overrideText
+			}
+			lines = append(lines, t.Name+" "+HashContent(text))
+		}
+		templateSet.hash = HashContent(strings.Join(lines, "\n"))[:16]
+	}
+	return templateSet.hash
+}
+
+// resetTemplateSetHash drops the cached hash of the templates.
+func resetTemplateSetHash() {
+	templateSet.Lock()
+	templateSet.hash = ""
+	templateSet.Unlock()
+}
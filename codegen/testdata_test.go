@@ -0,0 +1,124 @@
+package codegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The fixtures shared by the tests of this package and of codegen_test, which
+// can not build schemas from a schema package of its own.
+
+// FixturePolicy is a privacy policy known by its name only.
+type FixturePolicy string
+
+// GetName returns the name of the policy.
+func (p FixturePolicy) GetName() string { return string(p) }
+
+// FixtureSchema returns a schema with a field of every type, exposed as a
+// graphql node.
+func FixtureSchema(name string) *DeclaredSchema {
+	allow, owner := FixturePolicy("AllowAll"), FixturePolicy("OwnerOnly")
+	return &DeclaredSchema{
+		Name: name,
+		Fields: []FieldStruct{
+			*Field().SetName(IDField).SetCodeName("ID").SetType(StringType).
+				SetDefaultValue(`""`).SetExampleValue(`"abc"`).
+				SetUnique(true).SetIndexed(true).SetPrivacy(allow).
+				SetWritePrivacy(owner),
+			*Field().SetName("name").SetCodeName("Name").SetType(StringType).
+				SetDefaultValue(`""`).SetExampleValue(`"name"`).
+				SetPrivacy(allow).SetWritePrivacy(owner).SetCanOrderBy(true),
+			*Field().SetName("amount").SetCodeName("Amount").
+				SetType(FloatType).SetDefaultValue("0.0").
+				SetExampleValue("1.5").SetPrivacy(allow).
+				SetWritePrivacy(owner),
+			*Field().SetName("count").SetCodeName("Count").SetType(IntType).
+				SetDefaultValue("int64(0)").SetExampleValue("2").
+				SetPrivacy(allow).SetWritePrivacy(owner),
+			*Field().SetName("active").SetCodeName("Active").
+				SetType(BoolType).SetDefaultValue("false").
+				SetExampleValue("true").SetPrivacy(allow).
+				SetWritePrivacy(owner),
+		},
+		Edges:           []EdgeStruct{},
+		EdgePointers:    map[string]EdgeStruct{},
+		DeletionPrivacy: owner,
+		GraphQLNode: &GraphQLNode{
+			Name:        name,
+			CodeName:    name,
+			Description: "A fixture schema",
+			Fields: []GraphQLField{{Name: "id", Type: "ID!",
+				CodeName: "ID", CodeType: "graphql.ID"}},
+			Edges: []GraphQLEdge{},
+		},
+	}
+}
+
+// RegisteredFixture returns the registered fixture schema of the name,
+// registering it the first time, as a schema can only be registered once.
+func RegisteredFixture(name string) *DeclaredSchema {
+	for _, s := range RegisteredSchemas() {
+		if s.GetName() == name {
+			return s.(*DeclaredSchema)
+		}
+	}
+	s := FixtureSchema(name)
+	Register(s)
+	return s
+}
+
+// AddFixtureEdge adds an edge between the schemas, exposed in graphql, and
+// points the schema at the other end to it.
+func AddFixtureEdge(
+	from *DeclaredSchema,
+	to *DeclaredSchema,
+	codeName string,
+	includeReverse bool,
+) {
+	allow, owner := FixturePolicy("AllowAll"), FixturePolicy("OwnerOnly")
+	ge := GraphQLEdge{
+		From:                 from.Name,
+		To:                   to.Name,
+		FieldName:            strings.ToLower(codeName),
+		FieldCodeName:        codeName,
+		TotalName:            codeName,
+		ReverseFieldName:     "reverse" + codeName,
+		ReverseFieldCodeName: "Reverse" + codeName,
+		FromCodeName:         from.Name,
+		ToCodeName:           to.Name,
+		IncludeReverse:       includeReverse,
+		EdgeCodeName:         codeName,
+		OrderBy:              "name",
+	}
+	e := Edge().SetName(strings.ToUpper(codeName)).SetCodeName(codeName).
+		SetFromNode(from).SetToNode(to).SetForwardsName(codeName).
+		SetBackwardsName("Reverse" + codeName).SetPrivacy(allow).
+		SetReversePrivacy(allow).SetDeletionPrivacy(owner).SetGQLEdge(&ge)
+	e.WritePrivacy = owner
+	e.SetFields([]EdgeFieldStruct{*EdgeField().SetName("weight").
+		SetCodeName("Weight").SetType(IntType).SetDefaultValue("int64(0)").
+		SetExampleValue("1").SetPrivacy(allow).SetWritePrivacy(owner)})
+	from.Edges = append(from.Edges, *e)
+	from.GraphQLNode.Edges = append(from.GraphQLNode.Edges, ge)
+	to.AddEdgePointer(*e)
+}
+
+// FixtureRoot creates a temporary root holding the files, keyed by their path
+// relative to it. The returned function removes the root.
+func FixtureRoot(t *testing.T, files map[string]string) (string, func()) {
+	root, err := ioutil.TempDir("", "codegen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range files {
+		err = File{Path: filepath.Join(root, path), Content: content}.Write()
+		if err != nil {
+			os.RemoveAll(root)
+			t.Fatal(err)
+		}
+	}
+	return root, func() { os.RemoveAll(root) }
+}
@@ -2,6 +2,15 @@
 
 package codegen
 
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+)
+
 // FieldType is a string wrapper for types of fields.
 type FieldType string
 
@@ -12,3 +21,76 @@ const (
 	IntType    = FieldType("int64")
 	BoolType   = FieldType("bool")
 )
+
+// FieldTypes are the valid types of fields.
+var FieldTypes = []FieldType{StringType, FloatType, IntType, BoolType}
+
+// IsValid returns whether the type is one of the valid types of fields.
+func (t FieldType) IsValid() bool {
+	for _, v := range FieldTypes {
+		if t == v {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckValue checks that the value, which is pasted as is into the generated
+// code, is a Go expression that can be assigned to a field of the type.
+func (t FieldType) CheckValue(value string) error {
+	_, err := t.checkValue(value)
+	return err
+}
+
+// CheckDefaultValue checks the value like CheckValue, and also that it keeps
+// the type when it is stored in an interface{}, as the generated mutators keep
+// default values in a map[string]interface{}. An untyped constant such as 0
+// can be assigned to a float64, but it is stored as an int.
+func (t FieldType) CheckDefaultValue(value string) error {
+	stored, err := t.checkValue(value)
+	if err != nil {
+		return err
+	}
+	if stored != string(t) {
+		return fmt.Errorf("%s is stored as %s in the generated mutators, not "+
+			"%s, so write it as %s(%s)", value, stored, t, t, value)
+	}
+	return nil
+}
+
+// checkValue type-checks the value as it is used, by assigning it to the type,
+// and returns the type it has when it is stored in an interface{}.
+func (t FieldType) checkValue(value string) (string, error) {
+	if value == "" {
+		return "", errors.New("the value is empty")
+	}
+	if _, err := parser.ParseExpr(value); err != nil {
+		return "", fmt.Errorf("%s is not a Go expression", value)
+	}
+
+	fset := token.NewFileSet()
+	source := "package p\n\nvar _ " + string(t) + " = " + value + "\n" +
+		"var _ interface{} = " + value + "\n"
+	file, err := parser.ParseFile(fset, "", source, 0)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a Go expression", value)
+	}
+	var firstErr error
+	config := types.Config{Error: func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}}
+	info := &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+	config.Check("p", fset, []*ast.File{file}, info)
+	if e, ok := firstErr.(types.Error); ok {
+		return "", errors.New(e.Msg)
+	} else if firstErr != nil {
+		return "", firstErr
+	}
+
+	// The untyped constants are recorded with their default type once they
+	// are assigned to the interface{}
+	spec := file.Decls[1].(*ast.GenDecl).Specs[0].(*ast.ValueSpec)
+	return types.TypeString(info.Types[spec.Values[0]].Type, nil), nil
+}
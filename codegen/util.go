@@ -3,47 +3,189 @@
 package codegen
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
 
-// ExtractManualSections returns the strings that represent the manual sections
-// in a file.
-func ExtractManualSections(content string) []string {
-	sections := []string{}
-	re := regexp.MustCompile(
-		"(?sU)" +
-			strings.Replace(StartManual, "*", "\\*", -1) + "\\n?" +
-			"(.*)" +
-			"\\n\\s*" + strings.Replace(EndManual, "*", "\\*", -1),
-	)
-	res := re.FindAllStringSubmatch(content, -1)
-	for _, v := range res {
-		if len(v) == 2 {
-			s := v[1]
-			sections = append(sections, s)
-		} else {
-			sections = append(sections, "")
+// ManualSection is the code written by hand in a manual section of a file.
+type ManualSection struct {
+	Name    string // Empty for the unnamed sections of older files
+	Content string
+}
+
+// Names of the manual sections the writers have in common.
+const (
+	ManualImports   = "imports"
+	ManualFunctions = "functions"
+)
+
+// CodeUnmatchedManual is the code of a manual section on disk that the
+// template of its file no longer has.
+const CodeUnmatchedManual = ErrorCode("unmatched-manual-section")
+
+// ExtractManualSections returns the manual sections in a file, in the order
+// they appear.
+func ExtractManualSections(content string) []ManualSection {
+	sections := []ManualSection{}
+	for _, groups := range ManualExtractor.FindAllStringSubmatch(content, -1) {
+		// The code is every line between the start and the end line. The
+		// line breaks around it are part of the template, or every
+		// generation would add a blank line
+		code := groups[3] + groups[4][:strings.LastIndex(groups[4], "\n")]
+		if strings.TrimSpace(code) == "" {
+			code = ""
 		}
+		sections = append(sections, ManualSection{
+			Name:    groups[2],
+			Content: strings.TrimPrefix(code, "\n"),
+		})
 	}
 	return sections
 }
 
 // StartManual is a string constant that represents the start of a manul block.
+// It has no name, as written by older versions of the generator, see
+// StartManualSection.
 const StartManual = "// * START MANUAL SECTION *"
 
 // EndManual is a string consctant that represents the end of a manual block.
 const EndManual = "// * END MANUAL SECTION *"
 
-// ManualExtractor is a regex that matches the manual pieces of code
+// StartManualSection returns the line that starts the manual section with the
+// name, such as "// * START MANUAL SECTION: imports *". Sections are carried
+// over to the section with the same name when a file is regenerated.
+func StartManualSection(name string) string {
+	return "// * START MANUAL SECTION: " + name + " *"
+}
+
+// manualStartPrefix is what the start of a manual section begins with, named
+// or not.
+const manualStartPrefix = "// * START MANUAL SECTION"
+
+// ManualExtractor is a regex that matches the manual pieces of code. The
+// groups are the start line, the name of the section, its content and the
+// white space before the end line.
 var ManualExtractor = regexp.MustCompile(
 	"(?sU)" +
-		strings.Replace(StartManual, "*", "\\*", -1) + "\\n?" +
+		"(" + regexp.QuoteMeta(manualStartPrefix) + "(?:: ([\\w.-]+))? \\*)" +
+		"\\n?" +
 		"(.*)" +
-		"(\\n\\s*)" + strings.Replace(EndManual, "*", "\\*", -1),
+		"(\\n\\s*)" + regexp.QuoteMeta(EndManual),
 )
 
-// ReplaceAllStringSubmatchFunc finds submatches and replaces the submatches
+// StripManualSections removes the content of the manual sections, leaving the
+// empty blocks in place, with the end line indented as it was.
+func StripManualSections(content string) string {
+	return ReplaceAllStringSubmatchFunc(
+		ManualExtractor,
+		content,
+		func(groups []string) string {
+			indent := groups[4][strings.LastIndex(groups[4], "\n")+1:]
+			return groups[1] + "\n" + indent + EndManual
+		},
+	)
+}
+
+// ManualParts hands the manual sections read from a file to the templates of
+// its writer by name. Files written before the sections had names have
+// unnamed sections, which are handed out in order instead.
+type ManualParts struct {
+	sections []ManualSection
+	used     []bool
+	next     int // Number of sections asked for so far
+}
+
+// NewManualParts returns the manual parts of a file, which has none if
+// sections is empty.
+func NewManualParts(sections []ManualSection) *ManualParts {
+	return &ManualParts{
+		sections: sections,
+		used:     make([]bool, len(sections)),
+	}
+}
+
+// Get returns the content of the manual section with the name, empty if the
+// file has none. The content is escaped as the rest of the writer output, see
+// Unescape.
+func (m *ManualParts) Get(name string) string {
+	index := m.next
+	m.next++
+	found := -1
+	for i, s := range m.sections {
+		if s.Name == name {
+			found = i
+			break
+		}
+	}
+	if found < 0 && index < len(m.sections) && m.sections[index].Name == "" {
+		found = index
+	}
+	if found < 0 || m.used[found] {
+		return ""
+	}
+	m.used[found] = true
+	return strings.Replace(m.sections[found].Content, "%", "%%", -1)
+}
+
+// Err returns an error for every manual section with code that no template
+// asked for, so the code is not dropped without notice.
+func (m *ManualParts) Err() error {
+	errs := Errors{}
+	for i, s := range m.sections {
+		if m.used[i] || strings.TrimSpace(s.Content) == "" {
+			continue
+		}
+		name := fmt.Sprintf("%q", s.Name)
+		if s.Name == "" {
+			name = fmt.Sprintf("number %d", i+1)
+		}
+		errs = append(errs, &Error{
+			Code: CodeUnmatchedManual,
+			Err: fmt.Errorf("manual section %s has code, but the template "+
+				"has no section for it any more; move the code to another "+
+				"section, or run with --force to drop it", name),
+		})
+	}
+	return errs.Err()
+}
+
+// stripManualSectionsV1 removes the content of the manual sections the way
+// generators before 1.1.0 did, keeping the white space before the end lines,
+// for the hashes they recorded.
+func stripManualSectionsV1(content string) string {
+	return ReplaceAllStringSubmatchFunc(
+		ManualExtractor,
+		content,
+		func(groups []string) string {
+			return groups[1] + groups[4] + EndManual
+		},
+	)
+}
+
+// FillManualSections puts the code of the sections back into the empty manual
+// sections of the content, in order.
+func FillManualSections(content string, sections []ManualSection) string {
+	i := 0
+	return ReplaceAllStringSubmatchFunc(
+		ManualExtractor,
+		content,
+		func(groups []string) string {
+			if i >= len(sections) {
+				return groups[0]
+			}
+			code := sections[i].Content
+			i++
+			if code == "" {
+				return groups[0]
+			}
+			return groups[1] + "\n" + code + groups[4] + EndManual
+		},
+	)
+}
+
+// ReplaceAllStringSubmatchFunc finds submatches and replaces the submatches.
+// Groups that are not part of a match are empty.
 func ReplaceAllStringSubmatchFunc(re *regexp.Regexp, str string, repl func([]string) string) string {
 	result := ""
 	lastIndex := 0
@@ -51,6 +193,10 @@ func ReplaceAllStringSubmatchFunc(re *regexp.Regexp, str string, repl func([]str
 	for _, v := range re.FindAllSubmatchIndex([]byte(str), -1) {
 		groups := []string{}
 		for i := 0; i < len(v); i += 2 {
+			if v[i] < 0 {
+				groups = append(groups, "")
+				continue
+			}
 			groups = append(groups, str[v[i]:v[i+1]])
 		}
 		result += str[lastIndex:v[0]] + repl(groups)
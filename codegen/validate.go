@@ -0,0 +1,350 @@
+// Checking the schemas themselves before anything is rendered. Problems such
+// as a duplicate field or an edge to an unknown schema would otherwise produce
+// code that does not compile, or fails at runtime.
+
+package codegen
+
+import (
+	"fmt"
+	"go/token"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Codes of the problems found in schemas.
+const (
+	CodeEmptyName          = ErrorCode("empty-name")
+	CodeEmptyCodeName      = ErrorCode("empty-code-name")
+	CodeInvalidCodeName    = ErrorCode("invalid-code-name")
+	CodeLowercaseCodeName  = ErrorCode("lowercase-code-name")
+	CodeDuplicateSchema    = ErrorCode("duplicate-schema")
+	CodeDuplicateField     = ErrorCode("duplicate-field")
+	CodeDuplicateEdge      = ErrorCode("duplicate-edge")
+	CodeInvalidType        = ErrorCode("invalid-type")
+	CodeInvalidDefault     = ErrorCode("invalid-default-value")
+	CodeInvalidExample     = ErrorCode("invalid-example-value")
+	CodeMissingID          = ErrorCode("missing-id-field")
+	CodeWrongFromNode      = ErrorCode("wrong-from-node")
+	CodeUnknownNode        = ErrorCode("unknown-node")
+	CodeUnknownOrderBy     = ErrorCode("unknown-order-by-field")
+	CodeOrderByNotSortable = ErrorCode("order-by-not-sortable")
+	CodeDuplicateGQLEdge   = ErrorCode("duplicate-graphql-edge")
+)
+
+// IDField is the name of the field every schema has to declare.
+const IDField = "id"
+
+// ValidateSchemas checks the schemas that are generated together, and returns
+// every problem found as Errors. Each error carries the schema, edge and field
+// it was found in, and one of the codes above.
+func ValidateSchemas(schemas []Schema) error {
+	v := schemaValidator{
+		schemas:       map[string]Schema{},
+		edgeNames:     map[string]string{},
+		edgeCodeNames: map[string]string{},
+		gqlFields:     map[string]string{},
+		gqlEdges:      map[string]GraphQLEdge{},
+	}
+	for _, s := range schemas {
+		if _, ok := v.schemas[s.GetName()]; ok {
+			v.add(Error{Schema: s.GetName()}, CodeDuplicateSchema,
+				"the schema is declared twice")
+			continue
+		}
+		v.schemas[s.GetName()] = s
+	}
+	for _, s := range schemas {
+		v.schema(s)
+	}
+	return v.errs.Err()
+}
+
+// schemaValidator collects the problems found in the schemas.
+type schemaValidator struct {
+	schemas       map[string]Schema      // By name
+	edgeNames     map[string]string      // Schema of each edge, by edge name
+	edgeCodeNames map[string]string      // Schema of each edge, by code name
+	gqlFields     map[string]string      // Each graphql edge, by node and field
+	gqlEdges      map[string]GraphQLEdge // First graphql edge between two nodes
+	errs          Errors
+}
+
+func (v *schemaValidator) add(
+	context Error,
+	code ErrorCode,
+	format string,
+	args ...interface{},
+) {
+	context.Code = code
+	context.Err = fmt.Errorf(format, args...)
+	v.errs = append(v.errs, &context)
+}
+
+func (v *schemaValidator) schema(s Schema) {
+	context := Error{Schema: s.GetName()}
+	if s.GetName() == "" {
+		v.add(context, CodeEmptyName, "the schema has no name")
+	} else {
+		// The name of a schema is used as is in the generated code
+		v.codeName(context, s.GetName(), "name")
+	}
+
+	hasID := false
+	names := map[string]bool{}
+	codeNames := map[string]bool{}
+	for _, f := range s.GetFields() {
+		hasID = hasID || f.Name == IDField
+		v.field(Error{Schema: s.GetName(), Field: f.Name}, f.Name, f.CodeName,
+			f.Type, names, codeNames)
+		v.values(Error{Schema: s.GetName(), Field: f.Name}, f.Type,
+			f.DefaultValue, f.ExampleValue)
+	}
+	if !hasID {
+		v.add(context, CodeMissingID, "the schema has no %q field", IDField)
+	}
+
+	for _, e := range s.GetEdges() {
+		v.edge(s, e)
+	}
+	if n := s.GetGraphQLNode(); n != nil {
+		v.graphQLNode(s, n)
+	}
+}
+
+// field checks a field of a schema or an edge against the other fields seen
+// so far.
+func (v *schemaValidator) field(
+	context Error,
+	name string,
+	codeName string,
+	t FieldType,
+	names map[string]bool,
+	codeNames map[string]bool,
+) {
+	if name == "" {
+		v.add(context, CodeEmptyName, "the field has no name")
+	} else if names[name] {
+		v.add(context, CodeDuplicateField, "the field is declared twice")
+	}
+	names[name] = true
+	v.codeName(context, codeName, "code name")
+	if codeName != "" && codeNames[codeName] {
+		v.add(context, CodeDuplicateField, "code name %s is used by another "+
+			"field", codeName)
+	}
+	codeNames[codeName] = true
+	if !t.IsValid() {
+		v.add(context, CodeInvalidType, "unknown type %q (valid types are %s, "+
+			"%s, %s, %s)", t, StringType, FloatType, IntType, BoolType)
+	}
+}
+
+// values checks the default and example values of a field against its type.
+// Values of an invalid type are not checked, as the type is reported already.
+func (v *schemaValidator) values(
+	context Error,
+	t FieldType,
+	defaultValue string,
+	exampleValue string,
+) {
+	if !t.IsValid() {
+		return
+	}
+	if err := t.CheckDefaultValue(defaultValue); err != nil {
+		v.add(context, CodeInvalidDefault, "invalid default value: %v", err)
+	}
+	if err := t.CheckValue(exampleValue); err != nil {
+		v.add(context, CodeInvalidExample, "invalid example value: %v", err)
+	}
+}
+
+func (v *schemaValidator) edge(s Schema, e EdgeStruct) {
+	context := Error{Schema: s.GetName(), Edge: e.Name}
+	if e.Name == "" {
+		v.add(context, CodeEmptyName, "the edge has no name")
+	} else if other, ok := v.edgeNames[e.Name]; ok {
+		v.add(context, CodeDuplicateEdge, "the edge name is also used by an "+
+			"edge of %s", other)
+	} else {
+		v.edgeNames[e.Name] = s.GetName()
+	}
+	v.codeName(context, e.CodeName, "code name")
+	if other, ok := v.edgeCodeNames[e.CodeName]; ok && e.CodeName != "" {
+		v.add(context, CodeDuplicateEdge, "code name %s is also used by an "+
+			"edge of %s", e.CodeName, other)
+	} else if e.CodeName != "" {
+		v.edgeCodeNames[e.CodeName] = s.GetName()
+	}
+
+	if e.FromNode == nil || e.FromNode.GetName() != s.GetName() {
+		v.add(context, CodeWrongFromNode, "the edge is declared on %s, but "+
+			"does not start from it", s.GetName())
+	}
+	to := v.known(e.ToNode)
+	if to == nil {
+		v.add(context, CodeUnknownNode, "the edge points to %s, which is not "+
+			"one of the generated schemas", nodeName(e.ToNode))
+	}
+
+	names := map[string]bool{}
+	codeNames := map[string]bool{}
+	for _, f := range e.Fields {
+		fieldContext := Error{Schema: s.GetName(), Edge: e.Name, Field: f.Name}
+		v.field(fieldContext, f.Name, f.CodeName, f.Type, names, codeNames)
+		v.values(fieldContext, f.Type, f.DefaultValue, f.ExampleValue)
+	}
+
+	// The connections of the edge are ordered by a field of the node at the
+	// other end
+	if e.GQLEdge != nil {
+		if to != nil {
+			v.orderBy(context, e.GQLEdge.OrderBy, "order_by", to)
+		}
+		if from := v.known(e.FromNode); from != nil {
+			v.orderBy(context, e.GQLEdge.ReverseOrderBy, "reverse_order_by",
+				from)
+		}
+	}
+}
+
+// orderBy checks that a graphql edge is ordered by a field of the schema that
+// can be ordered by.
+func (v *schemaValidator) orderBy(
+	context Error,
+	name string,
+	key string,
+	s Schema,
+) {
+	if name == "" {
+		return
+	}
+	context.Field = name
+	for _, f := range s.GetFields() {
+		if f.Name != name {
+			continue
+		}
+		if !f.CanOrderBy {
+			v.add(context, CodeOrderByNotSortable, "graphql %s names a field "+
+				"of %s that can not be ordered by, set CanOrderBy on it", key,
+				s.GetName())
+		}
+		return
+	}
+	v.add(context, CodeUnknownOrderBy, "graphql %s names a field %s does not "+
+		"have", key, s.GetName())
+}
+
+func (v *schemaValidator) graphQLNode(s Schema, n *GraphQLNode) {
+	context := Error{Schema: s.GetName()}
+	if n.Name == "" {
+		v.add(context, CodeEmptyName, "the graphql node has no name")
+	}
+	v.codeName(context, n.CodeName, "graphql code name")
+	names := map[string]bool{}
+	for _, f := range n.Fields {
+		fieldContext := Error{Schema: s.GetName(), Field: f.Name}
+		if f.Name == "" {
+			v.add(fieldContext, CodeEmptyName, "the graphql field has no name")
+		} else if names[f.Name] {
+			v.add(fieldContext, CodeDuplicateField, "the graphql field is "+
+				"declared twice")
+		}
+		names[f.Name] = true
+	}
+	for _, e := range n.Edges {
+		context := Error{Schema: s.GetName(), Edge: e.EdgeCodeName}
+		for _, name := range []string{e.From, e.To} {
+			if _, ok := v.schemas[name]; !ok {
+				v.add(context, CodeUnknownNode, "the graphql edge %s connects "+
+					"%q, which is not one of the generated schemas", e.FieldName,
+					name)
+			}
+		}
+		v.gqlEdge(context, e, "the graphql edge "+e.FieldName+" of "+
+			s.GetName())
+		if e.IncludeReverse {
+			reverse := e
+			reverse.FromCodeName, reverse.ToCodeName = e.ToCodeName,
+				e.FromCodeName
+			reverse.FieldCodeName = e.ReverseFieldCodeName
+			v.gqlEdge(context, reverse, "the reverse of the graphql edge "+
+				e.FieldName+" of "+s.GetName())
+		}
+	}
+}
+
+// gqlEdge checks that no other graphql edge, reverse ones included, gives the
+// node it starts from a field of the same name, which the dataloader keys are
+// named after. Edges between the same nodes in the same direction share their
+// connection and edge resolver types, so they must have the same fields.
+func (v *schemaValidator) gqlEdge(context Error, e GraphQLEdge, edge string) {
+	field := e.FromCodeName + "." + e.FieldCodeName
+	if other, ok := v.gqlFields[field]; ok {
+		v.add(context, CodeDuplicateGQLEdge, "%s gives %s the field %s, as %s "+
+			"does", edge, e.FromCodeName, e.FieldCodeName, other)
+		return
+	}
+	v.gqlFields[field] = edge
+
+	nodes := e.FromCodeName + "To" + e.ToCodeName
+	if other, ok := v.gqlEdges[nodes]; ok {
+		if !sameGraphQLFields(e.Fields, other.Fields) {
+			v.add(context, CodeDuplicateGQLEdge, "%s connects %s to %s, as %s "+
+				"does, so they share the resolver types %s, but their fields "+
+				"differ", edge, e.FromCodeName, e.ToCodeName,
+				v.gqlFields[other.FromCodeName+"."+other.FieldCodeName], nodes)
+		}
+		return
+	}
+	v.gqlEdges[nodes] = e
+}
+
+// sameGraphQLFields returns whether the fields would generate the same
+// resolvers.
+func sameGraphQLFields(a []GraphQLField, b []GraphQLField) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Type != b[i].Type ||
+			a[i].CodeName != b[i].CodeName || a[i].CodeType != b[i].CodeType {
+			return false
+		}
+	}
+	return true
+}
+
+// codeName checks that a name used in the generated code is an exported
+// identifier.
+func (v *schemaValidator) codeName(context Error, name string, what string) {
+	if name == "" {
+		v.add(context, CodeEmptyCodeName, "the %s is empty", what)
+		return
+	}
+	if !token.IsIdentifier(name) {
+		v.add(context, CodeInvalidCodeName, "the %s %q is not a valid Go "+
+			"identifier", what, name)
+		return
+	}
+	if r, _ := utf8.DecodeRuneInString(name); !unicode.IsUpper(r) {
+		v.add(context, CodeLowercaseCodeName, "the %s %s has to start with an "+
+			"upper case letter to be exported", what, name)
+	}
+}
+
+// known returns the generated schema with the name of the node, nil if there
+// is none.
+func (v *schemaValidator) known(node Schema) Schema {
+	if node == nil {
+		return nil
+	}
+	return v.schemas[node.GetName()]
+}
+
+// nodeName returns the name of a node for messages.
+func nodeName(node Schema) string {
+	if node == nil {
+		return "no schema"
+	}
+	return node.GetName()
+}
@@ -0,0 +1,47 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSchemasDuplicateGraphQLEdge(t *testing.T) {
+	user, group := FixtureSchema("User"), FixtureSchema("Group")
+	AddFixtureEdge(user, group, "UserToGroup", true)
+	AddFixtureEdge(group, user, "GroupToMember", true)
+
+	// The reverse of each edge shares the resolver types of the other one
+	err := ValidateSchemas([]Schema{user, group})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	group.GraphQLNode.Edges[0].Fields = []GraphQLField{{Name: "role",
+		Type: "String!", CodeName: "Role", CodeType: "string"}}
+	err = ValidateSchemas([]Schema{user, group})
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("expected an error per direction, got %v", err)
+	}
+	for _, e := range errs {
+		if e.Code != CodeDuplicateGQLEdge || e.Schema != "Group" ||
+			e.Edge != "GroupToMember" ||
+			!strings.Contains(e.Err.Error(), "usertogroup of User does, so "+
+				"they share the resolver types") ||
+			!strings.Contains(e.Err.Error(), "but their fields differ") {
+			t.Errorf("unexpected error %v", e)
+		}
+	}
+
+	group.GraphQLNode.Edges[0].Fields = nil
+	group.GraphQLNode.Edges[0].ReverseFieldCodeName = "UserToGroup"
+	err = ValidateSchemas([]Schema{user, group})
+	errs, ok = err.(Errors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("expected one error, got %v", err)
+	}
+	if e := errs[0]; e.Code != CodeDuplicateGQLEdge ||
+		!strings.Contains(e.Err.Error(), "gives User the field UserToGroup") {
+		t.Errorf("unexpected error %v", e)
+	}
+}
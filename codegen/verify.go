@@ -0,0 +1,375 @@
+// Type-checking the generated packages in memory, along with the rest of the
+// target repository, before anything is written. Formatting only catches
+// syntax errors, while wrong identifiers or missing imports would otherwise
+// only show up when the target repository is built.
+
+package codegen
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CodeTypeError is the code of a type error found in the generated code.
+const CodeTypeError = ErrorCode("type-error")
+
+// Verify type-checks the packages of the traced files, which are the files
+// rendered again with the name of their templates in line directives. The
+// other files of the run, and the files on disk, fill in the rest of the
+// packages. Type errors are returned as Errors with the file, schema, edge and
+// template they come from, and the field whose name is on the offending line.
+// Test files are not checked.
+func Verify(config Config, schemas []Schema, files []File, traced []File) error {
+	modulePath, err := rootImportPath(config.Root)
+	if err != nil {
+		return err
+	}
+	v := &verifier{
+		fset:       token.NewFileSet(),
+		root:       config.Root,
+		modulePath: modulePath,
+		overlay:    map[string]string{},
+		written:    map[string]string{},
+		packages:   map[string]*types.Package{},
+		checked:    map[string]bool{},
+		files:      map[string]File{},
+		schemas:    map[string]Schema{},
+	}
+	v.std = importer.ForCompiler(v.fset, "source", nil)
+	for _, f := range files {
+		v.overlay[f.Path] = f.Content
+		v.written[f.Path] = f.Content
+	}
+	for _, f := range traced {
+		v.overlay[f.Path] = f.Content
+		v.files[f.Path] = f
+	}
+	for _, s := range schemas {
+		v.schemas[s.GetName()] = s
+	}
+
+	// Every package with a traced file is checked, in a fixed order so the
+	// errors are too
+	dirs := []string{}
+	for _, f := range traced {
+		dir := filepath.Dir(f.Path)
+		if isGoSource(f.Path) && !v.checked[dir] {
+			v.checked[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(v.root, dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("%s is not in the root %s", dir, v.root)
+		}
+		_, err = v.load(pathJoin(v.modulePath, filepath.ToSlash(rel)), dir)
+		v.errs.Add(err, Error{File: dir, Code: CodeTypeError})
+	}
+	return v.errs.Err()
+}
+
+// verifier imports packages for the type checker, from the rendered files
+// where there are any and from disk otherwise.
+type verifier struct {
+	fset       *token.FileSet
+	root       string
+	modulePath string                    // Import path of the root
+	overlay    map[string]string         // Rendered content by path
+	written    map[string]string         // Content to be written by path
+	std        types.Importer            // Importer of the standard library
+	packages   map[string]*types.Package // Loaded packages by import path
+	checked    map[string]bool           // Directories whose errors count
+	files      map[string]File           // Traced files by path
+	schemas    map[string]Schema         // Schemas by name
+	errs       Errors
+}
+
+// Import imports a package for the type checker.
+func (v *verifier) Import(path string) (*types.Package, error) {
+	return v.ImportFrom(path, v.root, 0)
+}
+
+// ImportFrom imports a package for the type checker. Packages of the root are
+// looked for in the root, other packages where the go tool finds them.
+func (v *verifier) ImportFrom(
+	path string,
+	srcDir string,
+	mode types.ImportMode,
+) (*types.Package, error) {
+	if p, ok := v.packages[path]; ok {
+		if p == nil {
+			return nil, fmt.Errorf("import cycle through %s", path)
+		}
+		return p, nil
+	}
+	if path == v.modulePath || strings.HasPrefix(path, v.modulePath+"/") {
+		rel := strings.TrimPrefix(strings.TrimPrefix(path, v.modulePath), "/")
+		return v.load(path, filepath.Join(v.root, filepath.FromSlash(rel)))
+	}
+	bp, err := build.Default.Import(path, srcDir, build.FindOnly)
+	if err != nil {
+		return nil, err
+	}
+	if bp.Goroot {
+		return v.std.Import(path)
+	}
+	return v.load(path, bp.Dir)
+}
+
+// load type-checks the package in the directory. The errors are recorded if
+// the directory is one of the checked ones.
+func (v *verifier) load(path string, dir string) (*types.Package, error) {
+	v.packages[path] = nil
+	files, err := v.parseDir(dir)
+	if err != nil {
+		delete(v.packages, path)
+		return nil, err
+	}
+	if len(files) == 0 {
+		delete(v.packages, path)
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	config := types.Config{Importer: v, Error: func(err error) {
+		if v.checked[dir] {
+			v.addError(err)
+		}
+	}}
+	p, _ := config.Check(path, v.fset, files, nil)
+	v.packages[path] = p
+	return p, nil
+}
+
+// parseDir parses the Go files of a directory that are on disk or rendered,
+// preferring the rendered content, without the test files.
+func (v *verifier) parseDir(dir string) ([]*ast.File, error) {
+	paths := map[string]bool{}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, info := range infos {
+		if !info.IsDir() {
+			paths[filepath.Join(dir, info.Name())] = true
+		}
+	}
+	for path := range v.overlay {
+		if filepath.Dir(path) == dir {
+			paths[path] = true
+		}
+	}
+	// Generated files have no build constraints, and are not on disk yet
+	sorted := []string{}
+	for path := range paths {
+		if !isGoSource(path) {
+			continue
+		}
+		if _, ok := v.overlay[path]; ok {
+			sorted = append(sorted, path)
+			continue
+		}
+		match, err := build.Default.MatchFile(dir, filepath.Base(path))
+		if err == nil && match {
+			sorted = append(sorted, path)
+		}
+	}
+	sort.Strings(sorted)
+
+	files := []*ast.File{}
+	for _, path := range sorted {
+		var source interface{}
+		if content, ok := v.overlay[path]; ok {
+			source = content
+		}
+		f, err := parser.ParseFile(v.fset, path, source, 0)
+		if err != nil {
+			v.addError(err)
+			continue
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// lineDirective matches the line directives of a traced file.
+var lineDirective = regexp.MustCompile(`/\*line [^*]*\*/`)
+
+// addError records a type error, with what it was generated from.
+func (v *verifier) addError(err error) {
+	e, ok := err.(types.Error)
+	if !ok {
+		v.errs.Add(err, Error{Code: CodeTypeError})
+		return
+	}
+
+	// The adjusted position is in the template, the raw one in the file
+	at := e.Fset.Position(e.Pos)
+	raw := e.Fset.PositionFor(e.Pos, false)
+	context := Error{File: raw.Filename, Code: CodeTypeError}
+	f, ok := v.files[raw.Filename]
+	if !ok {
+		context.Err = fmt.Errorf("line %d: %s", raw.Line, e.Msg)
+		v.errs = append(v.errs, &context)
+		return
+	}
+	context.Layer = f.Layer
+	context.Schema = f.Source.Schema
+	context.Edge = f.Source.Edge
+	// The name of the template is relative, so it was joined to the
+	// directory of the file
+	if at.Filename != raw.Filename {
+		name, err := filepath.Rel(filepath.Dir(raw.Filename), at.Filename)
+		if err != nil {
+			name = at.Filename
+		}
+		context.Template = strings.TrimSuffix(filepath.ToSlash(name),
+			TemplateExt)
+	}
+	line := strings.TrimSpace(lineDirective.ReplaceAllString(
+		lineOf(f.Content, raw.Line), ""))
+	fileLine := formattedLine(f.Content, v.written[f.Path], raw.Offset)
+	context.Field = v.fieldOf(f.Source, line)
+	context.Err = fmt.Errorf("line %d: %s in %q", fileLine, e.Msg, line)
+	v.errs = append(v.errs, &context)
+}
+
+// fieldOf returns the name of the field of the source whose name or code name
+// is on the line, the longest one if there are several, empty if there is
+// none.
+func (v *verifier) fieldOf(source Source, line string) string {
+	s, ok := v.schemas[source.Schema]
+	if !ok {
+		return ""
+	}
+	names := map[string]string{}
+	if source.Edge == "" {
+		for _, f := range s.GetFields() {
+			names[f.CodeName] = f.Name
+			names[`"`+f.Name+`"`] = f.Name
+		}
+	} else {
+		for _, e := range s.GetEdges() {
+			if e.Name != source.Edge {
+				continue
+			}
+			for _, f := range e.Fields {
+				names[f.CodeName] = f.Name
+				names[`"`+f.Name+`"`] = f.Name
+			}
+		}
+	}
+	found := ""
+	for name := range names {
+		if name == "" || !strings.Contains(line, name) {
+			continue
+		}
+		if len(name) > len(found) || len(name) == len(found) && name < found {
+			found = name
+		}
+	}
+	return names[found]
+}
+
+// rootImportPath returns the import path of the root, from its go.mod file or
+// from its place in the GOPATH.
+func rootImportPath(root string) (string, error) {
+	file, err := os.Open(filepath.Join(root, "go.mod"))
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 2 && fields[0] == "module" {
+				return strings.Trim(fields[1], `"`), nil
+			}
+		}
+		return "", errors.New(filepath.Join(root, "go.mod") + " has no " +
+			"module line")
+	}
+	p, err := build.Default.ImportDir(root, build.FindOnly)
+	if err == nil && p.ImportPath != "" && p.ImportPath != "." {
+		return p.ImportPath, nil
+	}
+	return "", fmt.Errorf("cannot tell the import path of %s, it needs a "+
+		"go.mod file or to be in the GOPATH", root)
+}
+
+// formattedLine returns the line of the formatted content with the token at
+// the offset of the traced content. Formatting keeps the tokens in the same
+// order, but for the commas and semicolons it adds or drops, so the nth token
+// of the traced content is the nth one of the formatted content.
+func formattedLine(traced string, formatted string, offset int) int {
+	n := 0
+	scanTokens(traced, func(tokenOffset int, line int) bool {
+		if tokenOffset >= offset {
+			return false
+		}
+		n++
+		return true
+	})
+	found := 0
+	scanTokens(formatted, func(tokenOffset int, line int) bool {
+		found = line
+		n--
+		return n >= 0
+	})
+	return found
+}
+
+// scanTokens calls f with the offset and line of the tokens of the code that
+// are not comments, commas or semicolons, until it returns false.
+func scanTokens(code string, f func(offset int, line int) bool) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(code))
+	var s scanner.Scanner
+	s.Init(file, []byte(code), nil, 0)
+	for {
+		pos, tok, _ := s.Scan()
+		if tok == token.EOF {
+			return
+		}
+		if tok == token.COMMA || tok == token.SEMICOLON {
+			continue
+		}
+		if !f(file.Offset(pos), file.PositionFor(pos, false).Line) {
+			return
+		}
+	}
+}
+
+// lineOf returns the line of the content, numbered from 1.
+func lineOf(content string, line int) string {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return lines[line-1]
+}
+
+// isGoSource returns whether the path is a Go file that is not a test.
+func isGoSource(path string) bool {
+	return strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go")
+}
+
+// pathJoin joins an import path and a slash separated relative path.
+func pathJoin(path string, rel string) string {
+	if rel == "." {
+		return path
+	}
+	return path + "/" + rel
+}
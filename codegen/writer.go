@@ -4,28 +4,175 @@ package codegen
 
 import (
 	"bytes"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"reflect"
+	"sort"
+	"strings"
 	"text/template"
 )
 
 // =============================================================================
-// Template executor
+// Templates
 // =============================================================================
 
-// ExecTemplate executes a template to return a string.
-func ExecTemplate(
-	tmplStr string,
-	tmplName string,
-	data interface{},
-	funcMap template.FuncMap,
-) string {
-	tmpl, err := template.New(tmplName).Funcs(funcMap).Parse(tmplStr)
-	if err != nil {
-		panic(err)
+// Template is a named template of the generated code. Its data is always of
+// the same type, which is the contract for the fields the template, or an
+// override of it, can refer to.
+type Template struct {
+	Name  string           // Name of the template, such as db/node_query_where
+	Data  interface{}      // Value of the type of the data, nil if it has none
+	Funcs template.FuncMap // Functions the template can call
+	Text  string           // Text of the built in template
+
+	builtin      *template.Template
+	override     *template.Template
+	overrideText string
+}
+
+var registeredTemplates = map[string]*Template{}
+
+// tracing is whether the output of every template starts with a line
+// directive naming it, so positions in the generated code point back to the
+// template. It is only set by a traced render, see renderLayer.
+var tracing bool
+
+// NewTemplate parses and registers a built in template. Parse errors and
+// registering two templates with the same name panic.
+func NewTemplate(t Template) *Template {
+	if _, ok := registeredTemplates[t.Name]; ok {
+		panic("codegen: template " + t.Name + " is registered twice")
+	}
+	t.builtin = template.Must(template.New(t.Name).Funcs(t.Funcs).Parse(t.Text))
+	registeredTemplates[t.Name] = &t
+	resetTemplateSetHash()
+	return &t
+}
+
+// Overridden returns whether the template is overridden from a file.
+func (t *Template) Overridden() bool {
+	return t.override != nil
+}
+
+// Exec executes the template, or its override, to return a string. Errors are
+// returned as an *Error naming the template.
+func (t *Template) Exec(data interface{}) (string, error) {
+	if reflect.TypeOf(data) != reflect.TypeOf(t.Data) {
+		return "", &Error{Template: t.Name, Err: fmt.Errorf("executed with "+
+			"data of type %T instead of %T", data, t.Data)}
+	}
+	tmpl := t.builtin
+	if t.override != nil {
+		tmpl = t.override
 	}
 	var tpl bytes.Buffer
-	err = tmpl.Execute(&tpl, data)
+	err := tmpl.Execute(&tpl, data)
+	if err != nil {
+		return "", &Error{Template: t.Name, Err: err}
+	}
+	if tracing {
+		return traceOutput(t.Name, tpl.String()), nil
+	}
+	return tpl.String(), nil
+}
+
+// Sections collects the code of the sections of a generated file, in order,
+// along with the first error they were rendered with. The writers add the
+// output of every template they execute, and check the error once they join
+// the sections.
+type Sections struct {
+	code []string
+	err  error
+}
+
+// Add adds the code of a section, or records the error of its template.
+func (s *Sections) Add(code string, err error) {
 	if err != nil {
-		panic(err)
+		if s.err == nil {
+			s.err = err
+		}
+		return
+	}
+	s.code = append(s.code, code)
+}
+
+// Join returns the code of the sections separated by sep, or the first error
+// a section was rendered with.
+func (s *Sections) Join(sep string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return strings.Join(s.code, sep), nil
+}
+
+// traceOutput adds a line directive naming the template to its output. The
+// directive goes right before the code, with the line and column the code is
+// at in the output.
+func traceOutput(name string, output string) string {
+	code := strings.TrimLeft(output, " \t\n")
+	if code == "" {
+		return output
+	}
+	lead := output[:len(output)-len(code)]
+	line := strings.Count(lead, "\n") + 1
+	column := len(lead) - strings.LastIndex(lead, "\n")
+	return fmt.Sprintf("%s/*line %s%s:%d:%d*/%s", lead, name, TemplateExt, line,
+		column, code)
+}
+
+// FormatSource formats the generated go code. The code is formatted with its
+// manual sections left empty, and their code is put back as it was written,
+// so the generated code around them does not depend on what they contain. A
+// syntax error is returned with the line of the unformatted code it was found
+// in, as the line number alone does not point anywhere a user can look. The
+// code of a traced render is left as it is.
+func FormatSource(code string) ([]byte, error) {
+	// Formatting moves the line directives of a traced render around, and a
+	// traced render is only type-checked
+	if tracing {
+		return []byte(code), nil
+	}
+	sections := ExtractManualSections(code)
+	stripped := StripManualSections(code)
+	res, err := format.Source([]byte(stripped))
+	if err != nil {
+		return nil, formatError(stripped, err)
+	}
+	filled := FillManualSections(string(res), sections)
+	if filled != string(res) {
+		// The code of the manual sections still has to parse
+		_, err = parser.ParseFile(token.NewFileSet(), "", filled, 0)
+		if err != nil {
+			return nil, formatError(filled, err)
+		}
+	}
+	return []byte(filled), nil
+}
+
+// formatError returns the error of formatting the code, with the line it was
+// found in.
+func formatError(code string, err error) error {
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		lines := strings.Split(code, "\n")
+		if line := list[0].Pos.Line; line > 0 && line <= len(lines) {
+			return fmt.Errorf("could not format the generated code: %v "+
+				"in %q", list[0], strings.TrimSpace(lines[line-1]))
+		}
+	}
+	return fmt.Errorf("could not format the generated code: %v", err)
+}
+
+// Templates returns the registered templates, sorted by name.
+func Templates() []*Template {
+	templates := []*Template{}
+	for _, t := range registeredTemplates {
+		templates = append(templates, t)
 	}
-	return tpl.String()
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates
 }
@@ -0,0 +1,220 @@
+// Parsing yaml into the same positioned values as json, so schema files can be
+// written in either. The yaml is decoded into nodes by gopkg.in/yaml.v3, which
+// keep the line and column of every value, and the nodes are turned into the
+// values the json walker reads. Aliases are resolved, and merge keys are
+// merged into the mapping they are in.
+
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isYAMLFile returns whether the path names a yaml file.
+func isYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// yamlErrorLine matches the line of an error of the yaml decoder.
+var yamlErrorLine = regexp.MustCompile(`^yaml: line ([0-9]+): (.*)$`)
+
+// parseYAML parses a single yaml document into a tree of positioned values.
+func parseYAML(content []byte) (*jsonValue, error) {
+	p := yamlParser{content: content}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	var document yaml.Node
+	err := decoder.Decode(&document)
+	if err == io.EOF {
+		return nil, jsonError{0, "the file has no yaml document"}
+	} else if err != nil {
+		return nil, p.decodeError(err)
+	}
+	var next yaml.Node
+	err = decoder.Decode(&next)
+	if err == nil {
+		return nil, jsonError{p.offset(&next), "a schema file must hold a " +
+			"single yaml document"}
+	} else if err != io.EOF {
+		return nil, p.decodeError(err)
+	}
+	return p.value(&document)
+}
+
+type yamlParser struct {
+	content []byte
+}
+
+// decodeError returns an error of the decoder at the start of its line.
+func (p *yamlParser) decodeError(err error) error {
+	msg := strings.TrimPrefix(err.Error(), "yaml: ")
+	groups := yamlErrorLine.FindStringSubmatch(err.Error())
+	if groups == nil {
+		return jsonError{0, msg}
+	}
+	line, _ := strconv.Atoi(groups[1])
+	return jsonError{p.lineOffset(line, 1), groups[2]}
+}
+
+// offset returns the offset of a node in the input.
+func (p *yamlParser) offset(n *yaml.Node) int64 {
+	return p.lineOffset(n.Line, n.Column)
+}
+
+// lineOffset returns the offset of a line and column, both starting at 1. The
+// columns of the decoder count characters rather than bytes.
+func (p *yamlParser) lineOffset(line int, column int) int64 {
+	offset := 0
+	for l := 1; l < line; l++ {
+		i := bytes.IndexByte(p.content[offset:], '\n')
+		if i < 0 {
+			return int64(len(p.content))
+		}
+		offset += i + 1
+	}
+	for c := 1; c < column && offset < len(p.content); c++ {
+		if p.content[offset] == '\n' {
+			break
+		}
+		_, size := utf8.DecodeRune(p.content[offset:])
+		offset += size
+	}
+	return int64(offset)
+}
+
+func (p *yamlParser) value(n *yaml.Node) (*jsonValue, error) {
+	offset := p.offset(n)
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return &jsonValue{offset: offset}, nil
+		}
+		return p.value(n.Content[0])
+	case yaml.AliasNode:
+		v, err := p.value(n.Alias)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonValue{offset: offset, value: v.value, plain: v.plain}, nil
+	case yaml.SequenceNode:
+		array := []*jsonValue{}
+		for _, item := range n.Content {
+			v, err := p.value(item)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, v)
+		}
+		return &jsonValue{offset: offset, value: array}, nil
+	case yaml.MappingNode:
+		o := &jsonObject{
+			values:     map[string]*jsonValue{},
+			keyOffsets: map[string]int64{},
+		}
+		err := p.mapping(o, n, false)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonValue{offset: offset, value: o}, nil
+	}
+	return p.scalar(n)
+}
+
+// mapping adds the pairs of a mapping node to the object. The pairs of a
+// merged mapping do not replace the keys the object has already.
+func (p *yamlParser) mapping(o *jsonObject, n *yaml.Node, merged bool) error {
+	// Merge keys are merged last, as the keys of the mapping win over them
+	merges := []*yaml.Node{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, item := n.Content[i], n.Content[i+1]
+		if key.Kind == yaml.ScalarNode && key.ShortTag() == "!!merge" {
+			merges = append(merges, item)
+			continue
+		}
+		if key.Kind != yaml.ScalarNode {
+			return jsonError{p.offset(key), "keys must be strings"}
+		}
+		if _, ok := o.values[key.Value]; ok {
+			if merged {
+				continue
+			}
+			return jsonError{p.offset(key), fmt.Sprintf("duplicate key %q",
+				key.Value)}
+		}
+		v, err := p.value(item)
+		if err != nil {
+			return err
+		}
+		o.keys = append(o.keys, key.Value)
+		o.values[key.Value] = v
+		o.keyOffsets[key.Value] = p.offset(key)
+	}
+
+	for _, m := range merges {
+		sources := []*yaml.Node{m}
+		if m.Kind == yaml.SequenceNode {
+			sources = m.Content
+		}
+		for _, s := range sources {
+			if s.Kind == yaml.AliasNode {
+				s = s.Alias
+			}
+			if s.Kind != yaml.MappingNode {
+				return jsonError{p.offset(s), "only mappings can be merged"}
+			}
+			err := p.mapping(o, s, true)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// scalar returns the value of a scalar node: null, a boolean, a number or a
+// string. The text of a boolean or a number is kept, so it can be used where
+// a string is expected.
+func (p *yamlParser) scalar(n *yaml.Node) (*jsonValue, error) {
+	offset := p.offset(n)
+	switch n.ShortTag() {
+	case "!!null":
+		return &jsonValue{offset: offset}, nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return nil, jsonError{offset, err.Error()}
+		}
+		return &jsonValue{offset: offset, value: b, plain: n.Value}, nil
+	case "!!int", "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return nil, jsonError{offset, err.Error()}
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, jsonError{offset, fmt.Sprintf("%s is not a number "+
+				"json can hold", n.Value)}
+		}
+		number := json.Number(strings.TrimPrefix(n.Value, "+"))
+		if _, err := number.Float64(); err != nil || strings.Contains(
+			n.Value, "_") {
+			// Hexadecimal, octal and underscored numbers are written out
+			number = json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+		return &jsonValue{offset: offset, value: number, plain: n.Value}, nil
+	case "!!str", "!!timestamp", "!!binary":
+		return &jsonValue{offset: offset, value: n.Value}, nil
+	}
+	return nil, jsonError{offset, fmt.Sprintf("unsupported tag %s",
+		n.ShortTag())}
+}
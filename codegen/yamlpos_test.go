@@ -0,0 +1,188 @@
+package codegen
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// plainValue returns the parsed value without positions, as encoding/json
+// would decode it.
+func plainValue(v *jsonValue) interface{} {
+	switch value := v.value.(type) {
+	case []*jsonValue:
+		array := []interface{}{}
+		for _, item := range value {
+			array = append(array, plainValue(item))
+		}
+		return array
+	case *jsonObject:
+		object := map[string]interface{}{}
+		for key, item := range value.values {
+			object[key] = plainValue(item)
+		}
+		return object
+	}
+	return v.value
+}
+
+func TestParseYAMLMatchesJSON(t *testing.T) {
+	yaml := `# A schema file
+---
+version: 1
+defaults: &field
+  type: float64
+  can_order_by: true
+schemas:
+- name: Payment   # the name
+  deletion_privacy: "OwnerOnly"
+  fields:
+    - <<: *field
+      name: amount
+      code_name: 'Amount''s'
+      default_value: 0.0
+      unique: false
+      example_value: ~
+    - *field
+  edges: []
+  graphql:
+    description: |
+      A payment
+      # not a comment
+    total: >-
+      folded
+      text
+
+      and more
+    fields: [{name: amount, type: "Float"},
+      plain text, 1e3, 0x1F]
+    escaped: "tab\there \u00e9"
+    continued: a plain
+      string
+`
+	want := `{
+  "version": 1,
+  "defaults": {"type": "float64", "can_order_by": true},
+  "schemas": [{
+    "name": "Payment",
+    "deletion_privacy": "OwnerOnly",
+    "fields": [{
+      "name": "amount",
+      "code_name": "Amount's",
+      "type": "float64",
+      "default_value": 0.0,
+      "can_order_by": true,
+      "unique": false,
+      "example_value": null
+    }, {"type": "float64", "can_order_by": true}],
+    "edges": [],
+    "graphql": {
+      "description": "A payment\n# not a comment\n",
+      "total": "folded text\nand more",
+      "fields": [{"name": "amount", "type": "Float"}, "plain text", 1e3, 31],
+      "escaped": "tab\there \u00e9",
+      "continued": "a plain string"
+    }
+  }]
+}`
+	got, err := parseYAML([]byte(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := interface{}(nil)
+	decoder := json.NewDecoder(strings.NewReader(want))
+	decoder.UseNumber()
+	err = decoder.Decode(&expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(plainValue(got), expected) {
+		t.Errorf("parsed %#v, expected %#v", plainValue(got), expected)
+	}
+}
+
+func TestParseYAMLPositions(t *testing.T) {
+	yaml := "schemas:\n  - name: Café\n    fields: [ {né: 1, type: int} ]\n"
+	got, err := parseYAML([]byte(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := got.value.(*jsonObject).values["schemas"].value.([]*jsonValue)[0]
+	fields := schema.value.(*jsonObject).values["fields"]
+	field := fields.value.([]*jsonValue)[0].value.(*jsonObject)
+	for _, test := range []struct {
+		offset int64
+		line   int
+		column int
+	}{
+		{schema.value.(*jsonObject).values["name"].offset, 2, 11},
+		// Columns count bytes, the é takes two
+		{field.keyOffsets["type"], 3, 24},
+		{field.values["type"].offset, 3, 30},
+	} {
+		line, column := position([]byte(yaml), test.offset)
+		if line != test.line || column != test.column {
+			t.Errorf("got %d:%d, expected %d:%d", line, column, test.line,
+				test.column)
+		}
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, test := range []struct {
+		yaml   string
+		line   int
+		column int
+		msg    string
+	}{
+		{"a: 1\n  b: 2\n", 2, 1, "mapping values are not allowed"},
+		{"a: 1\na: 2\n", 2, 1, `duplicate key "a"`},
+		{"a: !custom 1\n", 1, 4, "unsupported tag !custom"},
+		{"a: .inf\n", 1, 4, "is not a number json can hold"},
+		{"a:\n\t- 1\n", 2, 1, "cannot start any token"},
+		{"a: [1, 2\n", 1, 1, "did not find expected ',' or ']'"},
+		{"a: 1\n---\nb: 2\n", 2, 1, "single yaml document"},
+		{"# only a comment\n", 1, 1, "no yaml document"},
+	} {
+		_, err := parseYAML([]byte(test.yaml))
+		e, ok := err.(jsonError)
+		if !ok {
+			t.Errorf("%q: expected an error, got %v", test.yaml, err)
+			continue
+		}
+		line, column := position([]byte(test.yaml), e.offset)
+		if line != test.line || column != test.column ||
+			!strings.Contains(e.msg, test.msg) {
+			t.Errorf("%q: got %d:%d: %s, expected %d:%d: %s", test.yaml,
+				line, column, e.msg, test.line, test.column, test.msg)
+		}
+	}
+}
+
+func TestLoadYAMLSchemaFile(t *testing.T) {
+	dir, cleanup := FixtureRoot(t, map[string]string{"payment.yaml": `version: 1
+schemas:
+  - name: Payment
+    fields:
+      - name: amount
+        code_name: Amount
+        type: float64
+        default_value: 0.0
+      - name: note
+        code_name: Note
+        type: text
+        privacy: AllowAll
+`})
+	defer cleanup()
+	path := filepath.Join(dir, "payment.yaml")
+
+	_, err := LoadSchemaFiles([]string{path}, nil)
+	want := path + `:11:15: unknown type "text" (valid types are string, ` +
+		"float64, int64, bool)\n" + path + `:12:18: unknown privacy policy ` +
+		`"AllowAll", no policies are registered`
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v, expected %s", err, want)
+	}
+}
@@ -1,3 +1,6 @@
 #!/bin/bash
-go build || { echo 'failed to build' ; exit 1; }
-./splits-go-schema-codegen ../splits-go-api $@
+# Builds the generator along with the schemas registered from Go, from the
+# binary in the repository of the schemas that imports them, and generates.
+main=${CODEGEN_MAIN:-../splits-go-api/cmd/codegen}
+go build -o splits-go-schema-codegen "$main" || { echo 'failed to build' ; exit 1; }
+./splits-go-schema-codegen generate "$@"